@@ -0,0 +1,78 @@
+package userclient
+
+import (
+	"sync"
+	"time"
+)
+
+type breakerState int
+
+const (
+	breakerClosed breakerState = iota
+	breakerOpen
+	breakerHalfOpen
+)
+
+// breaker is a circuit breaker. After threshold consecutive failures it opens
+// and rejects calls for cooldown. Then a single trial call is let through:
+// success closes the breaker, failure opens it again.
+type breaker struct {
+	mu        sync.Mutex
+	state     breakerState
+	failures  int
+	openedAt  time.Time
+	threshold int
+	cooldown  time.Duration
+	now       func() time.Time
+}
+
+func newBreaker(threshold int, cooldown time.Duration) *breaker {
+	b := &breaker{threshold: threshold, cooldown: cooldown, now: time.Now}
+	breakerStateGauge.Set(float64(breakerClosed))
+	return b
+}
+
+// allow reports whether a call may be made now. A true result must be
+// followed by record.
+func (b *breaker) allow() bool {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	switch b.state {
+	case breakerOpen:
+		if b.now().Sub(b.openedAt) < b.cooldown {
+			return false
+		}
+		b.setState(breakerHalfOpen)
+		return true
+	case breakerHalfOpen:
+		// A trial call is already in flight.
+		return false
+	default:
+		return true
+	}
+}
+
+// record reports the result of an allowed call. Only failures of the user
+// service count; a "not found" answer is a success.
+func (b *breaker) record(failed bool) {
+	b.mu.Lock()
+	defer b.mu.Unlock()
+
+	if !failed {
+		b.failures = 0
+		b.setState(breakerClosed)
+		return
+	}
+
+	b.failures++
+	if b.state == breakerHalfOpen || b.failures >= b.threshold {
+		b.openedAt = b.now()
+		b.setState(breakerOpen)
+	}
+}
+
+func (b *breaker) setState(state breakerState) {
+	b.state = state
+	breakerStateGauge.Set(float64(state))
+}
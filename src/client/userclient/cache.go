@@ -0,0 +1,224 @@
+package userclient
+
+import (
+	"bookem-room-service/util"
+	"container/list"
+	"context"
+	"crypto/subtle"
+	"errors"
+	"fmt"
+	"net/http"
+	"strconv"
+	"strings"
+	"sync"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"golang.org/x/sync/singleflight"
+)
+
+// CachingUserClient is a UserClient that caches the users found by the client
+// it wraps. Unknown users are cached too, for a shorter time. Concurrent
+// lookups of the same user share a single call to the user service.
+//
+// The user service calls the invalidation endpoint (see InvalidationHandler)
+// when a user changes role or is deleted, so that the change is seen before
+// the entry expires.
+type CachingUserClient struct {
+	next        UserClient
+	ttl         time.Duration
+	negativeTTL time.Duration
+	maxEntries  int
+
+	mu      sync.Mutex
+	lru     *list.List             // Front is the most recently used entry.
+	entries map[uint]*list.Element // User ID -> element holding *userCacheEntry.
+	// generation is bumped by every invalidation, so that a lookup that was
+	// in flight during one does not cache what it read before it.
+	generation uint64
+
+	group singleflight.Group
+}
+
+type userCacheEntry struct {
+	id        uint
+	user      *UserDTO // Nil for an unknown user.
+	expiresAt time.Time
+}
+
+// NewCachingUserClient caches the users found by next for ttl, unknown users
+// for negativeTTL, and holds at most maxEntries users (no bound if zero).
+func NewCachingUserClient(next UserClient, ttl time.Duration, negativeTTL time.Duration, maxEntries int) *CachingUserClient {
+	return &CachingUserClient{
+		next:        next,
+		ttl:         ttl,
+		negativeTTL: negativeTTL,
+		maxEntries:  maxEntries,
+		lru:         list.New(),
+		entries:     make(map[uint]*list.Element),
+	}
+}
+
+func (c *CachingUserClient) FindById(ctx context.Context, id uint) (*UserDTO, error) {
+	if entry, ok := c.get(id); ok {
+		if entry.user == nil {
+			userCacheLookupsTotal.WithLabelValues(cacheNegativeHit).Inc()
+			return nil, fmt.Errorf("%w: %d", ErrUserNotFound, id)
+		}
+		userCacheLookupsTotal.WithLabelValues(cacheHit).Inc()
+		return copyUser(entry.user), nil
+	}
+	userCacheLookupsTotal.WithLabelValues(cacheMiss).Inc()
+
+	// The shared call must not fail because the caller that started it went
+	// away, so it runs detached from its cancellation; the user client bounds
+	// it with its own timeouts.
+	key := strconv.FormatUint(uint64(id), 10)
+	result := c.group.DoChan(key, func() (any, error) {
+		generation := c.currentGeneration()
+		user, err := c.next.FindById(context.WithoutCancel(ctx), id)
+
+		switch {
+		case err == nil:
+			c.set(id, user, c.ttl, generation)
+		case errors.Is(err, ErrUserNotFound) && c.negativeTTL > 0:
+			c.set(id, nil, c.negativeTTL, generation)
+		}
+		return user, err
+	})
+
+	select {
+	case <-ctx.Done():
+		return nil, ctx.Err()
+	case res := <-result:
+		if res.Err != nil {
+			return nil, res.Err
+		}
+		return copyUser(res.Val.(*UserDTO)), nil
+	}
+}
+
+func (c *CachingUserClient) Ping(ctx context.Context) error {
+	return c.next.Ping(ctx)
+}
+
+// Invalidate drops the cached users, so that their next lookup calls the
+// user service.
+func (c *CachingUserClient) Invalidate(ids ...uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for _, id := range ids {
+		if elem, ok := c.entries[id]; ok {
+			c.remove(elem)
+		}
+		c.group.Forget(strconv.FormatUint(uint64(id), 10))
+	}
+}
+
+// InvalidateAll drops every cached user.
+func (c *CachingUserClient) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.generation++
+	for id := range c.entries {
+		c.group.Forget(strconv.FormatUint(uint64(id), 10))
+	}
+	c.lru.Init()
+	c.entries = make(map[uint]*list.Element)
+}
+
+func (c *CachingUserClient) get(id uint) (*userCacheEntry, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[id]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*userCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return entry, true
+}
+
+func (c *CachingUserClient) set(id uint, user *UserDTO, ttl time.Duration, generation uint64) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if generation != c.generation {
+		return
+	}
+
+	if elem, ok := c.entries[id]; ok {
+		c.remove(elem)
+	}
+
+	entry := &userCacheEntry{id: id, user: copyUser(user), expiresAt: time.Now().Add(ttl)}
+	c.entries[id] = c.lru.PushFront(entry)
+
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *CachingUserClient) currentGeneration() uint64 {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+	return c.generation
+}
+
+// remove drops an entry. The caller must hold the lock.
+func (c *CachingUserClient) remove(elem *list.Element) {
+	c.lru.Remove(elem)
+	delete(c.entries, elem.Value.(*userCacheEntry).id)
+}
+
+func copyUser(user *UserDTO) *UserDTO {
+	if user == nil {
+		return nil
+	}
+	copied := *user
+	return &copied
+}
+
+// ---------------------------------------------------------------
+
+// InvalidationHandler drops one cached user, or every one, for the user service
+// to call when users change. It must be routed with a trailing *id wildcard:
+// DELETE <path>/5 drops user 5, DELETE <path>/ drops all. Callers must send the
+// shared token as "Authorization: Bearer <token>".
+func (c *CachingUserClient) InvalidationHandler(token string) gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		given, _ := strings.CutPrefix(ctx.GetHeader("Authorization"), "Bearer ")
+		if token == "" || subtle.ConstantTimeCompare([]byte(given), []byte(token)) != 1 {
+			ctx.AbortWithStatusJSON(http.StatusUnauthorized, gin.H{"error": "Unauthorized"})
+			return
+		}
+
+		param := ctx.Param("id")
+		if param == "" || param == "/" {
+			util.TEL.Info(ctx.Request.Context(), "invalidating every cached user")
+			c.InvalidateAll()
+			ctx.Status(http.StatusNoContent)
+			return
+		}
+
+		id, err := strconv.ParseUint(strings.TrimPrefix(param, "/"), 10, 32)
+		if err != nil {
+			ctx.AbortWithStatusJSON(http.StatusBadRequest, gin.H{"error": "Bad request"})
+			return
+		}
+
+		util.TEL.Info(ctx.Request.Context(), "invalidating cached user", "id", id)
+		c.Invalidate(uint(id))
+		ctx.Status(http.StatusNoContent)
+	}
+}
@@ -1,64 +1,181 @@
 package userclient
 
 import (
+	"bookem-room-service/config"
 	"bookem-room-service/util"
 	"context"
 	"encoding/json"
+	"errors"
 	"fmt"
-	"io"
+	"math/rand/v2"
 	"net/http"
-
-	"go.opentelemetry.io/otel"
-	"go.opentelemetry.io/otel/propagation"
+	"net/url"
+	"strings"
+	"time"
 )
 
 type UserClient interface {
-	FindById(context context.Context, it uint) (*UserDTO, error)
+	FindById(ctx context.Context, it uint) (*UserDTO, error)
+	// Ping reports whether the user service is reachable and healthy.
+	Ping(ctx context.Context) error
 }
 
+// ErrUserNotFound is returned when the user service does not know the user.
+var ErrUserNotFound = errors.New("user not found")
+
+// ErrUserServiceUnavailable is returned when the user service cannot answer:
+// it is unreachable, timed out, failed with a 5xx or the breaker is open.
+var ErrUserServiceUnavailable = errors.New("user service unavailable")
+
 type userClient struct {
-	baseURL string
+	baseURL      string
+	healthURL    string
+	httpClient   *http.Client
+	timeout      time.Duration
+	maxRetries   int
+	retryBackoff time.Duration
+	breaker      *breaker
 }
 
-func NewUserClient() UserClient {
+// NewUserClient returns a client of the user service. httpClient may be nil,
+// in which case a client with its own connection pool is used. Per-call
+// timeouts come from config, not from httpClient.
+func NewUserClient(config config.UserServiceConfig, httpClient *http.Client) UserClient {
+	if httpClient == nil {
+		httpClient = &http.Client{Transport: http.DefaultTransport.(*http.Transport).Clone()}
+	}
+
 	return &userClient{
-		baseURL: "http://user-service:8080/api", // TODO: This should not be hardcoded
+		baseURL:      strings.TrimSuffix(config.BaseURL, "/"),
+		healthURL:    healthURL(config.BaseURL),
+		httpClient:   httpClient,
+		timeout:      time.Duration(config.Timeout),
+		maxRetries:   config.MaxRetries,
+		retryBackoff: time.Duration(config.RetryBackoff),
+		breaker:      newBreaker(config.BreakerThreshold, time.Duration(config.BreakerCooldown)),
 	}
 }
 
-func (c *userClient) FindById(context context.Context, id uint) (*UserDTO, error) {
-	util.TEL.Info("find user", "id", id)
+// healthURL returns the /healthz endpoint at the root of the user service
+// host, e.g. http://user-service:8080/api -> http://user-service:8080/healthz.
+func healthURL(baseURL string) string {
+	parsed, err := url.Parse(baseURL)
+	if err != nil {
+		return strings.TrimSuffix(baseURL, "/") + "/healthz"
+	}
+	return (&url.URL{Scheme: parsed.Scheme, Host: parsed.Host, Path: "/healthz"}).String()
+}
 
-	req, err := http.NewRequest("GET", fmt.Sprintf("%s/%d", c.baseURL, id), nil)
+func (c *userClient) Ping(ctx context.Context) error {
+	req, err := http.NewRequestWithContext(ctx, "GET", c.healthURL, nil)
 	if err != nil {
-		util.TEL.Error("could not create request", err)
-		return nil, err
+		return err
 	}
-	otel.GetTextMapPropagator().Inject(context, propagation.HeaderCarrier(req.Header))
 
-	resp, err := http.DefaultClient.Do(req)
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		util.TEL.Error("could not send request", err)
-		return nil, err
+		return err
 	}
+	defer resp.Body.Close()
 
 	if resp.StatusCode != http.StatusOK {
-		util.TEL.Error("user not found", nil, "id", id, "status_code", resp.StatusCode)
-		return nil, fmt.Errorf("user %d not found", id)
+		return fmt.Errorf("user service health check returned %d", resp.StatusCode)
 	}
+	return nil
+}
+
+func (c *userClient) FindById(ctx context.Context, id uint) (*UserDTO, error) {
+	ctx, span := util.TEL.Start(ctx, "user-service-find-by-id")
+	defer span.End()
+
+	util.TEL.Info(ctx, "find user", "id", id)
+
+	start := time.Now()
+	outcome := outcomeOK
+	defer func() { observeRequest("find_by_id", outcome, start) }()
+
+	if !c.breaker.allow() {
+		util.TEL.Warn(ctx, "circuit breaker is open, not calling the user service", "id", id)
+		outcome = outcomeBreakerOpen
+		return nil, fmt.Errorf("%w: circuit breaker is open", ErrUserServiceUnavailable)
+	}
+
+	user, outcome, err := c.findById(ctx, id)
+	c.breaker.record(errors.Is(err, ErrUserServiceUnavailable))
+	return user, err
+}
 
-	bodyBytes, err := io.ReadAll(resp.Body)
+// findById calls the user service, retrying transport errors and 5xx
+// responses with exponential backoff.
+func (c *userClient) findById(ctx context.Context, id uint) (*UserDTO, string, error) {
+	for attempt := 0; ; attempt++ {
+		user, outcome, err := c.findByIdOnce(ctx, id)
+		if !retryable(outcome) || attempt >= c.maxRetries || ctx.Err() != nil {
+			return user, outcome, err
+		}
+
+		delay := backoff(c.retryBackoff, attempt)
+		util.TEL.Warn(ctx, "retrying user service call", "id", id, "attempt", attempt+1, "delay", delay, "error", err)
+		retriesTotal.WithLabelValues("find_by_id").Inc()
+
+		select {
+		case <-ctx.Done():
+			return nil, outcome, err
+		case <-time.After(delay):
+		}
+	}
+}
+
+func (c *userClient) findByIdOnce(ctx context.Context, id uint) (*UserDTO, string, error) {
+	ctx, cancel := context.WithTimeout(ctx, c.timeout)
+	defer cancel()
+
+	req, err := http.NewRequestWithContext(ctx, "GET", fmt.Sprintf("%s/%d", c.baseURL, id), nil)
+	if err != nil {
+		util.TEL.Error(ctx, "could not create request", err)
+		return nil, outcomeRequestError, err
+	}
+	util.TEL.Inject(ctx, req)
+
+	resp, err := c.httpClient.Do(req)
 	if err != nil {
-		util.TEL.Error("could not parse bytes from response", err)
-		return nil, err
+		util.TEL.Error(ctx, "could not send request", err)
+		return nil, outcomeTransportError, fmt.Errorf("%w: %w", ErrUserServiceUnavailable, err)
 	}
 	defer resp.Body.Close()
 
+	switch {
+	case resp.StatusCode == http.StatusNotFound:
+		util.TEL.Info(ctx, "user not found", "id", id)
+		return nil, outcomeNotFound, fmt.Errorf("%w: %d", ErrUserNotFound, id)
+	case resp.StatusCode >= 500:
+		util.TEL.Error(ctx, "user service failed", nil, "id", id, "status_code", resp.StatusCode)
+		return nil, outcomeServerError, fmt.Errorf("%w: status %d", ErrUserServiceUnavailable, resp.StatusCode)
+	case resp.StatusCode != http.StatusOK:
+		util.TEL.Error(ctx, "unexpected status from user service", nil, "id", id, "status_code", resp.StatusCode)
+		return nil, outcomeBadStatus, fmt.Errorf("user service returned status %d for user %d", resp.StatusCode, id)
+	}
+
 	var obj UserDTO
-	if err := json.Unmarshal(bodyBytes, &obj); err != nil {
-		util.TEL.Error("could not unmarshall JSON", err)
-		return nil, err
+	if err := json.NewDecoder(resp.Body).Decode(&obj); err != nil {
+		util.TEL.Error(ctx, "could not unmarshall JSON", err)
+		return nil, outcomeDecodeError, fmt.Errorf("%w: invalid response: %w", ErrUserServiceUnavailable, err)
 	}
 
-	return &obj, nil
+	return &obj, outcomeOK, nil
+}
+
+func retryable(outcome string) bool {
+	return outcome == outcomeTransportError || outcome == outcomeServerError
+}
+
+// backoff returns the delay before retry number attempt+1: base doubled per
+// attempt, with up to half of it taken off at random so that clients do not
+// retry in lockstep.
+func backoff(base time.Duration, attempt int) time.Duration {
+	delay := base << attempt
+	if delay <= 0 {
+		return 0
+	}
+	return delay - rand.N(delay/2+1)
 }
@@ -0,0 +1,76 @@
+package userclient
+
+import (
+	"bookem-room-service/util"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+var (
+	requestDuration = util.RegisterCollector(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "user_service_request_duration_seconds",
+			Help:    "Duration of requests to the user service in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"operation", "outcome"},
+	))
+
+	requestErrorsTotal = util.RegisterCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_service_errors_total",
+			Help: "Total number of failed requests to the user service, by reason",
+		},
+		[]string{"operation", "reason"},
+	))
+
+	retriesTotal = util.RegisterCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_service_retries_total",
+			Help: "Total number of retried requests to the user service",
+		},
+		[]string{"operation"},
+	))
+
+	userCacheLookupsTotal = util.RegisterCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "user_cache_lookups_total",
+			Help: "Total number of user lookups in the user cache, by result",
+		},
+		[]string{"result"},
+	))
+
+	breakerStateGauge = util.RegisterCollector(prometheus.NewGauge(
+		prometheus.GaugeOpts{
+			Name: "user_service_breaker_state",
+			Help: "State of the user service circuit breaker (0 closed, 1 open, 2 half-open)",
+		},
+	))
+)
+
+// Outcomes of a request to the user service.
+const (
+	outcomeOK             = "ok"
+	outcomeRequestError   = "request_error"
+	outcomeTransportError = "transport_error"
+	outcomeNotFound       = "not_found"
+	outcomeBadStatus      = "bad_status"
+	outcomeServerError    = "server_error"
+	outcomeDecodeError    = "decode_error"
+	outcomeBreakerOpen    = "breaker_open"
+)
+
+// Results of a user cache lookup.
+const (
+	cacheHit         = "hit"
+	cacheNegativeHit = "negative_hit"
+	cacheMiss        = "miss"
+)
+
+func observeRequest(operation string, outcome string, start time.Time) {
+	requestDuration.WithLabelValues(operation, outcome).Observe(time.Since(start).Seconds())
+	if outcome != outcomeOK {
+		requestErrorsTotal.WithLabelValues(operation, outcome).Inc()
+	}
+}
@@ -0,0 +1,546 @@
+// Package config holds the settings of the service.
+//
+// Settings are loaded in three layers: the defaults below, then the JSON file
+// named by CONFIG_FILE (if set), then the environment variables. The result is
+// validated once on startup and passed to the parts of the service that need
+// it, nothing else reads the environment.
+package config
+
+import (
+	"bookem-room-service/util"
+	"encoding/json"
+	"errors"
+	"fmt"
+	"net/url"
+	"os"
+	"strconv"
+	"strings"
+	"time"
+)
+
+type Config struct {
+	ServiceName   string `json:"serviceName"`
+	DeploymentEnv string `json:"deploymentEnv"`
+
+	HTTP        HTTPConfig         `json:"http"`
+	DB          DBConfig           `json:"db"`
+	UserService UserServiceConfig  `json:"userService"`
+	Images      ImagesConfig       `json:"images"`
+	JWT         JWTConfig          `json:"jwt"`
+	CORS        CORSConfig         `json:"cors"`
+	Log         util.LogConfig     `json:"log"`
+	Tracing     util.TracingConfig `json:"tracing"`
+	Search      SearchConfig       `json:"search"`
+}
+
+type HTTPConfig struct {
+	Addr              string   `json:"addr"`
+	ReadHeaderTimeout Duration `json:"readHeaderTimeout"`
+	ReadTimeout       Duration `json:"readTimeout"`
+	WriteTimeout      Duration `json:"writeTimeout"`
+	IdleTimeout       Duration `json:"idleTimeout"`
+	// ShutdownTimeout bounds the whole shutdown, ShutdownDelay included.
+	ShutdownTimeout Duration `json:"shutdownTimeout"`
+	// ShutdownDelay is how long /readyz reports 503 before the server stops
+	// accepting connections, so that probes see it and traffic moves away.
+	ShutdownDelay Duration `json:"shutdownDelay"`
+	// HealthCheckTimeout bounds each dependency check of /readyz.
+	HealthCheckTimeout Duration `json:"healthCheckTimeout"`
+}
+
+type DBConfig struct {
+	Host     string `json:"host"`
+	Port     int    `json:"port"`
+	User     string `json:"user"`
+	Password string `json:"password"`
+	Name     string `json:"name"`
+	SSLMode  string `json:"sslMode"`
+	// AutoMigrate applies pending migrations on startup. When disabled the
+	// service only checks the schema version and migrations are run with the
+	// migrate subcommand.
+	AutoMigrate bool `json:"autoMigrate"`
+}
+
+// DSN returns the Postgres connection string.
+func (c DBConfig) DSN() string {
+	return fmt.Sprintf(
+		"host=%s port=%d user=%s password=%s dbname=%s sslmode=%s",
+		c.Host, c.Port, c.User, c.Password, c.Name, c.SSLMode,
+	)
+}
+
+type UserServiceConfig struct {
+	BaseURL string `json:"baseUrl"`
+	// Timeout bounds a single attempt of a call. Every retry gets its own.
+	Timeout Duration `json:"timeout"`
+	// MaxRetries is the number of retries after a failed attempt. Only
+	// transport errors and 5xx responses are retried.
+	MaxRetries int `json:"maxRetries"`
+	// RetryBackoff is the delay before the first retry. It doubles with every
+	// further retry, with jitter.
+	RetryBackoff Duration `json:"retryBackoff"`
+	// BreakerThreshold is the number of consecutive failed calls that opens
+	// the circuit breaker. While open, calls fail fast for BreakerCooldown.
+	BreakerThreshold int      `json:"breakerThreshold"`
+	BreakerCooldown  Duration `json:"breakerCooldown"`
+	// CacheTTL is how long found users are cached. Zero disables the cache.
+	CacheTTL Duration `json:"cacheTtl"`
+	// CacheNegativeTTL is how long unknown users are cached. Zero disables
+	// negative caching.
+	CacheNegativeTTL Duration `json:"cacheNegativeTtl"`
+	CacheEntries     int      `json:"cacheEntries"`
+	// InvalidationToken is the shared secret the user service sends to drop
+	// cached users. Empty disables the invalidation endpoint.
+	InvalidationToken string `json:"invalidationToken"`
+}
+
+// Image storage backends.
+const (
+	ImageBackendLocal = "local"
+	ImageBackendS3    = "s3"
+)
+
+type ImagesConfig struct {
+	// Backend is local (files in Directory) or s3 (a bucket, see S3).
+	Backend   string         `json:"backend"`
+	Directory string         `json:"directory"`
+	S3        ImagesS3Config `json:"s3"`
+	// PublicURL is the URL photos are served under, e.g. /img/ behind nginx
+	// or the public URL of the bucket.
+	PublicURL string `json:"publicUrl"`
+	// Limits of POST /api/photos: the size of each photo, of the whole
+	// request, and the number of photos in it. MaxFileBytes applies to photos
+	// sent in room payloads too.
+	MaxFileBytes    int `json:"maxFileBytes"`
+	MaxRequestBytes int `json:"maxRequestBytes"`
+	MaxFiles        int `json:"maxFiles"`
+	// Dimensions, in pixels, of the largest photo accepted.
+	MaxWidth  int `json:"maxWidth"`
+	MaxHeight int `json:"maxHeight"`
+	// ReconcileInterval is how often images that nothing references are
+	// deleted (zero disables it). Images younger than OrphanGracePeriod are
+	// kept, as are uploads that no room uses yet for StagedPhotoTTL.
+	ReconcileInterval Duration `json:"reconcileInterval"`
+	OrphanGracePeriod Duration `json:"orphanGracePeriod"`
+	StagedPhotoTTL    Duration `json:"stagedPhotoTTL"`
+}
+
+type ImagesS3Config struct {
+	// Endpoint is the base URL of the S3 API, e.g. http://minio:9000.
+	Endpoint  string `json:"endpoint"`
+	Region    string `json:"region"`
+	Bucket    string `json:"bucket"`
+	AccessKey string `json:"accessKey"`
+	SecretKey string `json:"secretKey"`
+	// PathStyle puts the bucket in the path instead of the host name.
+	PathStyle bool `json:"pathStyle"`
+}
+
+type JWTConfig struct {
+	PublicKeyPath string `json:"publicKeyPath"`
+}
+
+type CORSConfig struct {
+	AllowOrigins []string `json:"allowOrigins"`
+}
+
+type SearchConfig struct {
+	// Concurrency is the number of rooms evaluated at once by a search.
+	Concurrency int `json:"concurrency"`
+	// CacheTTL is how long search hits are cached. Zero disables the cache.
+	CacheTTL     Duration `json:"cacheTtl"`
+	CacheEntries int      `json:"cacheEntries"`
+}
+
+// Duration is a time.Duration written as a string ("90s", "5m") in JSON.
+type Duration time.Duration
+
+func (d *Duration) UnmarshalJSON(data []byte) error {
+	var raw string
+	if err := json.Unmarshal(data, &raw); err != nil {
+		return fmt.Errorf("duration must be a string like \"30s\": %w", err)
+	}
+	parsed, err := time.ParseDuration(raw)
+	if err != nil {
+		return err
+	}
+	*d = Duration(parsed)
+	return nil
+}
+
+func (d Duration) MarshalJSON() ([]byte, error) {
+	return json.Marshal(time.Duration(d).String())
+}
+
+func Default() Config {
+	return Config{
+		ServiceName: "room-service",
+		HTTP: HTTPConfig{
+			Addr:               ":8080",
+			ReadHeaderTimeout:  Duration(5 * time.Second),
+			ReadTimeout:        Duration(30 * time.Second),
+			WriteTimeout:       Duration(30 * time.Second),
+			IdleTimeout:        Duration(2 * time.Minute),
+			ShutdownTimeout:    Duration(20 * time.Second),
+			ShutdownDelay:      Duration(5 * time.Second),
+			HealthCheckTimeout: Duration(2 * time.Second),
+		},
+		DB: DBConfig{
+			Port:        5432,
+			SSLMode:     "disable",
+			AutoMigrate: true,
+		},
+		UserService: UserServiceConfig{
+			BaseURL:          "http://user-service:8080/api",
+			Timeout:          Duration(2 * time.Second),
+			MaxRetries:       2,
+			RetryBackoff:     Duration(100 * time.Millisecond),
+			BreakerThreshold: 5,
+			BreakerCooldown:  Duration(30 * time.Second),
+			CacheTTL:         Duration(time.Minute),
+			CacheNegativeTTL: Duration(10 * time.Second),
+			CacheEntries:     10000,
+		},
+		Images: ImagesConfig{
+			Backend:   ImageBackendLocal,
+			Directory: "/app/images/",
+			PublicURL: "/img/",
+			S3: ImagesS3Config{
+				Region:    "us-east-1",
+				PathStyle: true,
+			},
+			MaxFileBytes:      10 << 20,
+			MaxRequestBytes:   50 << 20,
+			MaxFiles:          10,
+			MaxWidth:          8000,
+			MaxHeight:         8000,
+			ReconcileInterval: Duration(time.Hour),
+			OrphanGracePeriod: Duration(time.Hour),
+			StagedPhotoTTL:    Duration(24 * time.Hour),
+		},
+		CORS: CORSConfig{
+			AllowOrigins: []string{"http://localhost:5173", "http://localhost", "http://bookem.local"},
+		},
+		Log: util.LogConfig{
+			Level: "debug",
+			File:  "/app/logs/app.log",
+		},
+		Tracing: util.TracingConfig{
+			Exporter:    util.ExporterOTLPHTTP,
+			SampleRatio: 1,
+		},
+		Search: SearchConfig{
+			Concurrency:  8,
+			CacheTTL:     Duration(time.Minute),
+			CacheEntries: 1000,
+		},
+	}
+}
+
+// Load builds the configuration from the defaults, the file named by
+// CONFIG_FILE and the environment, and validates it.
+func Load() (*Config, error) {
+	config := Default()
+
+	if path := os.Getenv("CONFIG_FILE"); path != "" {
+		if err := config.loadFile(path); err != nil {
+			return nil, err
+		}
+	}
+
+	if err := config.loadEnv(); err != nil {
+		return nil, err
+	}
+
+	if err := config.Validate(); err != nil {
+		return nil, err
+	}
+	return &config, nil
+}
+
+func (c *Config) loadFile(path string) error {
+	file, err := os.Open(path)
+	if err != nil {
+		return fmt.Errorf("could not open config file: %w", err)
+	}
+	defer file.Close()
+
+	decoder := json.NewDecoder(file)
+	decoder.DisallowUnknownFields()
+	if err := decoder.Decode(c); err != nil {
+		return fmt.Errorf("could not parse config file %s: %w", path, err)
+	}
+	return nil
+}
+
+// loadEnv overrides the settings with the environment variables that are set.
+// An empty value counts as set, e.g. LOG_FILE="" disables the log file.
+func (c *Config) loadEnv() error {
+	var errs []error
+	lookup := os.LookupEnv
+
+	str := func(name string, target *string) {
+		if value, ok := lookup(name); ok {
+			*target = value
+		}
+	}
+	integer := func(name string, target *int) {
+		if value, ok := lookup(name); ok {
+			parsed, err := strconv.Atoi(strings.TrimSpace(value))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", name, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	float := func(name string, target *float64) {
+		if value, ok := lookup(name); ok {
+			parsed, err := strconv.ParseFloat(strings.TrimSpace(value), 64)
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a number", name, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	boolean := func(name string, target *bool) {
+		if value, ok := lookup(name); ok {
+			parsed, err := strconv.ParseBool(strings.TrimSpace(value))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a boolean", name, value))
+				return
+			}
+			*target = parsed
+		}
+	}
+	duration := func(name string, target *Duration) {
+		if value, ok := lookup(name); ok {
+			parsed, err := time.ParseDuration(strings.TrimSpace(value))
+			if err != nil {
+				errs = append(errs, fmt.Errorf("%s: %q is not a duration", name, value))
+				return
+			}
+			*target = Duration(parsed)
+		}
+	}
+	list := func(name string, target *[]string) {
+		if value, ok := lookup(name); ok {
+			*target = nil
+			for _, item := range strings.Split(value, ",") {
+				if item = strings.TrimSpace(item); item != "" {
+					*target = append(*target, item)
+				}
+			}
+		}
+	}
+
+	str("SERVICE_NAME", &c.ServiceName)
+	str("DEPLOYMENT_ENV", &c.DeploymentEnv)
+
+	str("HTTP_ADDR", &c.HTTP.Addr)
+	duration("HTTP_READ_HEADER_TIMEOUT", &c.HTTP.ReadHeaderTimeout)
+	duration("HTTP_READ_TIMEOUT", &c.HTTP.ReadTimeout)
+	duration("HTTP_WRITE_TIMEOUT", &c.HTTP.WriteTimeout)
+	duration("HTTP_IDLE_TIMEOUT", &c.HTTP.IdleTimeout)
+	duration("HTTP_SHUTDOWN_TIMEOUT", &c.HTTP.ShutdownTimeout)
+	duration("HTTP_SHUTDOWN_DELAY", &c.HTTP.ShutdownDelay)
+	duration("HTTP_HEALTH_CHECK_TIMEOUT", &c.HTTP.HealthCheckTimeout)
+
+	str("DB_HOST", &c.DB.Host)
+	integer("DB_PORT", &c.DB.Port)
+	str("DB_USER", &c.DB.User)
+	str("DB_PASSWORD", &c.DB.Password)
+	str("DB_NAME", &c.DB.Name)
+	str("DB_SSLMODE", &c.DB.SSLMode)
+	boolean("DB_AUTO_MIGRATE", &c.DB.AutoMigrate)
+
+	str("USER_SERVICE_URL", &c.UserService.BaseURL)
+	duration("USER_SERVICE_TIMEOUT", &c.UserService.Timeout)
+	integer("USER_SERVICE_MAX_RETRIES", &c.UserService.MaxRetries)
+	duration("USER_SERVICE_RETRY_BACKOFF", &c.UserService.RetryBackoff)
+	integer("USER_SERVICE_BREAKER_THRESHOLD", &c.UserService.BreakerThreshold)
+	duration("USER_SERVICE_BREAKER_COOLDOWN", &c.UserService.BreakerCooldown)
+	duration("USER_CACHE_TTL", &c.UserService.CacheTTL)
+	duration("USER_CACHE_NEGATIVE_TTL", &c.UserService.CacheNegativeTTL)
+	integer("USER_CACHE_ENTRIES", &c.UserService.CacheEntries)
+	str("USER_CACHE_INVALIDATION_TOKEN", &c.UserService.InvalidationToken)
+	str("IMG_BACKEND", &c.Images.Backend)
+	str("IMG_DIRECTORY", &c.Images.Directory)
+	str("IMG_PUBLIC_URL", &c.Images.PublicURL)
+	str("IMG_S3_ENDPOINT", &c.Images.S3.Endpoint)
+	str("IMG_S3_REGION", &c.Images.S3.Region)
+	str("IMG_S3_BUCKET", &c.Images.S3.Bucket)
+	str("IMG_S3_ACCESS_KEY", &c.Images.S3.AccessKey)
+	str("IMG_S3_SECRET_KEY", &c.Images.S3.SecretKey)
+	boolean("IMG_S3_PATH_STYLE", &c.Images.S3.PathStyle)
+	integer("IMG_MAX_FILE_BYTES", &c.Images.MaxFileBytes)
+	integer("IMG_MAX_REQUEST_BYTES", &c.Images.MaxRequestBytes)
+	integer("IMG_MAX_FILES", &c.Images.MaxFiles)
+	integer("IMG_MAX_WIDTH", &c.Images.MaxWidth)
+	integer("IMG_MAX_HEIGHT", &c.Images.MaxHeight)
+	duration("IMG_RECONCILE_INTERVAL", &c.Images.ReconcileInterval)
+	duration("IMG_ORPHAN_GRACE_PERIOD", &c.Images.OrphanGracePeriod)
+	duration("IMG_STAGED_PHOTO_TTL", &c.Images.StagedPhotoTTL)
+	str("JWT_PUBLIC_KEY_PATH", &c.JWT.PublicKeyPath)
+	list("CORS_ALLOW_ORIGINS", &c.CORS.AllowOrigins)
+
+	str("LOG_LEVEL", &c.Log.Level)
+	str("LOG_FILE", &c.Log.File)
+
+	str("TRACE_EXPORTER", &c.Tracing.Exporter)
+	float("TRACE_SAMPLE_RATIO", &c.Tracing.SampleRatio)
+
+	integer("SEARCH_CONCURRENCY", &c.Search.Concurrency)
+	duration("SEARCH_CACHE_TTL", &c.Search.CacheTTL)
+	integer("SEARCH_CACHE_ENTRIES", &c.Search.CacheEntries)
+
+	return errors.Join(errs...)
+}
+
+// Validate reports every invalid setting at once.
+func (c *Config) Validate() error {
+	var errs []error
+	invalid := func(format string, args ...any) {
+		errs = append(errs, fmt.Errorf(format, args...))
+	}
+
+	if c.ServiceName == "" {
+		invalid("service name (SERVICE_NAME) is required")
+	}
+
+	if c.HTTP.Addr == "" {
+		invalid("HTTP address (HTTP_ADDR) is required")
+	}
+	for _, timeout := range []struct {
+		name  string
+		value Duration
+	}{
+		{"HTTP_READ_HEADER_TIMEOUT", c.HTTP.ReadHeaderTimeout},
+		{"HTTP_READ_TIMEOUT", c.HTTP.ReadTimeout},
+		{"HTTP_WRITE_TIMEOUT", c.HTTP.WriteTimeout},
+		{"HTTP_IDLE_TIMEOUT", c.HTTP.IdleTimeout},
+		{"HTTP_SHUTDOWN_TIMEOUT", c.HTTP.ShutdownTimeout},
+		{"HTTP_HEALTH_CHECK_TIMEOUT", c.HTTP.HealthCheckTimeout},
+	} {
+		if timeout.value <= 0 {
+			invalid("HTTP timeout (%s) must be positive", timeout.name)
+		}
+	}
+	if c.HTTP.ShutdownDelay < 0 || c.HTTP.ShutdownDelay >= c.HTTP.ShutdownTimeout {
+		invalid("HTTP shutdown delay (HTTP_SHUTDOWN_DELAY) must be at least zero and shorter than HTTP_SHUTDOWN_TIMEOUT")
+	}
+
+	if c.DB.Host == "" {
+		invalid("database host (DB_HOST) is required")
+	}
+	if c.DB.Port < 1 || c.DB.Port > 65535 {
+		invalid("database port (DB_PORT) %d is not between 1 and 65535", c.DB.Port)
+	}
+	if c.DB.User == "" {
+		invalid("database user (DB_USER) is required")
+	}
+	if c.DB.Name == "" {
+		invalid("database name (DB_NAME) is required")
+	}
+
+	if u, err := url.Parse(c.UserService.BaseURL); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+		invalid("user service URL (USER_SERVICE_URL) %q is not an absolute http(s) URL", c.UserService.BaseURL)
+	}
+	if c.UserService.Timeout <= 0 {
+		invalid("user service timeout (USER_SERVICE_TIMEOUT) must be positive")
+	}
+	if c.UserService.MaxRetries < 0 {
+		invalid("user service retries (USER_SERVICE_MAX_RETRIES) must not be negative")
+	}
+	if c.UserService.RetryBackoff < 0 {
+		invalid("user service retry backoff (USER_SERVICE_RETRY_BACKOFF) must not be negative")
+	}
+	if c.UserService.BreakerThreshold < 1 {
+		invalid("user service breaker threshold (USER_SERVICE_BREAKER_THRESHOLD) must be at least 1")
+	}
+	if c.UserService.BreakerCooldown <= 0 {
+		invalid("user service breaker cooldown (USER_SERVICE_BREAKER_COOLDOWN) must be positive")
+	}
+	if c.UserService.CacheTTL < 0 {
+		invalid("user cache TTL (USER_CACHE_TTL) must not be negative")
+	}
+	if c.UserService.CacheNegativeTTL < 0 {
+		invalid("user cache negative TTL (USER_CACHE_NEGATIVE_TTL) must not be negative")
+	}
+	if c.UserService.CacheEntries < 0 {
+		invalid("user cache size (USER_CACHE_ENTRIES) must not be negative")
+	}
+
+	switch c.Images.Backend {
+	case ImageBackendLocal:
+		if c.Images.Directory == "" {
+			invalid("image directory (IMG_DIRECTORY) is required")
+		}
+	case ImageBackendS3:
+		if u, err := url.Parse(c.Images.S3.Endpoint); err != nil || (u.Scheme != "http" && u.Scheme != "https") || u.Host == "" {
+			invalid("S3 endpoint (IMG_S3_ENDPOINT) %q is not an absolute http(s) URL", c.Images.S3.Endpoint)
+		}
+		if c.Images.S3.Region == "" {
+			invalid("S3 region (IMG_S3_REGION) is required")
+		}
+		if c.Images.S3.Bucket == "" {
+			invalid("S3 bucket (IMG_S3_BUCKET) is required")
+		}
+		if c.Images.S3.AccessKey == "" || c.Images.S3.SecretKey == "" {
+			invalid("S3 credentials (IMG_S3_ACCESS_KEY, IMG_S3_SECRET_KEY) are required")
+		}
+	default:
+		invalid("image backend (IMG_BACKEND) %q is not one of local, s3", c.Images.Backend)
+	}
+	if c.Images.PublicURL == "" {
+		invalid("photo URL (IMG_PUBLIC_URL) is required")
+	}
+	if c.Images.MaxFileBytes < 1 {
+		invalid("photo size limit (IMG_MAX_FILE_BYTES) must be positive")
+	}
+	if c.Images.MaxRequestBytes < c.Images.MaxFileBytes {
+		invalid("upload size limit (IMG_MAX_REQUEST_BYTES) must be at least the photo size limit (IMG_MAX_FILE_BYTES)")
+	}
+	if c.Images.MaxFiles < 1 {
+		invalid("photo count limit (IMG_MAX_FILES) must be at least 1")
+	}
+	if c.Images.MaxWidth < 1 || c.Images.MaxHeight < 1 {
+		invalid("photo dimension limits (IMG_MAX_WIDTH, IMG_MAX_HEIGHT) must be positive")
+	}
+	if c.Images.ReconcileInterval < 0 {
+		invalid("photo reconcile interval (IMG_RECONCILE_INTERVAL) must not be negative")
+	}
+	if c.Images.OrphanGracePeriod < Duration(time.Minute) {
+		invalid("orphaned photo grace period (IMG_ORPHAN_GRACE_PERIOD) must be at least a minute")
+	}
+	if c.Images.StagedPhotoTTL < c.Images.OrphanGracePeriod {
+		invalid("staged photo TTL (IMG_STAGED_PHOTO_TTL) must be at least the grace period (IMG_ORPHAN_GRACE_PERIOD)")
+	}
+
+	if c.JWT.PublicKeyPath == "" {
+		invalid("JWT public key path (JWT_PUBLIC_KEY_PATH) is required")
+	}
+
+	if err := c.Log.Validate(); err != nil {
+		invalid("log settings (LOG_LEVEL): %v", err)
+	}
+	if err := c.Tracing.Validate(); err != nil {
+		invalid("tracing settings (TRACE_EXPORTER, TRACE_SAMPLE_RATIO): %v", err)
+	}
+
+	if c.Search.Concurrency < 1 {
+		invalid("search concurrency (SEARCH_CONCURRENCY) must be at least 1")
+	}
+	if c.Search.CacheTTL < 0 {
+		invalid("search cache TTL (SEARCH_CACHE_TTL) must not be negative")
+	}
+	if c.Search.CacheEntries < 0 {
+		invalid("search cache size (SEARCH_CACHE_ENTRIES) must not be negative")
+	}
+
+	if len(errs) > 0 {
+		return fmt.Errorf("invalid configuration:\n%w", errors.Join(errs...))
+	}
+	return nil
+}
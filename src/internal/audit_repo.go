@@ -0,0 +1,30 @@
+package internal
+
+import (
+	"gorm.io/gorm"
+)
+
+type AuditRepo interface {
+	Create(entry *AuditEntry) error
+	// FindByRoom returns the entries about the room, oldest first.
+	FindByRoom(roomId uint) ([]AuditEntry, error)
+}
+
+type auditRepo struct{ db *gorm.DB }
+
+func NewAuditRepo(db *gorm.DB) AuditRepo {
+	return &auditRepo{db}
+}
+
+func (r *auditRepo) Create(entry *AuditEntry) error {
+	return r.db.Create(entry).Error
+}
+
+func (r *auditRepo) FindByRoom(roomId uint) ([]AuditEntry, error) {
+	var entries []AuditEntry
+	err := r.db.Where("room_id = ?", roomId).Order("created_at, id").Find(&entries).Error
+	if err != nil {
+		return nil, err
+	}
+	return entries, nil
+}
@@ -41,7 +41,7 @@ func (r *roomAvailabilityRepo) FindListById(id uint) (*RoomAvailabilityList, err
 
 func (r *roomAvailabilityRepo) FindListsByRoomId(roomId uint) ([]RoomAvailabilityList, error) {
 	var lists []RoomAvailabilityList
-	err := r.db.Preload("Items").Where("room_id = ?", roomId).Find(&lists).Error
+	err := r.db.Preload("Items").Where("room_id = ?", roomId).Order("effective_from DESC, id").Find(&lists).Error
 	if err != nil {
 		return nil, err
 	}
@@ -0,0 +1,72 @@
+package internal
+
+import (
+	"gorm.io/gorm"
+)
+
+type CommodityRepo interface {
+	Create(commodity *Commodity) error
+	Update(commodity *Commodity) error
+	Delete(key string) error
+	FindByKey(key string) (*Commodity, error)
+	// FindByKeys returns the commodities with the given keys, skipping
+	// unknown ones.
+	FindByKeys(keys []string) ([]Commodity, error)
+	FindAll() ([]Commodity, error)
+	// CountRooms returns the number of rooms, deleted ones included, that
+	// have the commodity.
+	CountRooms(key string) (int64, error)
+}
+
+type commodityRepo struct{ db *gorm.DB }
+
+func NewCommodityRepo(db *gorm.DB) CommodityRepo {
+	return &commodityRepo{db}
+}
+
+func (r *commodityRepo) Create(commodity *Commodity) error {
+	return r.db.Create(commodity).Error
+}
+
+func (r *commodityRepo) Update(commodity *Commodity) error {
+	return r.db.Save(commodity).Error
+}
+
+func (r *commodityRepo) Delete(key string) error {
+	return r.db.Where("key = ?", key).Delete(&Commodity{}).Error
+}
+
+func (r *commodityRepo) FindByKey(key string) (*Commodity, error) {
+	var commodity Commodity
+	err := r.db.Where("key = ?", key).First(&commodity).Error
+	if err != nil {
+		return nil, err
+	}
+	return &commodity, nil
+}
+
+func (r *commodityRepo) FindByKeys(keys []string) ([]Commodity, error) {
+	var commodities []Commodity
+	err := r.db.Where("key IN ?", keys).Find(&commodities).Error
+	if err != nil {
+		return nil, err
+	}
+	return commodities, nil
+}
+
+func (r *commodityRepo) FindAll() ([]Commodity, error) {
+	var commodities []Commodity
+	err := r.db.Order("category, key").Find(&commodities).Error
+	if err != nil {
+		return nil, err
+	}
+	return commodities, nil
+}
+
+func (r *commodityRepo) CountRooms(key string) (int64, error) {
+	var count int64
+	err := r.db.Model(&Room{}).
+		Where("commodities IS NOT NULL AND jsonb_exists(commodities::jsonb, ?)", key).
+		Count(&count).Error
+	return count, err
+}
@@ -1,19 +1,32 @@
 package internal
 
-import "time"
+import (
+	"strings"
+	"time"
+)
 
 type RoomDTO struct {
-	ID          uint     `json:"id"`
-	HostID      uint     `json:"hostID"`
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Address     string   `json:"address"`
-	MinGuests   uint     `json:"minGuests"`
-	MaxGuests   uint     `json:"maxGuests"`
-	Photos      []string `json:"photos"`
-	Commodities []string `json:"commodities"`
-	AutoApprove bool     `json:"autoApprove"`
-	Deleted     bool     `json:"deleted"`
+	ID          uint       `json:"id"`
+	HostID      uint       `json:"hostID"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Address     string     `json:"address"`
+	MinGuests   uint       `json:"minGuests"`
+	MaxGuests   uint       `json:"maxGuests"`
+	Photos      []PhotoDTO `json:"photos"`
+	Commodities []string   `json:"commodities"`
+	AutoApprove bool       `json:"autoApprove"`
+	Deleted     bool       `json:"deleted"`
+	// Status is one of draft, pending_review, published, suspended and
+	// archived.
+	Status string `json:"status"`
+}
+
+// ManagedRoomDTO is a room as its host and admins see it. StatusComment is
+// what the admin said on the latest change of status, and is for them only.
+type ManagedRoomDTO struct {
+	RoomDTO
+	StatusComment string `json:"statusComment,omitempty"`
 }
 
 type CreateRoomDTO struct {
@@ -24,9 +37,11 @@ type CreateRoomDTO struct {
 	MinGuests     uint     `json:"minGuests"`
 	MaxGuests     uint     `json:"maxGuests"`
 	PhotosPayload []string `json:"photosPayload"`
-	Commodities   []string `json:"commodities"`
-	AutoApprove   bool     `json:"autoApprove"`
-	Deleted       bool     `json:"deleted"`
+	// PhotoIDs refers to photos uploaded with POST /api/photos beforehand.
+	PhotoIDs    []uint   `json:"photoIds"`
+	Commodities []string `json:"commodities"`
+	AutoApprove bool     `json:"autoApprove"`
+	Deleted     bool     `json:"deleted"`
 }
 
 func NewRoomDTO(r *Room) RoomDTO {
@@ -38,11 +53,180 @@ func NewRoomDTO(r *Room) RoomDTO {
 		Address:     r.Address,
 		MinGuests:   r.MinGuests,
 		MaxGuests:   r.MaxGuests,
-		Photos:      r.Photos,
+		Photos:      NewPhotoDTOs(r.Photos),
 		Commodities: r.Commodities,
 		AutoApprove: r.AutoApprove,
 		Deleted:     r.Deleted,
+		Status:      string(r.Status),
+	}
+}
+
+func NewManagedRoomDTO(r *Room) ManagedRoomDTO {
+	return ManagedRoomDTO{RoomDTO: NewRoomDTO(r), StatusComment: r.StatusComment}
+}
+
+type SuspendRoomDTO struct {
+	Reason string `json:"reason"`
+}
+
+// ReviewRoomDTO is what an admin says approving or rejecting a room. A
+// comment is required to reject it.
+type ReviewRoomDTO struct {
+	Comment string `json:"comment"`
+}
+
+type AuditEntryDTO struct {
+	ID        uint      `json:"id"`
+	ActorID   uint      `json:"actorId"`
+	Action    string    `json:"action"`
+	RoomID    *uint     `json:"roomId,omitempty"`
+	Details   string    `json:"details,omitempty"`
+	CreatedAt time.Time `json:"createdAt"`
+}
+
+func NewAuditEntryDTO(e *AuditEntry) AuditEntryDTO {
+	return AuditEntryDTO{
+		ID:        e.ID,
+		ActorID:   e.ActorID,
+		Action:    e.Action,
+		RoomID:    e.RoomID,
+		Details:   e.Details,
+		CreatedAt: e.CreatedAt,
+	}
+}
+
+// RoomHistory is what admins review of a room: every price and availability
+// list it ever had, newest first, and the audit entries about it.
+type RoomHistory struct {
+	Room              *Room
+	PriceLists        []RoomPriceList
+	AvailabilityLists []RoomAvailabilityList
+	Audit             []AuditEntry
+}
+
+type RoomHistoryDTO struct {
+	Room              ManagedRoomDTO            `json:"room"`
+	PriceLists        []RoomPriceListDTO        `json:"priceLists"`
+	AvailabilityLists []RoomAvailabilityListDTO `json:"availabilityLists"`
+	Audit             []AuditEntryDTO           `json:"audit"`
+}
+
+func NewRoomHistoryDTO(h *RoomHistory) RoomHistoryDTO {
+	dto := RoomHistoryDTO{
+		Room:              NewManagedRoomDTO(h.Room),
+		PriceLists:        make([]RoomPriceListDTO, 0, len(h.PriceLists)),
+		AvailabilityLists: make([]RoomAvailabilityListDTO, 0, len(h.AvailabilityLists)),
+		Audit:             make([]AuditEntryDTO, 0, len(h.Audit)),
+	}
+	for _, list := range h.PriceLists {
+		dto.PriceLists = append(dto.PriceLists, NewRoomPriceListDTO(&list))
+	}
+	for _, list := range h.AvailabilityLists {
+		dto.AvailabilityLists = append(dto.AvailabilityLists, NewRoomAvailabilityListDTO(&list))
+	}
+	for _, entry := range h.Audit {
+		dto.Audit = append(dto.Audit, NewAuditEntryDTO(&entry))
+	}
+	return dto
+}
+
+// ---------------------------------------------------------------
+
+var photoBaseURL = "/img/"
+
+// SetPhotoBaseURL sets the URL that photo keys are served under, e.g. the
+// public URL of the bucket.
+func SetPhotoBaseURL(url string) {
+	photoBaseURL = strings.TrimSuffix(url, "/") + "/"
+}
+
+// PhotoDTO is a room photo. URLs holds the URL of the original (under
+// "original") and of every variant; variants a photo has no copy of point at
+// the original, so clients can always pick the size they need.
+type PhotoDTO struct {
+	Key     string            `json:"key"`
+	URLs    map[string]string `json:"urls"`
+	Caption string            `json:"caption"`
+	AltText string            `json:"altText"`
+	Cover   bool              `json:"cover"`
+}
+
+func NewPhotoDTO(p Photo) PhotoDTO {
+	urls := map[string]string{PhotoOriginal: photoBaseURL + p.Key}
+	for _, variant := range photoVariants {
+		key, ok := p.Variants[variant.Name]
+		if !ok {
+			key = p.Key
+		}
+		urls[variant.Name] = photoBaseURL + key
+	}
+	return PhotoDTO{Key: p.Key, URLs: urls, Caption: p.Caption, AltText: p.AltText, Cover: p.Cover}
+}
+
+func NewPhotoDTOs(photos []Photo) []PhotoDTO {
+	result := make([]PhotoDTO, 0, len(photos))
+	for _, photo := range photos {
+		result = append(result, NewPhotoDTO(photo))
 	}
+	return result
+}
+
+type UploadedPhotoDTO struct {
+	ID          uint   `json:"id"`
+	PhotoDTO           // Flattened: key, urls.
+	ContentType string `json:"contentType"`
+	Size        int64  `json:"size"`
+}
+
+func NewUploadedPhotoDTO(p *RoomPhoto) UploadedPhotoDTO {
+	return UploadedPhotoDTO{
+		ID:          p.ID,
+		PhotoDTO:    NewPhotoDTO(p.Photo()),
+		ContentType: p.ContentType,
+		Size:        p.Size,
+	}
+}
+
+type AttachPhotosDTO struct {
+	PhotoIDs []uint `json:"photoIds"`
+}
+
+type UpdatePhotosDTO struct {
+	// Photos lists the photos of the room to keep, in the new order.
+	Photos []PhotoMetadataDTO `json:"photos"`
+	// Cover is the key of the cover photo, or empty for none.
+	Cover string `json:"cover"`
+}
+
+type PhotoMetadataDTO struct {
+	Key     string `json:"key"`
+	Caption string `json:"caption"`
+	AltText string `json:"altText"`
+}
+
+// ---------------------------------------------------------------
+
+type CommodityDTO struct {
+	Key      string            `json:"key"`
+	Category string            `json:"category"`
+	Icon     string            `json:"icon"`
+	Labels   map[string]string `json:"labels"`
+}
+
+func NewCommodityDTO(c *Commodity) CommodityDTO {
+	return CommodityDTO{
+		Key:      c.Key,
+		Category: c.Category,
+		Icon:     c.Icon,
+		Labels:   c.Labels,
+	}
+}
+
+// UpdateCommodityDTO replaces everything but the key of a commodity.
+type UpdateCommodityDTO struct {
+	Category string            `json:"category"`
+	Icon     string            `json:"icon"`
+	Labels   map[string]string `json:"labels"`
 }
 
 // ---------------------------------------------------------------
@@ -159,13 +343,38 @@ func NewRoomPriceItemDTO(item RoomPriceItem) RoomPriceItemDTO {
 	}
 }
 
+const (
+	// SortByRelevance orders search hits by full-text rank (or by ID when no
+	// text query is given).
+	SortByRelevance = "relevance"
+	// SortByPrice orders search hits by total price, cheapest first.
+	SortByPrice = "price"
+
+	// FlexibleModeCheapest picks the cheapest stay in the search window.
+	FlexibleModeCheapest = "cheapest"
+	// FlexibleModeEarliest picks the earliest stay in the search window.
+	FlexibleModeEarliest = "earliest"
+)
+
 type RoomsQueryDTO struct {
 	Address      string    `form:"address"`
+	Query        string    `form:"q"`
+	SortBy       string    `form:"sortBy" binding:"omitempty,oneof=relevance price"`
 	GuestsNumber uint      `form:"guestsNumber" binding:"required,min=1"`
 	DateFrom     time.Time `form:"dateFrom" binding:"required"`
 	DateTo       time.Time `form:"dateTo" binding:"required"`
-	PageNumber   uint      `form:"pageNumber" binding:"required,min=1"`
+	PageNumber   uint      `form:"pageNumber" binding:"required_without=Cursor"`
 	PageSize     uint      `form:"pageSize" binding:"required,min=1"`
+
+	// Cursor is an opaque token from PaginatedResultInfoDTO. When set, it is
+	// used instead of PageNumber.
+	Cursor string `form:"cursor"`
+
+	// StayLength enables flexible-date search. When set, DateFrom and DateTo
+	// are the window in which a stay of StayLength days must fit, and each
+	// hit carries the dates of the best stay according to FlexibleMode.
+	StayLength   uint   `form:"stayLength"`
+	FlexibleMode string `form:"flexibleMode" binding:"omitempty,oneof=cheapest earliest"`
 }
 
 type PaginatedResultInfoDTO struct {
@@ -173,17 +382,28 @@ type PaginatedResultInfoDTO struct {
 	PageSize   uint `json:"pageSize"`
 	TotalPages uint `json:"totalPages"`
 	TotalHits  uint `json:"totalHits"`
+
+	// NextCursor and PrevCursor point at the neighbouring pages and are empty
+	// if there is no such page. Page is 0 when the request used a cursor.
+	NextCursor string `json:"nextCursor,omitempty"`
+	PrevCursor string `json:"prevCursor,omitempty"`
 }
 
 type RoomResultDTO struct {
-	ID          uint     `json:"id"`
-	Name        string   `json:"name"`
-	Description string   `json:"description"`
-	Address     string   `json:"address"`
-	Photos      []string `json:"photos" gorm:"type:text;serializer:json"`
-	PerGuest    bool     `json:"perGuest"`
-	UnitPrice   float32  `json:"unitPrice"`
-	TotalPrice  float32  `json:"totalPrice"`
+	ID          uint       `json:"id"`
+	Name        string     `json:"name"`
+	Description string     `json:"description"`
+	Address     string     `json:"address"`
+	Photos      []PhotoDTO `json:"photos"`
+	PerGuest    bool       `json:"perGuest"`
+	UnitPrice   float32    `json:"unitPrice"`
+	TotalPrice  float32    `json:"totalPrice"`
+	Rank        float32    `json:"rank,omitempty"`
+
+	// DateFrom and DateTo are only set by flexible-date search and hold the
+	// stay that was priced.
+	DateFrom *time.Time `json:"dateFrom,omitempty"`
+	DateTo   *time.Time `json:"dateTo,omitempty"`
 }
 
 func NewRoomResultDTO(room Room, perGuest bool, unitPrice float32, totalPrice float32) RoomResultDTO {
@@ -192,13 +412,35 @@ func NewRoomResultDTO(room Room, perGuest bool, unitPrice float32, totalPrice fl
 		Name:        room.Name,
 		Description: room.Description,
 		Address:     room.Address,
-		Photos:      room.Photos,
+		Photos:      NewPhotoDTOs(coverFirst(room.Photos)),
 		PerGuest:    perGuest,
 		UnitPrice:   unitPrice,
 		TotalPrice:  totalPrice,
+		Rank:        room.Rank,
 	}
 }
 
+// coverFirst returns the photos with the cover moved to the front.
+func coverFirst(photos []Photo) []Photo {
+	for i, photo := range photos {
+		if photo.Cover {
+			result := make([]Photo, 0, len(photos))
+			result = append(result, photo)
+			result = append(result, photos[:i]...)
+			return append(result, photos[i+1:]...)
+		}
+	}
+	return photos
+}
+
+// FlexibleStay is a stay found by flexible-date search.
+type FlexibleStay struct {
+	DateFrom   time.Time
+	DateTo     time.Time
+	TotalPrice float32
+	PerGuest   bool
+}
+
 type RoomsResultDTO struct {
 	Hits []RoomResultDTO        `json:"hits"`
 	Info PaginatedResultInfoDTO `json:"info"`
@@ -2,6 +2,12 @@ package internal
 
 import (
 	"bookem-room-service/util"
+	"context"
+	"errors"
+	"fmt"
+	"io"
+	"iter"
+	"mime/multipart"
 	"net/http"
 	"strconv"
 
@@ -14,6 +20,15 @@ func NewRoute(handler Handler) *Route { return &Route{handler} }
 
 func (r *Route) Route(rg *gin.RouterGroup) {
 	rg.POST("/new", r.handler.createRoom)
+	rg.POST("/photos", r.handler.uploadPhotos)
+	rg.POST("/:id/photos", r.handler.attachPhotos)
+	rg.PUT("/:id/photos", r.handler.updatePhotos)
+	rg.POST("/:id/submit", r.handler.submitRoom)
+	rg.POST("/:id/archive", r.handler.archiveRoom)
+	rg.GET("/commodities", r.handler.findCommodities)
+	rg.POST("/commodities", r.handler.createCommodity)
+	rg.PUT("/commodities/:key", r.handler.updateCommodity)
+	rg.DELETE("/commodities/:key", r.handler.deleteCommodity)
 	rg.GET("/:id", r.handler.findRoomById)
 	rg.GET("/host/:id", r.handler.findRoomsByHostId)
 	rg.DELETE("/host/", r.handler.deleteHostRooms)
@@ -30,6 +45,13 @@ func (r *Route) Route(rg *gin.RouterGroup) {
 	rg.POST("/price", r.handler.updatePriceList)
 
 	rg.POST("/reservation/query", r.handler.queryForReservation)
+
+	rg.GET("/admin/rooms", r.handler.findAllRooms)
+	rg.GET("/admin/rooms/:id/history", r.handler.findRoomHistory)
+	rg.POST("/admin/rooms/:id/suspend", r.handler.suspendRoom)
+	rg.POST("/admin/rooms/:id/restore", r.handler.restoreRoom)
+	rg.POST("/admin/rooms/:id/approve", r.handler.approveRoom)
+	rg.POST("/admin/rooms/:id/reject", r.handler.rejectRoom)
 }
 
 type Handler struct{ service Service }
@@ -37,53 +59,378 @@ type Handler struct{ service Service }
 func NewHandler(s Service) Handler { return Handler{s} }
 
 func (h *Handler) createRoom(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "create-room-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "create-room-api")
+	defer span.End()
 
 	jwt, err := util.GetJwt(ctx)
 	if err != nil {
-		util.TEL.Error("failed fetching JWT", err)
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
 		AbortError(ctx, ErrUnauthenticated)
 		return
 	}
 
 	if jwt.Role != util.Host {
-		util.TEL.Error("user is not host", nil, "role", jwt.Role)
+		util.TEL.Error(reqCtx, "user is not host", nil, "role", jwt.Role)
 		AbortError(ctx, ErrUnauthorized)
 		return
 	}
 
 	var dto CreateRoomDTO
 	if err := ctx.ShouldBindJSON(&dto); err != nil {
-		util.TEL.Error("failed binding JSON", err)
+		util.TEL.Error(reqCtx, "failed binding JSON", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	room, err := h.service.Create(reqCtx, jwt.ID, dto)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed creating room", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusCreated, NewManagedRoomDTO(room))
+}
+
+// UploadLimits bound the photo uploads of a single request.
+type UploadLimits struct {
+	MaxFileBytes    int64
+	MaxRequestBytes int64
+	MaxFiles        int
+}
+
+var uploadLimits = UploadLimits{
+	MaxFileBytes:    10 << 20,
+	MaxRequestBytes: 50 << 20,
+	MaxFiles:        10,
+}
+
+func SetUploadLimits(limits UploadLimits) {
+	uploadLimits = limits
+}
+
+// uploadPhotos stores the photos sent as "photos" parts of a multipart body.
+// Parts are read one at a time instead of parsing the whole form, and each
+// is stored before the next is read, so that no more than one photo is held
+// in memory and no more than the limits is ever read.
+func (h *Handler) uploadPhotos(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "upload-photos-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Host {
+		util.TEL.Error(reqCtx, "user is not host", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	limits := uploadLimits
+	ctx.Request.Body = http.MaxBytesReader(ctx.Writer, ctx.Request.Body, limits.MaxRequestBytes)
+
+	reader, err := ctx.Request.MultipartReader()
+	if err != nil {
+		util.TEL.Error(reqCtx, "request is not multipart", err)
+		AbortError(ctx, ErrBadRequestCustom("Expected a multipart/form-data body"))
+		return
+	}
+
+	photos, err := h.service.UploadPhotos(reqCtx, jwt.ID, readUploadedPhotos(reqCtx, reader, limits))
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed uploading photos", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	result := make([]UploadedPhotoDTO, 0, len(photos))
+	for _, photo := range photos {
+		result = append(result, NewUploadedPhotoDTO(&photo))
+	}
+
+	ctx.JSON(http.StatusCreated, result)
+}
+
+// readUploadedPhotos yields the "photos" parts of a multipart body one at a
+// time, or an error once a part breaks the limits or cannot be read.
+func readUploadedPhotos(ctx context.Context, reader *multipart.Reader, limits UploadLimits) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		count := 0
+		for {
+			part, err := reader.NextPart()
+			if err == io.EOF {
+				return
+			}
+			if err != nil {
+				util.TEL.Error(ctx, "failed reading multipart body", err)
+				yield(nil, uploadReadError(err, limits))
+				return
+			}
+
+			if part.FormName() != "photos" {
+				part.Close()
+				continue
+			}
+
+			if count == limits.MaxFiles {
+				util.TEL.Error(ctx, "too many photos", nil, "max", limits.MaxFiles)
+				yield(nil, ErrPayloadTooLarge(fmt.Sprintf("At most %d photos can be uploaded at once", limits.MaxFiles)))
+				return
+			}
+
+			data, err := io.ReadAll(io.LimitReader(part, limits.MaxFileBytes+1))
+			part.Close()
+			if err != nil {
+				util.TEL.Error(ctx, "failed reading photo", err, "index", count)
+				yield(nil, uploadReadError(err, limits))
+				return
+			}
+			if int64(len(data)) > limits.MaxFileBytes {
+				util.TEL.Error(ctx, "photo too large", nil, "index", count, "max", limits.MaxFileBytes)
+				yield(nil, ErrPayloadTooLarge(fmt.Sprintf("Photo %d is larger than %d bytes", count, limits.MaxFileBytes)))
+				return
+			}
+
+			count++
+			if !yield(data, nil) {
+				return
+			}
+		}
+	}
+}
+
+// optionalCallerID returns the ID of the caller of an endpoint anyone may
+// call, or nil if the request carries no valid JWT.
+func optionalCallerID(ctx *gin.Context) *uint {
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		return nil
+	}
+	return &jwt.ID
+}
+
+// uploadReadError tells a body over the request limit apart from a malformed one.
+func uploadReadError(err error, limits UploadLimits) error {
+	var tooLarge *http.MaxBytesError
+	if errors.As(err, &tooLarge) {
+		return ErrPayloadTooLarge(fmt.Sprintf("Request is larger than %d bytes", limits.MaxRequestBytes))
+	}
+	return ErrBadRequestCustom("Malformed multipart body")
+}
+
+func (h *Handler) attachPhotos(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "attach-photos-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Host {
+		util.TEL.Error(reqCtx, "user is not host", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
+		AbortError(ctx, ErrBadRequest)
+		return
+	}
+
+	var dto AttachPhotosDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		util.TEL.Error(reqCtx, "failed binding JSON", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	room, err := h.service.AttachPhotos(reqCtx, jwt.ID, uint(id), dto.PhotoIDs)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed attaching photos", err, "id", id)
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewManagedRoomDTO(room))
+}
+
+func (h *Handler) updatePhotos(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "update-photos-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Host {
+		util.TEL.Error(reqCtx, "user is not host", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
+		AbortError(ctx, ErrBadRequest)
+		return
+	}
+
+	var dto UpdatePhotosDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		util.TEL.Error(reqCtx, "failed binding JSON", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	room, err := h.service.UpdatePhotos(reqCtx, jwt.ID, uint(id), dto)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed updating photos", err, "id", id)
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewManagedRoomDTO(room))
+}
+
+func (h *Handler) findCommodities(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-commodities-api")
+	defer span.End()
+
+	commodities, err := h.service.FindCommodities(reqCtx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not find commodities", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	result := make([]CommodityDTO, 0, len(commodities))
+	for _, commodity := range commodities {
+		result = append(result, NewCommodityDTO(&commodity))
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) createCommodity(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "create-commodity-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Admin {
+		util.TEL.Error(reqCtx, "user is not admin", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	var dto CommodityDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		util.TEL.Error(reqCtx, "failed binding JSON", err)
 		AbortError(ctx, err)
 		return
 	}
 
-	room, err := h.service.Create(util.TEL.Ctx(), jwt.ID, dto)
+	commodity, err := h.service.CreateCommodity(reqCtx, jwt.ID, dto)
 	if err != nil {
-		util.TEL.Error("failed creating room", err)
+		util.TEL.Error(reqCtx, "failed creating commodity", err)
 		AbortError(ctx, err)
 		return
 	}
 
-	ctx.JSON(http.StatusCreated, NewRoomDTO(room))
+	ctx.JSON(http.StatusCreated, NewCommodityDTO(commodity))
+}
+
+func (h *Handler) updateCommodity(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "update-commodity-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Admin {
+		util.TEL.Error(reqCtx, "user is not admin", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	var dto UpdateCommodityDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		util.TEL.Error(reqCtx, "failed binding JSON", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	commodity, err := h.service.UpdateCommodity(reqCtx, jwt.ID, ctx.Param("key"), dto)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed updating commodity", err, "key", ctx.Param("key"))
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewCommodityDTO(commodity))
+}
+
+func (h *Handler) deleteCommodity(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "delete-commodity-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Admin {
+		util.TEL.Error(reqCtx, "user is not admin", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	if err := h.service.DeleteCommodity(reqCtx, jwt.ID, ctx.Param("key")); err != nil {
+		util.TEL.Error(reqCtx, "failed deleting commodity", err, "key", ctx.Param("key"))
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.Status(http.StatusNoContent)
 }
 
 func (h *Handler) findRoomById(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "find-room-by-id-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-room-by-id-api")
+	defer span.End()
 
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		util.TEL.Error("could not parse ID into a number", err, "id", ctx.Param("id"))
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
 		AbortError(ctx, ErrBadRequest)
 		return
 	}
 
-	room, err := h.service.FindById(util.TEL.Ctx(), uint(id))
+	room, err := h.service.FindById(reqCtx, uint(id))
 	if err != nil {
-		util.TEL.Error("failed finding room", err, "id", id)
+		util.TEL.Error(reqCtx, "failed finding room", err, "id", id)
 		AbortError(ctx, err)
 		return
 	}
@@ -92,24 +439,41 @@ func (h *Handler) findRoomById(ctx *gin.Context) {
 }
 
 func (h *Handler) findRoomsByHostId(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "find-rooms-by-host-id-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-rooms-by-host-id-api")
+	defer span.End()
 
 	id, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		util.TEL.Error("could not parse ID into a number", err, "id", ctx.Param("id"))
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
 		AbortError(ctx, ErrBadRequest)
 		return
 	}
 
-	rooms, err := h.service.FindByHost(util.TEL.Ctx(), uint(id))
+	// Anyone may call this; the JWT only lets the host and admins see more.
+	rooms, seesAll, err := h.service.FindByHost(reqCtx, optionalCallerID(ctx), uint(id))
 	if err != nil {
-		util.TEL.Error("could not find rooms by host", err, "host_id", id)
+		util.TEL.Error(reqCtx, "could not find rooms by host", err, "host_id", id)
 		AbortError(ctx, err)
 		return
 	}
 
-	util.TEL.Debug("creating json output with rooms", "count", len(rooms))
+	rooms, err = paginateList(ctx, rooms, roomsByID)
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not paginate rooms", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	util.TEL.Debug(reqCtx, "creating json output with rooms", "count", len(rooms))
+	if seesAll {
+		result := make([]ManagedRoomDTO, 0)
+		for _, room := range rooms {
+			result = append(result, NewManagedRoomDTO(&room))
+		}
+		ctx.JSON(http.StatusOK, result)
+		return
+	}
+
 	result := make([]RoomDTO, 0)
 	for _, room := range rooms {
 		result = append(result, NewRoomDTO(&room))
@@ -119,19 +483,19 @@ func (h *Handler) findRoomsByHostId(ctx *gin.Context) {
 }
 
 func (h *Handler) findCurrentAvailabilityListOfRoom(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "find-current-availability-list-of-room-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-current-availability-list-of-room-api")
+	defer span.End()
 
 	roomId, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		util.TEL.Error("could not parse ID into a number", err, "id", ctx.Param("id"))
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
 		AbortError(ctx, ErrBadRequest)
 		return
 	}
 
-	list, err := h.service.FindCurrentAvailabilityListOfRoom(util.TEL.Ctx(), uint(roomId))
+	list, err := h.service.FindCurrentAvailabilityListOfRoom(reqCtx, optionalCallerID(ctx), uint(roomId))
 	if err != nil {
-		util.TEL.Error("could not get current availability list of room", err, "id", roomId)
+		util.TEL.Error(reqCtx, "could not get current availability list of room", err, "id", roomId)
 		AbortError(ctx, err)
 		return
 	}
@@ -141,24 +505,31 @@ func (h *Handler) findCurrentAvailabilityListOfRoom(ctx *gin.Context) {
 }
 
 func (h *Handler) findAvailabilityListsByRoomId(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "find-availability-lists-by-room-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-availability-lists-by-room-api")
+	defer span.End()
 
 	roomId, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		util.TEL.Error("could not parse ID into a number", err, "id", ctx.Param("id"))
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
 		AbortError(ctx, ErrBadRequest)
 		return
 	}
 
-	lists, err := h.service.FindAvailabilityListsByRoomId(util.TEL.Ctx(), uint(roomId))
+	lists, err := h.service.FindAvailabilityListsByRoomId(reqCtx, optionalCallerID(ctx), uint(roomId))
 	if err != nil {
-		util.TEL.Error("could not find availability lists of room", err, "id", roomId)
+		util.TEL.Error(reqCtx, "could not find availability lists of room", err, "id", roomId)
 		AbortError(ctx, err)
 		return
 	}
 
-	util.TEL.Debug("creating json output with availability lists", "count", len(lists))
+	lists, err = paginateList(ctx, lists, availabilityListsByNewest)
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not paginate availability lists", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	util.TEL.Debug(reqCtx, "creating json output with availability lists", "count", len(lists))
 
 	result := make([]RoomAvailabilityListDTO, 0)
 	for _, list := range lists {
@@ -168,19 +539,19 @@ func (h *Handler) findAvailabilityListsByRoomId(ctx *gin.Context) {
 }
 
 func (h *Handler) findAvailabilityListById(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "find-availability-list-by-id-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-availability-list-by-id-api")
+	defer span.End()
 
 	listId, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		util.TEL.Error("could not parse ID into a number", err, "id", ctx.Param("id"))
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
 		AbortError(ctx, ErrBadRequest)
 		return
 	}
 
-	list, err := h.service.FindAvailabilityListById(util.TEL.Ctx(), uint(listId))
+	list, err := h.service.FindAvailabilityListById(reqCtx, optionalCallerID(ctx), uint(listId))
 	if err != nil {
-		util.TEL.Error("could not find availability list", err, "list_id", listId)
+		util.TEL.Error(reqCtx, "could not find availability list", err, "list_id", listId)
 		AbortError(ctx, err)
 		return
 	}
@@ -190,32 +561,32 @@ func (h *Handler) findAvailabilityListById(ctx *gin.Context) {
 }
 
 func (h *Handler) updateAvailability(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "update-room-availability-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "update-room-availability-api")
+	defer span.End()
 
 	jwt, err := util.GetJwt(ctx)
 	if err != nil {
-		util.TEL.Error("could not get JWT", err)
+		util.TEL.Error(reqCtx, "could not get JWT", err)
 		AbortError(ctx, ErrUnauthenticated)
 		return
 	}
 
 	if jwt.Role != util.Host {
-		util.TEL.Error("user is not host", nil, "role", jwt.Role)
+		util.TEL.Error(reqCtx, "user is not host", nil, "role", jwt.Role)
 		AbortError(ctx, ErrUnauthorized)
 		return
 	}
 
 	var dto CreateRoomAvailabilityListDTO
 	if err := ctx.ShouldBindJSON(&dto); err != nil {
-		util.TEL.Error("failed to bind JSON", err)
+		util.TEL.Error(reqCtx, "failed to bind JSON", err)
 		AbortError(ctx, err)
 		return
 	}
 
-	list, err := h.service.UpdateAvailability(util.TEL.Ctx(), jwt.ID, dto)
+	list, err := h.service.UpdateAvailability(reqCtx, jwt.ID, dto)
 	if err != nil {
-		util.TEL.Error("could not update room availability", err)
+		util.TEL.Error(reqCtx, "could not update room availability", err)
 		AbortError(ctx, err)
 		return
 	}
@@ -224,32 +595,32 @@ func (h *Handler) updateAvailability(ctx *gin.Context) {
 }
 
 func (h *Handler) queryForReservation(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "query-room-for-reservation-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "query-room-for-reservation-api")
+	defer span.End()
 
 	jwt, err := util.GetJwt(ctx)
 	if err != nil {
-		util.TEL.Error("could not get JWT", err)
+		util.TEL.Error(reqCtx, "could not get JWT", err)
 		AbortError(ctx, ErrUnauthenticated)
 		return
 	}
 
 	if jwt.Role != util.Guest {
-		util.TEL.Error("user is not guest", nil, "role", jwt.Role)
+		util.TEL.Error(reqCtx, "user is not guest", nil, "role", jwt.Role)
 		AbortError(ctx, ErrUnauthorized)
 		return
 	}
 
 	var dto RoomReservationQueryDTO
 	if err := ctx.ShouldBindJSON(&dto); err != nil {
-		util.TEL.Error("failed to bind JSON", err)
+		util.TEL.Error(reqCtx, "failed to bind JSON", err)
 		AbortError(ctx, err)
 		return
 	}
 
-	result, err := h.service.QueryForReservation(util.TEL.Ctx(), jwt.ID, dto)
+	result, err := h.service.QueryForReservation(reqCtx, jwt.ID, dto)
 	if err != nil {
-		util.TEL.Error("could not query room for availability", err)
+		util.TEL.Error(reqCtx, "could not query room for availability", err)
 		AbortError(ctx, err)
 		return
 	}
@@ -258,19 +629,19 @@ func (h *Handler) queryForReservation(ctx *gin.Context) {
 }
 
 func (h *Handler) findCurrentPriceListOfRoom(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "find-current-price-list-of-room-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-current-price-list-of-room-api")
+	defer span.End()
 
 	roomId, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		util.TEL.Error("could not parse ID into a number", err, "id", ctx.Param("id"))
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
 		AbortError(ctx, ErrBadRequest)
 		return
 	}
 
-	list, err := h.service.FindCurrentPriceListOfRoom(util.TEL.Ctx(), uint(roomId))
+	list, err := h.service.FindCurrentPriceListOfRoom(reqCtx, optionalCallerID(ctx), uint(roomId))
 	if err != nil {
-		util.TEL.Error("could not get current price list of room", err, "id", roomId)
+		util.TEL.Error(reqCtx, "could not get current price list of room", err, "id", roomId)
 		AbortError(ctx, err)
 		return
 	}
@@ -279,24 +650,31 @@ func (h *Handler) findCurrentPriceListOfRoom(ctx *gin.Context) {
 }
 
 func (h *Handler) findPriceListsByRoomId(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "find-price-lists-by-room-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-price-lists-by-room-api")
+	defer span.End()
 
 	roomId, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		util.TEL.Error("could not parse ID into a number", err, "id", ctx.Param("id"))
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
 		AbortError(ctx, ErrBadRequest)
 		return
 	}
 
-	lists, err := h.service.FindPriceListsByRoomId(util.TEL.Ctx(), uint(roomId))
+	lists, err := h.service.FindPriceListsByRoomId(reqCtx, optionalCallerID(ctx), uint(roomId))
 	if err != nil {
-		util.TEL.Error("could not find price lists of room", err, "id", roomId)
+		util.TEL.Error(reqCtx, "could not find price lists of room", err, "id", roomId)
 		AbortError(ctx, err)
 		return
 	}
 
-	util.TEL.Debug("creating json output with price lists", "count", len(lists))
+	lists, err = paginateList(ctx, lists, priceListsByNewest)
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not paginate price lists", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	util.TEL.Debug(reqCtx, "creating json output with price lists", "count", len(lists))
 
 	result := make([]RoomPriceListDTO, 0)
 	for _, list := range lists {
@@ -307,19 +685,19 @@ func (h *Handler) findPriceListsByRoomId(ctx *gin.Context) {
 }
 
 func (h *Handler) findPriceListById(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "find-price-list-by-id-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-price-list-by-id-api")
+	defer span.End()
 
 	listId, err := strconv.Atoi(ctx.Param("id"))
 	if err != nil {
-		util.TEL.Error("could not parse ID into a number", err, "id", ctx.Param("id"))
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
 		AbortError(ctx, ErrBadRequest)
 		return
 	}
 
-	list, err := h.service.FindPriceListById(util.TEL.Ctx(), uint(listId))
+	list, err := h.service.FindPriceListById(reqCtx, optionalCallerID(ctx), uint(listId))
 	if err != nil {
-		util.TEL.Error("could not find price list", err, "id", listId)
+		util.TEL.Error(reqCtx, "could not find price list", err, "id", listId)
 		AbortError(ctx, err)
 		return
 	}
@@ -328,32 +706,32 @@ func (h *Handler) findPriceListById(ctx *gin.Context) {
 }
 
 func (h *Handler) updatePriceList(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "update-room-pricelist-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "update-room-pricelist-api")
+	defer span.End()
 
 	jwt, err := util.GetJwt(ctx)
 	if err != nil {
-		util.TEL.Error("could not get JWT", err)
+		util.TEL.Error(reqCtx, "could not get JWT", err)
 		AbortError(ctx, ErrUnauthenticated)
 		return
 	}
 
 	if jwt.Role != util.Host {
-		util.TEL.Error("user is not host", nil, "role", jwt.Role)
+		util.TEL.Error(reqCtx, "user is not host", nil, "role", jwt.Role)
 		AbortError(ctx, ErrUnauthorized)
 		return
 	}
 
 	var dto CreateRoomPriceListDTO
 	if err := ctx.ShouldBindJSON(&dto); err != nil {
-		util.TEL.Error("failed to bind JSON", err)
+		util.TEL.Error(reqCtx, "failed to bind JSON", err)
 		AbortError(ctx, err)
 		return
 	}
 
-	list, err := h.service.UpdatePriceList(util.TEL.Ctx(), jwt.ID, dto)
+	list, err := h.service.UpdatePriceList(reqCtx, jwt.ID, dto)
 	if err != nil {
-		util.TEL.Error("could not update room pricelist", err)
+		util.TEL.Error(reqCtx, "could not update room pricelist", err)
 		AbortError(ctx, err)
 		return
 	}
@@ -362,19 +740,19 @@ func (h *Handler) updatePriceList(ctx *gin.Context) {
 }
 
 func (h *Handler) findAvailableRooms(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "find-available-rooms-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-available-rooms-api")
+	defer span.End()
 
 	var dto RoomsQueryDTO
 	if err := ctx.ShouldBindQuery(&dto); err != nil {
-		util.TEL.Error("failed to bind query", err)
+		util.TEL.Error(reqCtx, "failed to bind query", err)
 		AbortError(ctx, err)
 		return
 	}
 
-	rooms, resultInfo, err := h.service.FindAvailableRooms(util.TEL.Ctx(), dto)
+	rooms, resultInfo, err := h.service.FindAvailableRooms(reqCtx, dto)
 	if err != nil {
-		util.TEL.Error("failed to find available rooms", err)
+		util.TEL.Error(reqCtx, "failed to find available rooms", err)
 		AbortError(ctx, err)
 		return
 	}
@@ -383,34 +761,345 @@ func (h *Handler) findAvailableRooms(ctx *gin.Context) {
 }
 
 func (h *Handler) deleteHostRooms(ctx *gin.Context) {
-	util.TEL.Push(ctx.Request.Context(), "delete-rooms-by-host-id-api")
-	defer util.TEL.Pop()
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "delete-rooms-by-host-id-api")
+	defer span.End()
 
 	jwt, err := util.GetJwt(ctx)
 	if err != nil {
-		util.TEL.Error("could not get JWT", err)
+		util.TEL.Error(reqCtx, "could not get JWT", err)
 		AbortError(ctx, ErrUnauthenticated)
 		return
 	}
 
 	if jwt.Role != util.Host {
-		util.TEL.Error("user is not host", nil, "role", jwt.Role)
+		util.TEL.Error(reqCtx, "user is not host", nil, "role", jwt.Role)
 		AbortError(ctx, ErrUnauthorized)
 		return
 	}
 
-	rooms, err := h.service.DeleteRoomsByHostId(util.TEL.Ctx(), jwt.ID)
+	rooms, err := h.service.DeleteRoomsByHostId(reqCtx, jwt.ID)
 	if err != nil {
-		util.TEL.Error("could not delete rooms by host", err, "host_id", jwt.ID)
+		util.TEL.Error(reqCtx, "could not delete rooms by host", err, "host_id", jwt.ID)
 		AbortError(ctx, err)
 		return
 	}
 
-	util.TEL.Debug("creating json output with rooms", "count", len(rooms))
-	result := make([]RoomDTO, 0)
+	util.TEL.Debug(reqCtx, "creating json output with rooms", "count", len(rooms))
+	result := make([]ManagedRoomDTO, 0)
 	for _, room := range rooms {
-		result = append(result, NewRoomDTO(&room))
+		result = append(result, NewManagedRoomDTO(&room))
+	}
+
+	ctx.JSON(http.StatusOK, result)
+}
+
+func (h *Handler) findAllRooms(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-all-rooms-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Admin {
+		util.TEL.Error(reqCtx, "user is not admin", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	var hostId *uint
+	if param := ctx.Query("hostId"); param != "" {
+		id, err := strconv.Atoi(param)
+		if err != nil || id < 0 {
+			util.TEL.Error(reqCtx, "could not parse host ID into a number", err, "host_id", param)
+			AbortError(ctx, ErrBadRequest)
+			return
+		}
+		host := uint(id)
+		hostId = &host
+	}
+
+	rooms, err := h.service.FindAllRooms(reqCtx, jwt.ID, hostId)
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not find rooms", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	rooms, err = paginateList(ctx, rooms, roomsByID)
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not paginate rooms", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	util.TEL.Debug(reqCtx, "creating json output with rooms", "count", len(rooms))
+	result := make([]ManagedRoomDTO, 0)
+	for _, room := range rooms {
+		result = append(result, NewManagedRoomDTO(&room))
 	}
 
 	ctx.JSON(http.StatusOK, result)
 }
+
+func (h *Handler) findRoomHistory(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "find-room-history-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Admin {
+		util.TEL.Error(reqCtx, "user is not admin", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
+		AbortError(ctx, ErrBadRequest)
+		return
+	}
+
+	history, err := h.service.FindRoomHistory(reqCtx, jwt.ID, uint(id))
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed finding room history", err, "id", id)
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewRoomHistoryDTO(history))
+}
+
+func (h *Handler) suspendRoom(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "suspend-room-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Admin {
+		util.TEL.Error(reqCtx, "user is not admin", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
+		AbortError(ctx, ErrBadRequest)
+		return
+	}
+
+	var dto SuspendRoomDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		util.TEL.Error(reqCtx, "failed binding JSON", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	room, err := h.service.SuspendRoom(reqCtx, jwt.ID, uint(id), dto.Reason)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed suspending room", err, "id", id)
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewManagedRoomDTO(room))
+}
+
+func (h *Handler) restoreRoom(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "restore-room-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Admin {
+		util.TEL.Error(reqCtx, "user is not admin", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
+		AbortError(ctx, ErrBadRequest)
+		return
+	}
+
+	room, err := h.service.RestoreRoom(reqCtx, jwt.ID, uint(id))
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed restoring room", err, "id", id)
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewManagedRoomDTO(room))
+}
+
+func (h *Handler) submitRoom(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "submit-room-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Host {
+		util.TEL.Error(reqCtx, "user is not host", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
+		AbortError(ctx, ErrBadRequest)
+		return
+	}
+
+	room, err := h.service.SubmitRoom(reqCtx, jwt.ID, uint(id))
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed submitting room", err, "id", id)
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewManagedRoomDTO(room))
+}
+
+func (h *Handler) archiveRoom(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "archive-room-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Host {
+		util.TEL.Error(reqCtx, "user is not host", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
+		AbortError(ctx, ErrBadRequest)
+		return
+	}
+
+	room, err := h.service.ArchiveRoom(reqCtx, jwt.ID, uint(id))
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed archiving room", err, "id", id)
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewManagedRoomDTO(room))
+}
+
+func (h *Handler) approveRoom(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "approve-room-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Admin {
+		util.TEL.Error(reqCtx, "user is not admin", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
+		AbortError(ctx, ErrBadRequest)
+		return
+	}
+
+	var dto ReviewRoomDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		util.TEL.Error(reqCtx, "failed binding JSON", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	room, err := h.service.ApproveRoom(reqCtx, jwt.ID, uint(id), dto.Comment)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed approving room", err, "id", id)
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewManagedRoomDTO(room))
+}
+
+func (h *Handler) rejectRoom(ctx *gin.Context) {
+	reqCtx, span := util.TEL.Start(ctx.Request.Context(), "reject-room-api")
+	defer span.End()
+
+	jwt, err := util.GetJwt(ctx)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed fetching JWT", err)
+		AbortError(ctx, ErrUnauthenticated)
+		return
+	}
+
+	if jwt.Role != util.Admin {
+		util.TEL.Error(reqCtx, "user is not admin", nil, "role", jwt.Role)
+		AbortError(ctx, ErrUnauthorized)
+		return
+	}
+
+	id, err := strconv.Atoi(ctx.Param("id"))
+	if err != nil {
+		util.TEL.Error(reqCtx, "could not parse ID into a number", err, "id", ctx.Param("id"))
+		AbortError(ctx, ErrBadRequest)
+		return
+	}
+
+	var dto ReviewRoomDTO
+	if err := ctx.ShouldBindJSON(&dto); err != nil {
+		util.TEL.Error(reqCtx, "failed binding JSON", err)
+		AbortError(ctx, err)
+		return
+	}
+
+	room, err := h.service.RejectRoom(reqCtx, jwt.ID, uint(id), dto.Comment)
+	if err != nil {
+		util.TEL.Error(reqCtx, "failed rejecting room", err, "id", id)
+		AbortError(ctx, err)
+		return
+	}
+
+	ctx.JSON(http.StatusOK, NewManagedRoomDTO(room))
+}
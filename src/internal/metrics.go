@@ -0,0 +1,129 @@
+package internal
+
+import (
+	"bookem-room-service/util"
+	"errors"
+	"time"
+
+	"github.com/prometheus/client_golang/prometheus"
+	"gorm.io/gorm"
+)
+
+// Domain metrics.
+var (
+	searchesTotal = util.RegisterCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "room_searches_total",
+			Help: "Total number of executed room searches",
+		},
+		[]string{"mode", "cache"},
+	))
+
+	searchHits = util.RegisterCollector(prometheus.NewHistogram(
+		prometheus.HistogramOpts{
+			Name:    "room_search_hits",
+			Help:    "Number of rooms found by a search",
+			Buckets: []float64{0, 1, 5, 10, 25, 50, 100, 250, 500},
+		},
+	))
+
+	roomsCreatedTotal = util.RegisterCollector(prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "rooms_created_total",
+			Help: "Total number of created rooms",
+		},
+	))
+
+	availabilityListUpdatesTotal = util.RegisterCollector(prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "room_availability_list_updates_total",
+			Help: "Total number of room availability list updates",
+		},
+	))
+
+	priceListUpdatesTotal = util.RegisterCollector(prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "room_price_list_updates_total",
+			Help: "Total number of room price list updates",
+		},
+	))
+
+	orphanedPhotosDeletedTotal = util.RegisterCollector(prometheus.NewCounter(
+		prometheus.CounterOpts{
+			Name: "room_orphaned_photos_deleted_total",
+			Help: "Total number of stored images deleted because nothing referenced them",
+		},
+	))
+
+	quoteFailuresTotal = util.RegisterCollector(prometheus.NewCounterVec(
+		prometheus.CounterOpts{
+			Name: "room_quote_failures_total",
+			Help: "Total number of reservation quotes that could not be given, by reason",
+		},
+		[]string{"reason"},
+	))
+)
+
+// Reasons of quoteFailuresTotal.
+const (
+	quoteFailureUserNotFound           = "user_not_found"
+	quoteFailureUserServiceUnavailable = "user_service_unavailable"
+	quoteFailureNotGuest               = "not_guest"
+	quoteFailureRoomNotFound           = "room_not_found"
+	quoteFailureUnavailable            = "unavailable"
+	quoteFailurePrice                  = "price_error"
+)
+
+// ---------------------------------------------------------------
+
+var dbQueryDuration = util.RegisterCollector(prometheus.NewHistogramVec(
+	prometheus.HistogramOpts{
+		Name:    "db_query_duration_seconds",
+		Help:    "Duration of database queries in seconds",
+		Buckets: prometheus.DefBuckets,
+	},
+	[]string{"operation", "table", "status"},
+))
+
+const dbQueryStartKey = "metrics:start"
+
+// RegisterDBMetrics installs GORM callbacks that observe the duration of every
+// query made through db in db_query_duration_seconds.
+func RegisterDBMetrics(db *gorm.DB) error {
+	before := func(tx *gorm.DB) {
+		tx.InstanceSet(dbQueryStartKey, time.Now())
+	}
+
+	after := func(operation string) func(tx *gorm.DB) {
+		return func(tx *gorm.DB) {
+			start, ok := tx.InstanceGet(dbQueryStartKey)
+			if !ok {
+				return
+			}
+
+			status := "ok"
+			if tx.Error != nil && !errors.Is(tx.Error, gorm.ErrRecordNotFound) {
+				status = "error"
+			}
+
+			dbQueryDuration.WithLabelValues(operation, tx.Statement.Table, status).
+				Observe(time.Since(start.(time.Time)).Seconds())
+		}
+	}
+
+	callback := db.Callback()
+	return errors.Join(
+		callback.Create().Before("*").Register("metrics:before_create", before),
+		callback.Create().After("*").Register("metrics:after_create", after("create")),
+		callback.Query().Before("*").Register("metrics:before_query", before),
+		callback.Query().After("*").Register("metrics:after_query", after("query")),
+		callback.Update().Before("*").Register("metrics:before_update", before),
+		callback.Update().After("*").Register("metrics:after_update", after("update")),
+		callback.Delete().Before("*").Register("metrics:before_delete", before),
+		callback.Delete().After("*").Register("metrics:after_delete", after("delete")),
+		callback.Row().Before("*").Register("metrics:before_row", before),
+		callback.Row().After("*").Register("metrics:after_row", after("row")),
+		callback.Raw().Before("*").Register("metrics:before_raw", before),
+		callback.Raw().After("*").Register("metrics:after_raw", after("raw")),
+	)
+}
@@ -3,8 +3,8 @@ package internal
 import (
 	"bookem-room-service/util"
 	"fmt"
-	"log"
 	"net/http"
+	"time"
 
 	"github.com/gin-gonic/gin"
 	"github.com/prometheus/client_golang/prometheus"
@@ -26,6 +26,20 @@ func ErrNotFound(resourceName string, id uint) *APIError {
 	}
 }
 
+func ErrNotFoundByKey(resourceName string, key string) *APIError {
+	return &APIError{
+		Code:    http.StatusNotFound,
+		Message: fmt.Sprintf("Resource %s with key %s not found", resourceName, key),
+	}
+}
+
+func ErrConflict(msg string) *APIError {
+	return &APIError{
+		Code:    http.StatusConflict,
+		Message: msg,
+	}
+}
+
 func ErrBadRequestCustom(msg string) *APIError {
 	return &APIError{
 		Code:    http.StatusBadRequest,
@@ -33,6 +47,27 @@ func ErrBadRequestCustom(msg string) *APIError {
 	}
 }
 
+func ErrServiceUnavailable(dependency string) *APIError {
+	return &APIError{
+		Code:    http.StatusServiceUnavailable,
+		Message: fmt.Sprintf("Service %s is unavailable, try again later", dependency),
+	}
+}
+
+func ErrInvalidPhoto(index int, reason error) *APIError {
+	return &APIError{
+		Code:    http.StatusBadRequest,
+		Message: fmt.Sprintf("Photo %d is invalid: %v", index, reason),
+	}
+}
+
+func ErrPayloadTooLarge(msg string) *APIError {
+	return &APIError{
+		Code:    http.StatusRequestEntityTooLarge,
+		Message: msg,
+	}
+}
+
 var (
 	ErrUnauthorized    = &APIError{Code: http.StatusUnauthorized, Message: "Unauthorized"}
 	ErrBadRequest      = &APIError{Code: http.StatusBadRequest, Message: "Bad request"}
@@ -53,32 +88,39 @@ func AbortError(c *gin.Context, err error) {
 	c.AbortWithStatusJSON(status, gin.H{
 		"error": message,
 	})
-	log.Printf("[ERROR] %s, returning HTTP %d, %v", message, status, err)
+	util.TEL.Error(c.Request.Context(), "request failed", err, "status", status, "message", message)
 }
 
 var (
-	httpRequestsTotal = prometheus.NewCounterVec(
+	httpRequestsTotal = util.RegisterCollector(prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_requests_total",
 			Help: "Total number of HTTP requests",
 		},
 		[]string{"method", "status", "endpoint"},
-	)
+	))
 
-	httpResponseSizeBytes = prometheus.NewCounterVec(
+	httpResponseSizeBytes = util.RegisterCollector(prometheus.NewCounterVec(
 		prometheus.CounterOpts{
 			Name: "http_response_size_bytes",
 			Help: "Total response size in bytes",
 		},
 		[]string{"endpoint", "status"},
-	)
+	))
+
+	httpRequestDuration = util.RegisterCollector(prometheus.NewHistogramVec(
+		prometheus.HistogramOpts{
+			Name:    "http_request_duration_seconds",
+			Help:    "Duration of HTTP requests in seconds",
+			Buckets: prometheus.DefBuckets,
+		},
+		[]string{"method", "status", "endpoint"},
+	))
 )
 
 func PrometheusMiddleware() gin.HandlerFunc {
-	prometheus.MustRegister(httpRequestsTotal)
-	prometheus.MustRegister(httpResponseSizeBytes)
-
 	return func(c *gin.Context) {
+		start := time.Now()
 		c.Next()
 
 		endpoint := c.FullPath()
@@ -87,11 +129,12 @@ func PrometheusMiddleware() gin.HandlerFunc {
 		size := float64(c.Writer.Size())
 
 		httpRequestsTotal.WithLabelValues(method, status, endpoint).Inc()
+		httpRequestDuration.WithLabelValues(method, status, endpoint).Observe(time.Since(start).Seconds())
 
 		if size >= 0 {
 			httpResponseSizeBytes.WithLabelValues(endpoint, status).Add(float64(size))
 		} else {
-			util.TEL.Warn("Response size < 0, cannot push to Prometheus", "size", size)
+			util.TEL.Warn(c.Request.Context(), "Response size < 0, cannot push to Prometheus", "size", size)
 		}
 	}
 }
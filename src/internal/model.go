@@ -1,6 +1,8 @@
 package internal
 
 import (
+	"maps"
+	"slices"
 	"time"
 )
 
@@ -12,7 +14,7 @@ type Room struct {
 	Address     string   `gorm:"type:varchar(150);not null"`
 	MinGuests   uint     `gorm:"not null"`
 	MaxGuests   uint     `gorm:"not null"`
-	Photos      []string `gorm:"type:text;serializer:json"`
+	Photos      []Photo  `gorm:"type:text;serializer:json"`
 	Commodities []string `gorm:"type:text;serializer:json"`
 
 	// AvailabilityListID refers to the latest list of times when the room is available.
@@ -24,6 +26,41 @@ type Room struct {
 	PriceListID *uint
 	AutoApprove bool `gorm:"not null;default:false"`
 	Deleted     bool `json:"deleted"  gorm:"type:boolean;not null;default:false"`
+	// Status is where the room is in its lifecycle, only published rooms are
+	// listed. StatusComment is what the admin said on the latest change of it.
+	Status        RoomStatus `gorm:"type:text;not null;default:'draft'"`
+	StatusComment string     `gorm:"not null;default:''"`
+
+	// Rank is the full-text search relevance of the room. It is not stored in
+	// the DB and is only populated by FindByFilters when a text query is given.
+	Rank float32 `gorm:"->;-:migration"`
+}
+
+// RoomStatus is the lifecycle status of a room. Hosts create drafts and submit
+// them for review, admins publish them or send them back, and may suspend
+// published rooms. Hosts archive rooms they no longer offer and may submit
+// them again later.
+type RoomStatus string
+
+const (
+	RoomDraft         RoomStatus = "draft"
+	RoomPendingReview RoomStatus = "pending_review"
+	RoomPublished     RoomStatus = "published"
+	RoomSuspended     RoomStatus = "suspended"
+	RoomArchived      RoomStatus = "archived"
+)
+
+var roomTransitions = map[RoomStatus][]RoomStatus{
+	RoomDraft:         {RoomPendingReview, RoomArchived},
+	RoomPendingReview: {RoomPublished, RoomDraft},
+	RoomPublished:     {RoomSuspended, RoomArchived},
+	RoomSuspended:     {RoomPublished},
+	RoomArchived:      {RoomPendingReview},
+}
+
+// CanBecome tells whether a room in status s may be moved to next.
+func (s RoomStatus) CanBecome(next RoomStatus) bool {
+	return slices.Contains(roomTransitions[s], next)
 }
 
 // RoomAvailabilityList is a list of dates when a specific room is available for booking.
@@ -76,3 +113,112 @@ type RoomPriceItem struct {
 	DateTo   time.Time       `gorm:"not null"`
 	Price    uint            `gorm:"not null"`
 }
+
+// Photo variant names. PhotoOriginal is not a variant, it names the original
+// image where all of them are listed (see PhotoDTO).
+const (
+	PhotoOriginal         = "original"
+	PhotoVariantThumbnail = "thumbnail"
+	PhotoVariantMedium    = "medium"
+)
+
+// photoVariants are the downscaled copies stored next to every photo.
+var photoVariants = []struct {
+	Name      string
+	MaxWidth  int
+	MaxHeight int
+}{
+	{PhotoVariantThumbnail, 320, 320},
+	{PhotoVariantMedium, 1024, 1024},
+}
+
+// Photo is a stored photo of a room. Variants maps a variant name to the key of
+// its image; a photo that already fits a variant has no copy for it.
+//
+// The photos of a room are kept in the order the host chose. At most one is
+// the cover, which search results show first.
+type Photo struct {
+	Key      string            `json:"key"`
+	Variants map[string]string `json:"variants,omitempty"`
+	Caption  string            `json:"caption,omitempty"`
+	AltText  string            `json:"altText,omitempty"`
+	Cover    bool              `json:"cover,omitempty"`
+}
+
+// Keys returns the keys of the original and of every variant.
+func (p Photo) Keys() []string {
+	keys := []string{p.Key}
+	for _, name := range slices.Sorted(maps.Keys(p.Variants)) {
+		keys = append(keys, p.Variants[name])
+	}
+	return keys
+}
+
+// RoomPhoto is a photo uploaded on its own, before the room it belongs to is
+// created or updated. It is staged (RoomID is nil) until a room references it.
+type RoomPhoto struct {
+	ID          uint              `gorm:"primaryKey"`
+	Key         string            `gorm:"not null;index"`
+	Variants    map[string]string `gorm:"type:text;serializer:json"`
+	ContentType string            `gorm:"not null"`
+	Size        int64             `gorm:"not null"`
+	UploaderID  uint              `gorm:"not null"`
+	RoomID      *uint             `gorm:"index"`
+	CreatedAt   time.Time
+}
+
+// Photo returns the photo as it is referenced by a room.
+func (p *RoomPhoto) Photo() Photo {
+	return Photo{Key: p.Key, Variants: p.Variants}
+}
+
+// PhotoBlob is an image stored once for every photo with the same content,
+// along with its variants. RefCount is the number of room photos and staged
+// uploads holding it; the images are deleted when it drops to zero.
+type PhotoBlob struct {
+	Key       string            `gorm:"primaryKey"`
+	Variants  map[string]string `gorm:"type:text;serializer:json"`
+	RefCount  int               `gorm:"not null"`
+	CreatedAt time.Time
+}
+
+// Photo returns the images of the blob.
+func (b *PhotoBlob) Photo() Photo {
+	return Photo{Key: b.Key, Variants: b.Variants}
+}
+
+// Commodity is an amenity of the catalog; Room.Commodities holds the keys of
+// those the room has. Labels maps a language tag to the name shown in that
+// language, there is always an English one.
+type Commodity struct {
+	Key       string            `gorm:"primaryKey"`
+	Category  string            `gorm:"not null"`
+	Icon      string            `gorm:"not null;default:''"`
+	Labels    map[string]string `gorm:"type:text;serializer:json;not null"`
+	CreatedAt time.Time
+	UpdatedAt time.Time
+}
+
+// Actions recorded in the audit log.
+const (
+	AuditListRooms       = "list_rooms"
+	AuditViewRoomHistory = "view_room_history"
+	AuditSuspendRoom     = "suspend_room"
+	AuditRestoreRoom     = "restore_room"
+	AuditSubmitRoom      = "submit_room"
+	AuditApproveRoom     = "approve_room"
+	AuditRejectRoom      = "reject_room"
+	AuditArchiveRoom     = "archive_room"
+)
+
+// AuditEntry records an action of an admin, or a change of status of a room
+// by its host. RoomID is nil for actions on no room in particular; Details
+// holds the comment or the filters of the action.
+type AuditEntry struct {
+	ID        uint   `gorm:"primaryKey"`
+	ActorID   uint   `gorm:"not null;index"`
+	Action    string `gorm:"not null"`
+	RoomID    *uint  `gorm:"index"`
+	Details   string `gorm:"not null;default:''"`
+	CreatedAt time.Time
+}
@@ -0,0 +1,207 @@
+package internal
+
+import (
+	"encoding/base64"
+	"encoding/json"
+	"strconv"
+
+	"github.com/gin-gonic/gin"
+)
+
+const defaultCursorPageSize = 20
+
+// PageCursor is the decoded form of an opaque pagination cursor. It points at
+// a single item of a sorted collection by its sort key and ID. A page fetched
+// with a cursor starts right after that item, or, when Backward is set, ends
+// right before it.
+type PageCursor struct {
+	Order    string  `json:"o"`
+	Key      float64 `json:"k"`
+	ID       uint    `json:"i"`
+	Backward bool    `json:"b,omitempty"`
+}
+
+func EncodeCursor(cursor PageCursor) string {
+	bytes, _ := json.Marshal(cursor)
+	return base64.RawURLEncoding.EncodeToString(bytes)
+}
+
+func DecodeCursor(token string) (PageCursor, error) {
+	var cursor PageCursor
+
+	bytes, err := base64.RawURLEncoding.DecodeString(token)
+	if err != nil {
+		return cursor, ErrBadRequestCustom("invalid cursor")
+	}
+	if err := json.Unmarshal(bytes, &cursor); err != nil {
+		return cursor, ErrBadRequestCustom("invalid cursor")
+	}
+
+	return cursor, nil
+}
+
+// CursorOrder describes how a collection is sorted for cursor pagination:
+// by a numeric key (ascending or descending), with ties broken by ascending
+// ID. Name is embedded in cursors so that a cursor issued for one order is not
+// used with another.
+type CursorOrder[T any] struct {
+	Name       string
+	Descending bool
+	Key        func(item T) (key float64, id uint)
+}
+
+func (o CursorOrder[T]) cursorOf(item T, backward bool) string {
+	key, id := o.Key(item)
+	return EncodeCursor(PageCursor{Order: o.Name, Key: key, ID: id, Backward: backward})
+}
+
+// after reports whether item comes strictly after the cursor in this order.
+func (o CursorOrder[T]) after(item T, cursor PageCursor) bool {
+	key, id := o.Key(item)
+	if key != cursor.Key {
+		return (key > cursor.Key) != o.Descending
+	}
+	return id > cursor.ID
+}
+
+// PaginateByCursor returns the page of at most pageSize items that follows
+// (or precedes, for backward cursors) the cursor token, along with cursors to
+// the neighbouring pages. An empty token returns the first page. Items must
+// already be sorted by order.
+func PaginateByCursor[T any](items []T, order CursorOrder[T], token string, pageSize uint) ([]T, string, string, error) {
+	start, end := 0, len(items)
+
+	if token != "" {
+		cursor, err := DecodeCursor(token)
+		if err != nil {
+			return nil, "", "", err
+		}
+		if cursor.Order != order.Name {
+			return nil, "", "", ErrBadRequestCustom("cursor was issued for a different sort order")
+		}
+
+		// Index of the first item after the cursor.
+		split := len(items)
+		for i, item := range items {
+			if order.after(item, cursor) {
+				split = i
+				break
+			}
+		}
+
+		if cursor.Backward {
+			// The cursor item itself (if it still exists) is excluded.
+			end = split
+			if end > 0 {
+				if key, id := order.Key(items[end-1]); key == cursor.Key && id == cursor.ID {
+					end--
+				}
+			}
+			start = max(0, end-int(pageSize))
+		} else {
+			start = split
+		}
+	}
+
+	end = min(end, start+int(pageSize))
+	page := items[start:end]
+
+	prev, next := windowCursors(items, start, end, order)
+	return page, prev, next, nil
+}
+
+// windowCursors returns the cursors to the pages before and after the window
+// items[start:end]. A cursor is empty if there is no such page.
+func windowCursors[T any](items []T, start int, end int, order CursorOrder[T]) (string, string) {
+	var prev, next string
+	if start > 0 && start < len(items) {
+		prev = order.cursorOf(items[start], true)
+	}
+	if end > start && end < len(items) {
+		next = order.cursorOf(items[end-1], false)
+	}
+	return prev, next
+}
+
+// paginateList pages through a list endpoint when the request has a `cursor`
+// or `pageSize` query parameter, otherwise it returns all items. To keep the
+// response a plain JSON array, cursors to the neighbouring pages are returned
+// in the X-Prev-Cursor and X-Next-Cursor headers.
+func paginateList[T any](ctx *gin.Context, items []T, order CursorOrder[T]) ([]T, error) {
+	token := ctx.Query("cursor")
+	pageSizeRaw := ctx.Query("pageSize")
+
+	if token == "" && pageSizeRaw == "" {
+		return items, nil
+	}
+
+	pageSize := uint(defaultCursorPageSize)
+	if pageSizeRaw != "" {
+		size, err := strconv.Atoi(pageSizeRaw)
+		if err != nil || size < 1 {
+			return nil, ErrBadRequestCustom("pageSize must be a positive number")
+		}
+		pageSize = uint(size)
+	}
+
+	page, prev, next, err := PaginateByCursor(items, order, token, pageSize)
+	if err != nil {
+		return nil, err
+	}
+
+	if prev != "" {
+		ctx.Header("X-Prev-Cursor", prev)
+	}
+	if next != "" {
+		ctx.Header("X-Next-Cursor", next)
+	}
+
+	return page, nil
+}
+
+var (
+	roomsByID = CursorOrder[Room]{
+		Name: "id",
+		Key:  func(r Room) (float64, uint) { return float64(r.ID), r.ID },
+	}
+
+	availabilityListsByNewest = CursorOrder[RoomAvailabilityList]{
+		Name:       "newest",
+		Descending: true,
+		Key: func(l RoomAvailabilityList) (float64, uint) {
+			return float64(l.EffectiveFrom.UnixMicro()), l.ID
+		},
+	}
+
+	priceListsByNewest = CursorOrder[RoomPriceList]{
+		Name:       "newest",
+		Descending: true,
+		Key: func(l RoomPriceList) (float64, uint) {
+			return float64(l.EffectiveFrom.UnixMicro()), l.ID
+		},
+	}
+)
+
+// searchHitOrder returns the order of search hits for the query. It must
+// match the order produced by FindAvailableRooms.
+func searchHitOrder(dto RoomsQueryDTO) CursorOrder[RoomResultDTO] {
+	if dto.SortBy == SortByPrice {
+		return CursorOrder[RoomResultDTO]{
+			Name: SortByPrice,
+			Key:  func(h RoomResultDTO) (float64, uint) { return float64(h.TotalPrice), h.ID },
+		}
+	}
+
+	if dto.Query != "" {
+		return CursorOrder[RoomResultDTO]{
+			Name:       SortByRelevance,
+			Descending: true,
+			Key:        func(h RoomResultDTO) (float64, uint) { return float64(h.Rank), h.ID },
+		}
+	}
+
+	return CursorOrder[RoomResultDTO]{
+		Name: "id",
+		Key:  func(h RoomResultDTO) (float64, uint) { return float64(h.ID), h.ID },
+	}
+}
@@ -0,0 +1,56 @@
+package internal
+
+import (
+	"errors"
+
+	"gorm.io/gorm"
+	"gorm.io/gorm/clause"
+)
+
+type PhotoBlobRepo interface {
+	// Acquire adds a reference to the blob, creating it with one reference
+	// if it does not exist.
+	Acquire(blob *PhotoBlob) error
+	// Release removes a reference to the blob with the given key. When it
+	// was the last one the blob is deleted and unreferenced is called with
+	// it before the deletion commits, so that the blob cannot be acquired
+	// again in the meantime. Releasing an unknown blob does nothing.
+	Release(key string, unreferenced func(blob *PhotoBlob)) error
+}
+
+type photoBlobRepo struct{ db *gorm.DB }
+
+func NewPhotoBlobRepo(db *gorm.DB) PhotoBlobRepo {
+	return &photoBlobRepo{db}
+}
+
+func (r *photoBlobRepo) Acquire(blob *PhotoBlob) error {
+	blob.RefCount = 1
+	return r.db.Clauses(clause.OnConflict{
+		Columns:   []clause.Column{{Name: "key"}},
+		DoUpdates: clause.Assignments(map[string]any{"ref_count": gorm.Expr("photo_blobs.ref_count + 1")}),
+	}).Create(blob).Error
+}
+
+func (r *photoBlobRepo) Release(key string, unreferenced func(blob *PhotoBlob)) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		var blob PhotoBlob
+		err := tx.Clauses(clause.Locking{Strength: "UPDATE"}).Where("key = ?", key).First(&blob).Error
+		if errors.Is(err, gorm.ErrRecordNotFound) {
+			return nil
+		}
+		if err != nil {
+			return err
+		}
+
+		if blob.RefCount > 1 {
+			return tx.Model(&blob).Update("ref_count", blob.RefCount-1).Error
+		}
+
+		if err := tx.Delete(&blob).Error; err != nil {
+			return err
+		}
+		unreferenced(&blob)
+		return nil
+	})
+}
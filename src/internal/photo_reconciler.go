@@ -0,0 +1,130 @@
+package internal
+
+import (
+	"bookem-room-service/storage"
+	"bookem-room-service/util"
+	"context"
+	"regexp"
+	"time"
+)
+
+// photoKeyPattern matches the keys photos are stored under: the hash of the
+// image, the name of the variant if any (see photoVariants), and the
+// extension. Anything else in the store is not ours to delete.
+var photoKeyPattern = regexp.MustCompile(`^[0-9a-f]{64}(-thumbnail|-medium)?\.(jpg|png)$`)
+
+// PhotoReconciler deletes the images that nothing references: those of no
+// room and of no staged upload, and those of uploads that stayed staged for
+// too long. Requests clean up the images they stored when they fail, but not
+// when the cleanup fails too or the process dies halfway.
+type PhotoReconciler struct {
+	repo       Repository
+	photoRepo  RoomPhotoRepo
+	blobRepo   PhotoBlobRepo
+	imageStore storage.ImageStore
+	// gracePeriod spares the images stored by requests that have not
+	// referenced them yet.
+	gracePeriod time.Duration
+	stagedTTL   time.Duration
+}
+
+func NewPhotoReconciler(repo Repository, photoRepo RoomPhotoRepo, blobRepo PhotoBlobRepo, imageStore storage.ImageStore, gracePeriod time.Duration, stagedTTL time.Duration) *PhotoReconciler {
+	return &PhotoReconciler{repo, photoRepo, blobRepo, imageStore, gracePeriod, stagedTTL}
+}
+
+// Run reconciles right away, then every interval until ctx is done.
+func (r *PhotoReconciler) Run(ctx context.Context, interval time.Duration) {
+	ticker := time.NewTicker(interval)
+	defer ticker.Stop()
+
+	for {
+		if _, err := r.Reconcile(ctx); err != nil {
+			util.TEL.Error(ctx, "photo reconciliation failed", err)
+		}
+
+		select {
+		case <-ctx.Done():
+			return
+		case <-ticker.C:
+		}
+	}
+}
+
+// Reconcile makes one pass and returns the number of orphaned images it
+// deleted.
+func (r *PhotoReconciler) Reconcile(ctx context.Context) (int, error) {
+	ctx, span := util.TEL.Start(ctx, "reconcile-photos")
+	defer span.End()
+
+	now := time.Now()
+
+	// [1] Expire staged uploads and release their images
+
+	expired, err := r.photoRepo.FindStagedBefore(now.Add(-r.stagedTTL))
+	if err != nil {
+		return 0, err
+	}
+	for _, photo := range expired {
+		util.TEL.Info(ctx, "expiring staged photo", "id", photo.ID, "key", photo.Key, "uploaded_at", photo.CreatedAt)
+		deleted, err := r.photoRepo.DeleteStaged(&photo)
+		if err != nil {
+			return 0, err
+		}
+		if !deleted {
+			// A room took the photo, and its reference, since it was found.
+			util.TEL.Info(ctx, "staged photo was attached meanwhile", "id", photo.ID, "key", photo.Key)
+			continue
+		}
+		releasePhotos(ctx, r.blobRepo, r.imageStore, []Photo{photo.Photo()})
+	}
+
+	// [2] Find what is referenced. Images stored after this are younger than
+	// the grace period, so missing their references is harmless.
+
+	referenced := make(map[string]bool)
+
+	roomPhotos, err := r.repo.FindAllPhotos()
+	if err != nil {
+		return 0, err
+	}
+	for _, photo := range roomPhotos {
+		for _, key := range photo.Keys() {
+			referenced[key] = true
+		}
+	}
+
+	uploads, err := r.photoRepo.FindAll()
+	if err != nil {
+		return 0, err
+	}
+	for _, upload := range uploads {
+		for _, key := range upload.Photo().Keys() {
+			referenced[key] = true
+		}
+	}
+
+	// [3] Delete the rest of the photos
+
+	images, err := r.imageStore.List(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	deleted := 0
+	for _, image := range images {
+		if !photoKeyPattern.MatchString(image.Key) || referenced[image.Key] || now.Sub(image.LastModified) < r.gracePeriod {
+			continue
+		}
+
+		util.TEL.Info(ctx, "deleting orphaned image", "key", image.Key, "last_modified", image.LastModified)
+		if err := r.imageStore.Delete(ctx, image.Key); err != nil {
+			util.TEL.Error(ctx, "could not delete orphaned image", err, "key", image.Key)
+			continue
+		}
+		deleted++
+		orphanedPhotosDeletedTotal.Inc()
+	}
+
+	util.TEL.Info(ctx, "photos reconciled", "images", len(images), "referenced", len(referenced), "deleted", deleted, "expired_uploads", len(expired))
+	return deleted, nil
+}
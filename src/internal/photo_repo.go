@@ -0,0 +1,93 @@
+package internal
+
+import (
+	"fmt"
+	"time"
+
+	"gorm.io/gorm"
+)
+
+type RoomPhotoRepo interface {
+	Create(photo *RoomPhoto) error
+	// DeleteStaged deletes the photo unless a room references it by now, and
+	// reports whether it did.
+	DeleteStaged(photo *RoomPhoto) (bool, error)
+	FindByIds(ids []uint) ([]RoomPhoto, error)
+	FindAll() ([]RoomPhoto, error)
+	// FindStagedBefore returns the photos uploaded before t that no room
+	// references.
+	FindStagedBefore(t time.Time) ([]RoomPhoto, error)
+}
+
+type roomPhotoRepo struct{ db *gorm.DB }
+
+func NewRoomPhotoRepo(db *gorm.DB) RoomPhotoRepo {
+	return &roomPhotoRepo{db}
+}
+
+func (r *roomPhotoRepo) Create(photo *RoomPhoto) error {
+	return r.db.Create(photo).Error
+}
+
+func (r *roomPhotoRepo) DeleteStaged(photo *RoomPhoto) (bool, error) {
+	result := r.db.Where("id = ? AND room_id IS NULL", photo.ID).Delete(&RoomPhoto{})
+	if result.Error != nil {
+		return false, result.Error
+	}
+	return result.RowsAffected == 1, nil
+}
+
+func (r *roomPhotoRepo) FindByIds(ids []uint) ([]RoomPhoto, error) {
+	var photos []RoomPhoto
+	err := r.db.Where("id IN ?", ids).Find(&photos).Error
+	if err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+func (r *roomPhotoRepo) FindAll() ([]RoomPhoto, error) {
+	var photos []RoomPhoto
+	err := r.db.Find(&photos).Error
+	if err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+func (r *roomPhotoRepo) FindStagedBefore(t time.Time) ([]RoomPhoto, error) {
+	var photos []RoomPhoto
+	err := r.db.Where("room_id IS NULL AND created_at < ?", t).Find(&photos).Error
+	if err != nil {
+		return nil, err
+	}
+	return photos, nil
+}
+
+// attachPhotos assigns the staged photos to the room, within tx. It fails if
+// any of them is not staged anymore.
+func attachPhotos(tx *gorm.DB, roomId uint, ids []uint) error {
+	if len(ids) == 0 {
+		return nil
+	}
+
+	result := tx.Model(&RoomPhoto{}).
+		Where("id IN ? AND room_id IS NULL", ids).
+		Update("room_id", roomId)
+	if result.Error != nil {
+		return result.Error
+	}
+	if result.RowsAffected != int64(len(ids)) {
+		return fmt.Errorf("%d of %d photos were already attached", int64(len(ids))-result.RowsAffected, len(ids))
+	}
+	return nil
+}
+
+// detachPhotos deletes the uploads of the room with the given keys, within
+// tx. Photos added with the room itself have no upload, so none may match.
+func detachPhotos(tx *gorm.DB, roomId uint, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+	return tx.Where("room_id = ? AND key IN ?", roomId, keys).Delete(&RoomPhoto{}).Error
+}
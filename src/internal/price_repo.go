@@ -44,7 +44,7 @@ func (r *roomPriceRepo) FindListById(id uint) (*RoomPriceList, error) {
 
 func (r *roomPriceRepo) FindListsByRoomId(roomId uint) ([]RoomPriceList, error) {
 	var lists []RoomPriceList
-	err := r.db.Preload("Items").Where("room_id = ?", roomId).Find(&lists).Error
+	err := r.db.Preload("Items").Where("room_id = ?", roomId).Order("effective_from DESC, id").Find(&lists).Error
 	if err != nil {
 		return nil, err
 	}
@@ -6,14 +6,40 @@ import (
 
 type Repository interface {
 	Create(room *Room) error
+	// CreateWithPhotos creates the room and attaches the staged photos to it,
+	// in one transaction.
+	CreateWithPhotos(room *Room, photoIds []uint) error
 	Update(room *Room) error
+	// UpdateWithPhotos saves the photos of the room and attaches the staged
+	// photos to it, in one transaction.
+	UpdateWithPhotos(room *Room, photoIds []uint) error
+	// UpdateDetachingPhotos saves the photos of the room and deletes the
+	// uploads of the removed photos, in one transaction.
+	UpdateDetachingPhotos(room *Room, removed []string) error
+	// UpdateStatusWithAudit saves the status and status comment of the room if
+	// it is still from, and records the audit entry, in one transaction. It
+	// reports whether the room was still from.
+	UpdateStatusWithAudit(room *Room, from RoomStatus, entry *AuditEntry) (bool, error)
+	// FindAllPhotos returns the photos of every room, deleted ones included.
+	FindAllPhotos() ([]Photo, error)
 	Delete(room *Room) error
 	FindById(id uint) (*Room, error)
+	// FindAll returns every room, deleted and suspended ones included.
+	FindAll() ([]Room, error)
 	FindByHost(hostId uint) ([]Room, error)
-	FindByFilters(guestsNumber uint, address string) ([]Room, error)
+	FindByFilters(guestsNumber uint, address string, text string) ([]Room, error)
 	DeleteRoomsByHostId(hostId uint) error
 }
 
+// roomSearchVector is the tsvector expression used for full-text search over
+// rooms. It must stay in sync with the expression of idx_rooms_search in
+// migrations/sql/0001_initial_schema.up.sql, or the index is not used.
+const roomSearchVector = "to_tsvector('english', " +
+	"coalesce(name, '') || ' ' || " +
+	"coalesce(description, '') || ' ' || " +
+	"coalesce(commodities, '') || ' ' || " +
+	"coalesce(address, ''))"
+
 type repository struct {
 	db *gorm.DB
 }
@@ -26,10 +52,75 @@ func (r *repository) Create(room *Room) error {
 	return r.db.Create(room).Error
 }
 
+func (r *repository) CreateWithPhotos(room *Room, photoIds []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := tx.Create(room).Error; err != nil {
+			return err
+		}
+		return attachPhotos(tx, room.ID, photoIds)
+	})
+}
+
 func (r *repository) Update(room *Room) error {
 	return r.db.Save(room).Error
 }
 
+func (r *repository) UpdateWithPhotos(room *Room, photoIds []uint) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := attachPhotos(tx, room.ID, photoIds); err != nil {
+			return err
+		}
+		return updatePhotos(tx, room)
+	})
+}
+
+func (r *repository) UpdateDetachingPhotos(room *Room, removed []string) error {
+	return r.db.Transaction(func(tx *gorm.DB) error {
+		if err := detachPhotos(tx, room.ID, removed); err != nil {
+			return err
+		}
+		return updatePhotos(tx, room)
+	})
+}
+
+// updatePhotos saves the photos of the room only, within tx, so that the
+// rest of the row is not overwritten with what was read before.
+func updatePhotos(tx *gorm.DB, room *Room) error {
+	return tx.Model(room).Select("photos").Updates(room).Error
+}
+
+func (r *repository) UpdateStatusWithAudit(room *Room, from RoomStatus, entry *AuditEntry) (bool, error) {
+	updated := false
+	err := r.db.Transaction(func(tx *gorm.DB) error {
+		result := tx.Model(&Room{}).
+			Where("id = ? AND status = ?", room.ID, from).
+			Updates(map[string]any{"status": room.Status, "status_comment": room.StatusComment})
+		if result.Error != nil {
+			return result.Error
+		}
+		if result.RowsAffected == 0 {
+			return nil
+		}
+		updated = true
+		return tx.Create(entry).Error
+	})
+	return updated && err == nil, err
+}
+
+func (r *repository) FindAllPhotos() ([]Photo, error) {
+	var rooms []Room
+	err := r.db.Select("id", "photos").Find(&rooms).Error
+	if err != nil {
+		return nil, err
+	}
+
+	var photos []Photo
+	for _, room := range rooms {
+		photos = append(photos, room.Photos...)
+	}
+	return photos, nil
+}
+
 func (r *repository) Delete(room *Room) error {
 	return r.db.Delete(&Room{}, room.ID).Error
 }
@@ -51,16 +142,29 @@ func (r *repository) FindById(id uint) (*Room, error) {
 	return &room, nil
 }
 
+func (r *repository) FindAll() ([]Room, error) {
+	var rooms []Room
+	err := r.db.Order("id").Find(&rooms).Error
+	if err != nil {
+		return nil, err
+	}
+	return rooms, nil
+}
+
 func (r *repository) FindByHost(hostId uint) ([]Room, error) {
 	var rooms []Room
-	err := r.db.Where("host_id = ?", hostId).Find(&rooms).Error
+	err := r.db.Where("host_id = ?", hostId).Order("id").Find(&rooms).Error
 	if err != nil {
 		return nil, err
 	}
 	return rooms, nil
 }
 
-func (r *repository) FindByFilters(guestsNumber uint, address string) ([]Room, error) {
+// FindByFilters returns rooms that can host guestsNumber guests, located at
+// address (substring match) and matching the free-text query text. When text
+// is given, rooms are ordered by relevance and Room.Rank is populated.
+// Otherwise they are ordered by ID.
+func (r *repository) FindByFilters(guestsNumber uint, address string, text string) ([]Room, error) {
 	var rooms []Room
 	query := r.db.Where("min_guests <= ? and max_guests >= ?", guestsNumber, guestsNumber)
 
@@ -68,6 +172,14 @@ func (r *repository) FindByFilters(guestsNumber uint, address string) ([]Room, e
 		query = query.Where("TRIM(LOWER(address)) LIKE CONCAT('%' || TRIM(LOWER( ? )) || '%')", address)
 	}
 
+	if text != "" {
+		query = query.
+			Select("rooms.*, ts_rank("+roomSearchVector+", websearch_to_tsquery('english', ?)) AS rank", text).
+			Where(roomSearchVector+" @@ websearch_to_tsquery('english', ?)", text).
+			Order("rank DESC")
+	}
+	query = query.Order("id")
+
 	err := query.Find(&rooms).Error
 	if err != nil {
 		return nil, query.Error
@@ -0,0 +1,215 @@
+package internal
+
+import (
+	"container/list"
+	"fmt"
+	"maps"
+	"strings"
+	"sync"
+	"time"
+)
+
+// SearchCache caches the evaluated hits of FindAvailableRooms (before
+// pagination) under a key built from the normalized query.
+//
+// Every entry remembers the candidate rooms it was computed from, i.e. all
+// rooms matching the query filters regardless of whether they were a hit, so
+// that a change to any of them drops the entry. A shared store (e.g. Redis)
+// can implement this by keeping a set of keys per room ID next to the entries.
+//
+// An entry computed while one of its rooms is being changed may be stale, so
+// implementations should also expire entries after a short TTL.
+type SearchCache interface {
+	// Get returns the hits cached under key.
+	Get(key string) ([]RoomResultDTO, bool)
+	// Set caches hits under key, computed from the candidate rooms roomIDs.
+	Set(key string, hits []RoomResultDTO, roomIDs []uint)
+	// InvalidateRooms drops every entry computed from any of the rooms.
+	InvalidateRooms(roomIDs ...uint)
+	// InvalidateAll drops every entry. It is used when a change can add new
+	// candidates to existing entries, e.g. when a room is created.
+	InvalidateAll()
+}
+
+// SearchCacheKey returns the cache key of a search query. Pagination
+// parameters are not part of the key since the cache holds all hits.
+//
+// Queries share a key only if FindByFilters treats them alike: the address is
+// matched as it is, but trimmed and lowercased, while the words of the text
+// query are what matters.
+func SearchCacheKey(dto RoomsQueryDTO) string {
+	address := strings.ToLower(strings.Trim(dto.Address, " "))
+	query := strings.Join(strings.Fields(strings.ToLower(dto.Query)), " ")
+
+	return fmt.Sprintf("a=%s|q=%s|s=%s|g=%d|f=%s|t=%s|l=%d|m=%s",
+		address,
+		query,
+		dto.SortBy,
+		dto.GuestsNumber,
+		dto.DateFrom.UTC().Format(time.DateOnly),
+		dto.DateTo.UTC().Format(time.DateOnly),
+		dto.StayLength,
+		dto.FlexibleMode,
+	)
+}
+
+// ---------------------------------------------------------------
+
+type noopSearchCache struct{}
+
+// NewNoopSearchCache returns a cache that never holds anything.
+func NewNoopSearchCache() SearchCache { return noopSearchCache{} }
+
+func (noopSearchCache) Get(key string) ([]RoomResultDTO, bool)               { return nil, false }
+func (noopSearchCache) Set(key string, hits []RoomResultDTO, roomIDs []uint) {}
+func (noopSearchCache) InvalidateRooms(roomIDs ...uint)                      {}
+func (noopSearchCache) InvalidateAll()                                       {}
+
+// ---------------------------------------------------------------
+
+type searchCacheEntry struct {
+	key       string
+	hits      []RoomResultDTO
+	roomIDs   []uint
+	expiresAt time.Time
+}
+
+// memorySearchCache is an in-process LRU cache with a TTL per entry.
+type memorySearchCache struct {
+	mu         sync.Mutex
+	ttl        time.Duration
+	maxEntries int
+
+	lru     *list.List               // Front is the most recently used entry.
+	entries map[string]*list.Element // Key -> element holding *searchCacheEntry.
+	byRoom  map[uint]map[string]struct{}
+}
+
+// NewMemorySearchCache returns an in-process cache holding at most maxEntries
+// entries, each for at most ttl.
+func NewMemorySearchCache(ttl time.Duration, maxEntries int) SearchCache {
+	return &memorySearchCache{
+		ttl:        ttl,
+		maxEntries: maxEntries,
+		lru:        list.New(),
+		entries:    make(map[string]*list.Element),
+		byRoom:     make(map[uint]map[string]struct{}),
+	}
+}
+
+func (c *memorySearchCache) Get(key string) ([]RoomResultDTO, bool) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	elem, ok := c.entries[key]
+	if !ok {
+		return nil, false
+	}
+
+	entry := elem.Value.(*searchCacheEntry)
+	if time.Now().After(entry.expiresAt) {
+		c.remove(elem)
+		return nil, false
+	}
+
+	c.lru.MoveToFront(elem)
+	return cloneHits(entry.hits), true
+}
+
+func (c *memorySearchCache) Set(key string, hits []RoomResultDTO, roomIDs []uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	if elem, ok := c.entries[key]; ok {
+		c.remove(elem)
+	}
+
+	entry := &searchCacheEntry{
+		key:       key,
+		hits:      cloneHits(hits),
+		roomIDs:   append([]uint(nil), roomIDs...),
+		expiresAt: time.Now().Add(c.ttl),
+	}
+	c.entries[key] = c.lru.PushFront(entry)
+
+	for _, id := range roomIDs {
+		keys, ok := c.byRoom[id]
+		if !ok {
+			keys = make(map[string]struct{})
+			c.byRoom[id] = keys
+		}
+		keys[key] = struct{}{}
+	}
+
+	for c.maxEntries > 0 && c.lru.Len() > c.maxEntries {
+		c.remove(c.lru.Back())
+	}
+}
+
+func (c *memorySearchCache) InvalidateRooms(roomIDs ...uint) {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	for _, id := range roomIDs {
+		for key := range c.byRoom[id] {
+			if elem, ok := c.entries[key]; ok {
+				c.remove(elem)
+			}
+		}
+	}
+}
+
+func (c *memorySearchCache) InvalidateAll() {
+	c.mu.Lock()
+	defer c.mu.Unlock()
+
+	c.lru.Init()
+	c.entries = make(map[string]*list.Element)
+	c.byRoom = make(map[uint]map[string]struct{})
+}
+
+// remove drops an entry. The caller must hold the lock.
+func (c *memorySearchCache) remove(elem *list.Element) {
+	entry := elem.Value.(*searchCacheEntry)
+
+	c.lru.Remove(elem)
+	delete(c.entries, entry.key)
+
+	for _, id := range entry.roomIDs {
+		keys := c.byRoom[id]
+		delete(keys, entry.key)
+		if len(keys) == 0 {
+			delete(c.byRoom, id)
+		}
+	}
+}
+
+// cloneHits copies hits deeply, so that neither the caller caching them nor
+// those getting them can change the entry.
+func cloneHits(hits []RoomResultDTO) []RoomResultDTO {
+	if hits == nil {
+		return nil
+	}
+
+	clones := make([]RoomResultDTO, len(hits))
+	for i, hit := range hits {
+		if hit.Photos != nil {
+			photos := make([]PhotoDTO, len(hit.Photos))
+			for j, photo := range hit.Photos {
+				photo.URLs = maps.Clone(photo.URLs)
+				photos[j] = photo
+			}
+			hit.Photos = photos
+		}
+		if hit.DateFrom != nil {
+			dateFrom := *hit.DateFrom
+			hit.DateFrom = &dateFrom
+		}
+		if hit.DateTo != nil {
+			dateTo := *hit.DateTo
+			hit.DateTo = &dateTo
+		}
+		clones[i] = hit
+	}
+	return clones
+}
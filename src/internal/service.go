@@ -2,32 +2,94 @@ package internal
 
 import (
 	"bookem-room-service/client/userclient"
+	"bookem-room-service/storage"
 	"bookem-room-service/util"
 	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"errors"
 	"fmt"
+	"iter"
 	"math"
+	"regexp"
+	"sort"
 	"strings"
 	"time"
+	"unicode/utf8"
+
+	"golang.org/x/sync/errgroup"
 )
 
+// defaultSearchConcurrency is the number of rooms evaluated at once by
+// FindAvailableRooms unless configured otherwise.
+const defaultSearchConcurrency = 8
+
 type Service interface {
-	Create(context context.Context, callerID uint, dto CreateRoomDTO) (*Room, error)
-	FindById(context context.Context, id uint) (*Room, error)
-	FindByHost(context context.Context, hostId uint) ([]Room, error)
-	FindAvailableRooms(context context.Context, dto RoomsQueryDTO) ([]RoomResultDTO, *PaginatedResultInfoDTO, error)
-	DeleteRoomsByHostId(context context.Context, hostId uint) ([]Room, error)
-
-	FindAvailabilityListById(context context.Context, id uint) (*RoomAvailabilityList, error)
-	FindAvailabilityListsByRoomId(context context.Context, roomId uint) ([]RoomAvailabilityList, error)
-	FindCurrentAvailabilityListOfRoom(context context.Context, roomId uint) (*RoomAvailabilityList, error)
-	UpdateAvailability(context context.Context, callerID uint, dto CreateRoomAvailabilityListDTO) (*RoomAvailabilityList, error)
-
-	FindPriceListById(context context.Context, id uint) (*RoomPriceList, error)
-	FindPriceListsByRoomId(context context.Context, roomId uint) ([]RoomPriceList, error)
-	FindCurrentPriceListOfRoom(context context.Context, roomId uint) (*RoomPriceList, error)
-	UpdatePriceList(context context.Context, callerID uint, dto CreateRoomPriceListDTO) (*RoomPriceList, error)
-
-	ClearYear(context context.Context, dateFrom time.Time, dateTo time.Time) (time.Time, time.Time)
+	Create(ctx context.Context, callerID uint, dto CreateRoomDTO) (*Room, error)
+	FindById(ctx context.Context, id uint) (*Room, error)
+	// FindByHost returns the rooms of a host, and whether the caller sees them
+	// all. Unless the caller is that host or an admin, only the published ones
+	// are returned. callerID is nil for anonymous callers.
+	FindByHost(ctx context.Context, callerID *uint, hostId uint) ([]Room, bool, error)
+	FindAvailableRooms(ctx context.Context, dto RoomsQueryDTO) ([]RoomResultDTO, *PaginatedResultInfoDTO, error)
+	DeleteRoomsByHostId(ctx context.Context, hostId uint) ([]Room, error)
+
+	// UploadPhotos stores photos uploaded by a host and stages them until a
+	// room references them. Files are read one at a time and each is stored
+	// before the next is read; an error of files is returned as it is. Either
+	// every photo is stored or none is.
+	UploadPhotos(ctx context.Context, callerID uint, files iter.Seq2[[]byte, error]) ([]RoomPhoto, error)
+	// AttachPhotos adds staged photos to an existing room of the caller.
+	AttachPhotos(ctx context.Context, callerID uint, roomId uint, photoIds []uint) (*Room, error)
+	// UpdatePhotos reorders the photos of a room of the caller and sets their
+	// captions, alt texts and the cover photo. Photos left out are removed.
+	UpdatePhotos(ctx context.Context, callerID uint, roomId uint, dto UpdatePhotosDTO) (*Room, error)
+
+	// FindCommodities returns the commodity catalog, ordered by category.
+	FindCommodities(ctx context.Context) ([]Commodity, error)
+	// CreateCommodity, UpdateCommodity and DeleteCommodity manage the
+	// commodity catalog. The caller must be an admin. A commodity that any
+	// room has cannot be deleted.
+	CreateCommodity(ctx context.Context, callerID uint, dto CommodityDTO) (*Commodity, error)
+	UpdateCommodity(ctx context.Context, callerID uint, key string, dto UpdateCommodityDTO) (*Commodity, error)
+	DeleteCommodity(ctx context.Context, callerID uint, key string) error
+
+	// FindAllRooms returns every room, whatever its status and deleted ones too,
+	// or only those of a host if hostId is given. The caller must be an admin.
+	FindAllRooms(ctx context.Context, callerID uint, hostId *uint) ([]Room, error)
+	// SuspendRoom unlists a published room, for the given reason, until
+	// RestoreRoom publishes it again. The caller must be an admin.
+	SuspendRoom(ctx context.Context, callerID uint, roomId uint, reason string) (*Room, error)
+	RestoreRoom(ctx context.Context, callerID uint, roomId uint) (*Room, error)
+	// ApproveRoom publishes a room pending review, RejectRoom sends it back
+	// to its host as a draft with a comment. The caller must be an admin.
+	ApproveRoom(ctx context.Context, callerID uint, roomId uint, comment string) (*Room, error)
+	RejectRoom(ctx context.Context, callerID uint, roomId uint, comment string) (*Room, error)
+
+	// SubmitRoom sends a draft or archived room of the caller for review,
+	// ArchiveRoom unlists it. The caller must be the host of the room.
+	SubmitRoom(ctx context.Context, callerID uint, roomId uint) (*Room, error)
+	ArchiveRoom(ctx context.Context, callerID uint, roomId uint) (*Room, error)
+
+	// FindRoomHistory returns a room, whatever its status, with all its price
+	// and availability lists and its audit entries. The caller must be an
+	// admin.
+	FindRoomHistory(ctx context.Context, callerID uint, roomId uint) (*RoomHistory, error)
+
+	// The availability and price lists of a room are visible to anyone once
+	// it is published, and to its host and admins unless it is deleted.
+	// callerID is nil for anonymous callers.
+	FindAvailabilityListById(ctx context.Context, callerID *uint, id uint) (*RoomAvailabilityList, error)
+	FindAvailabilityListsByRoomId(ctx context.Context, callerID *uint, roomId uint) ([]RoomAvailabilityList, error)
+	FindCurrentAvailabilityListOfRoom(ctx context.Context, callerID *uint, roomId uint) (*RoomAvailabilityList, error)
+	UpdateAvailability(ctx context.Context, callerID uint, dto CreateRoomAvailabilityListDTO) (*RoomAvailabilityList, error)
+
+	FindPriceListById(ctx context.Context, callerID *uint, id uint) (*RoomPriceList, error)
+	FindPriceListsByRoomId(ctx context.Context, callerID *uint, roomId uint) ([]RoomPriceList, error)
+	FindCurrentPriceListOfRoom(ctx context.Context, callerID *uint, roomId uint) (*RoomPriceList, error)
+	UpdatePriceList(ctx context.Context, callerID uint, dto CreateRoomPriceListDTO) (*RoomPriceList, error)
+
+	ClearYear(ctx context.Context, dateFrom time.Time, dateTo time.Time) (time.Time, time.Time)
 	// CalculatePriceForOneDay computes the price for the room for a single night.
 	// If the room is priced by guest, then the resulting price is multiplied by the number of guests.
 	//
@@ -36,7 +98,7 @@ type Service interface {
 	// guests.
 	//
 	// TODO: This should NOT return float32.
-	CalculatePriceForOneDay(context context.Context, day time.Time, guests uint, rules RoomPriceList) float32
+	CalculatePriceForOneDay(ctx context.Context, day time.Time, guests uint, rules RoomPriceList) float32
 	// CalculatePrice calculates the price of the room between dateFrom and dateTo.
 	//
 	// It's assumed that the room can be booked in this date range.
@@ -45,67 +107,182 @@ type Service interface {
 	// So if you want the price for a single guest, divide by the number of guests.
 	//
 	// TODO: This should NOT return float32.
-	CalculatePrice(context context.Context, dateFrom time.Time, dateTo time.Time, guestsNumber uint, roomId uint) (float32, bool, error)
-	IsRoomAvailableForOneDay(context context.Context, day time.Time, rules []RoomAvailabilityItem) bool
-	IsRoomAvailable(context context.Context, dateFrom time.Time, dateTo time.Time, roomId uint) bool
-	CalculateUnitPrice(context context.Context, perGuest bool, guestsNumber uint, dateFrom time.Time, dateTo time.Time, totalPrice float32) float32
-	PreparePaginatedResult(context context.Context, hits []RoomResultDTO, pageNumber uint, pageSize uint) ([]RoomResultDTO, PaginatedResultInfoDTO)
-
-	QueryForReservation(context context.Context, callerID uint, dto RoomReservationQueryDTO) (*RoomReservationQueryResponseDTO, error)
-
-	ExcludeDeletedRooms(context context.Context, rooms []Room) []Room
+	CalculatePrice(ctx context.Context, dateFrom time.Time, dateTo time.Time, guestsNumber uint, roomId uint) (float32, bool, error)
+	IsRoomAvailableForOneDay(ctx context.Context, day time.Time, rules []RoomAvailabilityItem) bool
+	IsRoomAvailable(ctx context.Context, dateFrom time.Time, dateTo time.Time, roomId uint) bool
+	// FindFlexibleStay finds a stay of stayLength days between dateFrom and
+	// dateTo during which the room can be booked. Depending on mode, the
+	// cheapest (default) or the earliest such stay is returned. Returns nil if
+	// the room cannot be booked for any stay in the window.
+	FindFlexibleStay(ctx context.Context, roomId uint, dateFrom time.Time, dateTo time.Time, stayLength uint, guests uint, mode string) (*FlexibleStay, error)
+	CalculateUnitPrice(ctx context.Context, perGuest bool, guestsNumber uint, dateFrom time.Time, dateTo time.Time, totalPrice float32) float32
+	// PreparePaginatedResult returns the page of hits with the given number,
+	// counted from 1. A page past the last one is empty.
+	PreparePaginatedResult(ctx context.Context, hits []RoomResultDTO, pageNumber uint, pageSize uint) ([]RoomResultDTO, PaginatedResultInfoDTO)
+
+	QueryForReservation(ctx context.Context, callerID uint, dto RoomReservationQueryDTO) (*RoomReservationQueryResponseDTO, error)
+
+	ExcludeDeletedRooms(ctx context.Context, rooms []Room) []Room
 }
 
 type service struct {
 	repo            Repository
 	availabiltyRepo RoomAvailabilityRepo
 	priceRepo       RoomPriceRepo
+	photoRepo       RoomPhotoRepo
+	blobRepo        PhotoBlobRepo
+	commodityRepo   CommodityRepo
+	auditRepo       AuditRepo
 	userClient      userclient.UserClient
+	imageStore      storage.ImageStore
+	searchCache     SearchCache
+
+	// searchConcurrency is the maximum number of rooms evaluated at once by
+	// FindAvailableRooms.
+	searchConcurrency int
+}
+
+// ServiceDeps are what the service is made of. A nil SearchCache means no
+// caching and a SearchConcurrency below 1 means the default.
+type ServiceDeps struct {
+	Repo              Repository
+	AvailabilityRepo  RoomAvailabilityRepo
+	PriceRepo         RoomPriceRepo
+	PhotoRepo         RoomPhotoRepo
+	BlobRepo          PhotoBlobRepo
+	CommodityRepo     CommodityRepo
+	AuditRepo         AuditRepo
+	UserClient        userclient.UserClient
+	ImageStore        storage.ImageStore
+	SearchCache       SearchCache
+	SearchConcurrency int
+}
+
+func NewService(deps ServiceDeps) Service {
+	if deps.SearchCache == nil {
+		deps.SearchCache = NewNoopSearchCache()
+	}
+	if deps.SearchConcurrency < 1 {
+		deps.SearchConcurrency = defaultSearchConcurrency
+	}
+	return &service{
+		repo:              deps.Repo,
+		availabiltyRepo:   deps.AvailabilityRepo,
+		priceRepo:         deps.PriceRepo,
+		photoRepo:         deps.PhotoRepo,
+		blobRepo:          deps.BlobRepo,
+		commodityRepo:     deps.CommodityRepo,
+		auditRepo:         deps.AuditRepo,
+		userClient:        deps.UserClient,
+		imageStore:        deps.ImageStore,
+		searchCache:       deps.SearchCache,
+		searchConcurrency: deps.SearchConcurrency,
+	}
 }
 
-func NewService(
-	roomRepo Repository,
-	availabiltyRepo RoomAvailabilityRepo,
-	priceRepo RoomPriceRepo,
-	userClient userclient.UserClient) Service {
-	return &service{roomRepo, availabiltyRepo, priceRepo, userClient}
+// userLookupError maps an error of the user client to the API error returned
+// for it: 404 for an unknown user, 503 when the user service is unavailable.
+func userLookupError(err error, resourceName string, id uint) error {
+	switch {
+	case errors.Is(err, userclient.ErrUserNotFound):
+		return ErrNotFound(resourceName, id)
+	case errors.Is(err, userclient.ErrUserServiceUnavailable):
+		return ErrServiceUnavailable("user")
+	default:
+		return err
+	}
 }
 
-func (s *service) Create(context context.Context, callerID uint, dto CreateRoomDTO) (*Room, error) {
-	util.TEL.Info("user wants to create a room", "caller_id", callerID)
+func (s *service) Create(ctx context.Context, callerID uint, dto CreateRoomDTO) (*Room, error) {
+	util.TEL.Info(ctx, "user wants to create a room", "caller_id", callerID)
 
-	util.TEL.Push(context, "validate-user")
-	defer util.TEL.Pop()
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-user")
+	defer span.End()
 
 	// Check if user exists.
 
-	util.TEL.Debug("check if user exists", "id", callerID)
-	caller, err := s.userClient.FindById(util.TEL.Ctx(), callerID)
+	util.TEL.Debug(ctx, "check if user exists", "id", callerID)
+	caller, err := s.userClient.FindById(ctx, callerID)
 	if err != nil {
-		util.TEL.Error("user does not exist", err, "id", callerID)
-		return nil, err
+		util.TEL.Error(ctx, "user does not exist", err, "id", callerID)
+		return nil, userLookupError(err, "user", callerID)
 	}
 
 	// Check if user is host.
 
-	util.TEL.Debug("check if user is a host", "id", callerID)
+	util.TEL.Debug(ctx, "check if user is a host", "id", callerID)
 	if caller.Role != string(util.Host) {
-		util.TEL.Error("user has a bad role", nil, "role", caller.Role)
+		util.TEL.Error(ctx, "user has a bad role", nil, "role", caller.Role)
 		return nil, ErrUnauthorized
 	}
 
 	// User must be creating a room for himself.
 
-	util.TEL.Debug("user must be creating a room for himself")
+	util.TEL.Debug(ctx, "user must be creating a room for himself")
 	if caller.Id != dto.HostID {
-		util.TEL.Error("wrong user", nil, "caller_id", caller.Id, "host_id", dto.HostID)
+		util.TEL.Error(ctx, "wrong user", nil, "caller_id", caller.Id, "host_id", dto.HostID)
 		return nil, ErrUnauthorized
 	}
 
-	// First create the room without photos.
+	// Commodities must be of the catalog.
+
+	if err := s.validateRoomCommodities(ctx, dto.Commodities); err != nil {
+		return nil, err
+	}
+
+	// Photos sent along must be valid images, those uploaded beforehand must
+	// be the caller's and unused.
+
+	images, err := processPhotosPayload(ctx, dto.PhotosPayload)
+	if err != nil {
+		return nil, err
+	}
+
+	staged, err := s.findStagedPhotos(ctx, callerID, dto.PhotoIDs)
+	if err != nil {
+		return nil, err
+	}
+
+	keys := make([]string, 0, len(images)+len(staged))
+	for _, image := range images {
+		keys = append(keys, photoKey(image))
+	}
+	for _, photo := range staged {
+		keys = append(keys, photo.Key)
+	}
+	if hasDuplicatePhoto(keys) {
+		return nil, ErrBadRequestCustom("The same picture cannot be added to a room twice")
+	}
+	span.End()
+
+	// Store the photos first, under keys that do not depend on the room ID.
+	// If anything below fails they are released; if that fails too, the
+	// photo reconciler deletes them later since no room references them.
+
+	ctx, span = util.TEL.Start(reqCtx, "add-all-photos-to-storage")
+	defer span.End()
+
+	var photos = make([]Photo, 0)
+	for _, image := range images {
+		photo, err := s.storePhoto(ctx, image)
+		if err != nil {
+			releasePhotos(ctx, s.blobRepo, s.imageStore, photos)
+			return nil, err
+		}
+		photos = append(photos, photo)
+	}
+	span.End()
+
+	// Then create the room with all photos, uploaded ones last, at once.
 
-	util.TEL.Push(context, "create-room-in-db")
-	defer util.TEL.Pop()
+	ctx, span = util.TEL.Start(reqCtx, "create-room-in-db")
+	defer span.End()
+
+	payloadPhotos := photos
+	for _, photo := range staged {
+		photos = append(photos, photo.Photo())
+	}
 
 	room := &Room{
 		HostID:      dto.HostID,
@@ -114,213 +291,693 @@ func (s *service) Create(context context.Context, callerID uint, dto CreateRoomD
 		Address:     dto.Address,
 		MinGuests:   dto.MinGuests,
 		MaxGuests:   dto.MaxGuests,
-		Photos:      []string{},
+		Photos:      photos,
 		Commodities: dto.Commodities,
 		AutoApprove: dto.AutoApprove,
 		Deleted:     dto.Deleted,
+		Status:      RoomDraft,
 	}
 
-	err = s.repo.Create(room)
+	err = s.repo.CreateWithPhotos(room, dto.PhotoIDs)
 	if err != nil {
+		// Uploaded photos stay staged, only those of the payload go.
+		util.TEL.Error(ctx, "could not create room", err)
+		releasePhotos(ctx, s.blobRepo, s.imageStore, payloadPhotos)
 		return nil, err
 	}
 
-	// Then add the photos (because we want deterministic filenames, so we need the ID).
+	// The new room may be a candidate of any cached search.
+	s.searchCache.InvalidateAll()
+	roomsCreatedTotal.Inc()
+
+	return room, nil
+}
+
+func (s *service) UploadPhotos(ctx context.Context, callerID uint, files iter.Seq2[[]byte, error]) ([]RoomPhoto, error) {
+	util.TEL.Info(ctx, "user uploads photos", "caller_id", callerID)
+
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-user")
+	defer span.End()
+
+	util.TEL.Debug(ctx, "check if user exists", "id", callerID)
+	caller, err := s.userClient.FindById(ctx, callerID)
+	if err != nil {
+		util.TEL.Error(ctx, "user does not exist", err, "id", callerID)
+		return nil, userLookupError(err, "user", callerID)
+	}
+
+	util.TEL.Debug(ctx, "check if user is a host", "id", callerID)
+	if caller.Role != string(util.Host) {
+		util.TEL.Error(ctx, "user has a bad role", nil, "role", caller.Role)
+		return nil, ErrUnauthorized
+	}
+	span.End()
+
+	// Save and stage each photo as it is read. If any is invalid or cannot be
+	// read, those saved before it are removed.
+
+	ctx, span = util.TEL.Start(reqCtx, "save-photos")
+	defer span.End()
+
+	photos := make([]RoomPhoto, 0)
+	for data, err := range files {
+		if err != nil {
+			s.unstagePhotos(ctx, photos)
+			return nil, err
+		}
 
-	util.TEL.Push(context, "add-all-photos-on-disk")
-	defer util.TEL.Pop()
+		image, err := util.ProcessImage(data)
+		if err != nil {
+			util.TEL.Error(ctx, "invalid photo", err, "index", len(photos))
+			s.unstagePhotos(ctx, photos)
+			return nil, ErrInvalidPhoto(len(photos), err)
+		}
 
-	var photos = make([]string, 0)
-	for _, imageBase64 := range dto.PhotosPayload {
-		imgFname := fmt.Sprintf("room-%d-%d", room.ID, len(photos))
-		_, path, err := util.SaveImageB64(imageBase64, imgFname)
+		photo, err := s.stagePhoto(ctx, callerID, image)
 		if err != nil {
-			util.TEL.Error("could not save image", err, "fname", imgFname)
-			s.repo.Delete(room)
+			s.unstagePhotos(ctx, photos)
 			return nil, err
 		}
-		photos = append(photos, path)
+		photos = append(photos, *photo)
+	}
+
+	if len(photos) == 0 {
+		util.TEL.Error(ctx, "no photos uploaded", nil)
+		return nil, ErrBadRequestCustom("Expected at least one photo")
+	}
+
+	return photos, nil
+}
+
+// stagePhoto saves an uploaded photo and records it as staged.
+func (s *service) stagePhoto(ctx context.Context, callerID uint, image *util.Image) (*RoomPhoto, error) {
+	stored, err := s.storePhoto(ctx, image)
+	if err != nil {
+		return nil, err
+	}
+
+	photo := &RoomPhoto{
+		Key:         stored.Key,
+		Variants:    stored.Variants,
+		ContentType: image.ContentType,
+		Size:        int64(len(image.Data)),
+		UploaderID:  callerID,
+	}
+	if err := s.photoRepo.Create(photo); err != nil {
+		util.TEL.Error(ctx, "could not stage photo", err, "key", stored.Key)
+		releasePhotos(ctx, s.blobRepo, s.imageStore, []Photo{stored})
+		return nil, err
+	}
+
+	return photo, nil
+}
+
+// unstagePhotos removes staged photos, best effort, when an upload fails
+// halfway. A photo is released only once its row is gone: those that cannot
+// be removed are released when the photo reconciler expires them.
+func (s *service) unstagePhotos(ctx context.Context, photos []RoomPhoto) {
+	stored := make([]Photo, 0, len(photos))
+	for _, photo := range photos {
+		deleted, err := s.photoRepo.DeleteStaged(&photo)
+		if err != nil {
+			util.TEL.Warn(ctx, "could not remove staged photo", "id", photo.ID, "error", err)
+			continue
+		}
+		if !deleted {
+			continue
+		}
+		stored = append(stored, photo.Photo())
+	}
+	releasePhotos(ctx, s.blobRepo, s.imageStore, stored)
+}
+
+func (s *service) AttachPhotos(ctx context.Context, callerID uint, roomId uint, photoIds []uint) (*Room, error) {
+	util.TEL.Info(ctx, "attach photos to room", "room_id", roomId, "photo_ids", photoIds)
+
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-room-and-user")
+	defer span.End()
+
+	util.TEL.Debug(ctx, "check if user exists", "id", callerID)
+	caller, err := s.userClient.FindById(ctx, callerID)
+	if err != nil {
+		util.TEL.Error(ctx, "user does not exist", err, "id", callerID)
+		return nil, userLookupError(err, "user", callerID)
+	}
+
+	util.TEL.Debug(ctx, "check if user is a host", "id", callerID)
+	if caller.Role != string(util.Host) {
+		util.TEL.Error(ctx, "user has a bad role", nil, "role", caller.Role)
+		return nil, ErrUnauthorized
+	}
+
+	room, err := s.findHostRoom(ctx, roomId)
+	if err != nil {
+		util.TEL.Error(ctx, "room not found", err, "id", roomId)
+		return nil, err
+	}
+
+	if room.HostID != callerID {
+		util.TEL.Error(ctx, "user does not own the room", nil, "caller_id", callerID, "host_id", room.HostID)
+		return nil, ErrUnauthorized
+	}
+
+	if len(photoIds) == 0 {
+		return nil, ErrBadRequestCustom("No photos to attach")
+	}
+
+	staged, err := s.findStagedPhotos(ctx, callerID, photoIds)
+	if err != nil {
+		return nil, err
+	}
+
+	photos := append([]Photo{}, room.Photos...)
+	for _, photo := range staged {
+		photos = append(photos, photo.Photo())
+	}
+	keys := make([]string, 0, len(photos))
+	for _, photo := range photos {
+		keys = append(keys, photo.Key)
 	}
+	if hasDuplicatePhoto(keys) {
+		return nil, ErrBadRequestCustom("The same picture cannot be added to a room twice")
+	}
+	span.End()
 
-	// Then update the model with the photos.
+	// Attach the photos and reference them from the room at once. The
+	// references of the staged uploads pass to the room.
 
-	util.TEL.Push(context, "add-refs-to-photos-in-db")
-	defer util.TEL.Pop()
+	ctx, span = util.TEL.Start(reqCtx, "attach-photos-in-db")
+	defer span.End()
 
 	room.Photos = photos
-	err = s.repo.Update(room)
+	if err := s.repo.UpdateWithPhotos(room, photoIds); err != nil {
+		util.TEL.Error(ctx, "could not attach uploaded photos", err, "photo_ids", photoIds)
+		return nil, err
+	}
+
+	s.searchCache.InvalidateRooms(room.ID)
+
+	return room, nil
+}
+
+// Longest caption and alt text of a photo, in characters.
+const (
+	maxPhotoCaptionLength = 200
+	maxPhotoAltTextLength = 250
+)
+
+func (s *service) UpdatePhotos(ctx context.Context, callerID uint, roomId uint, dto UpdatePhotosDTO) (*Room, error) {
+	util.TEL.Info(ctx, "update photos of room", "room_id", roomId)
+
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-room-and-user")
+	defer span.End()
+
+	util.TEL.Debug(ctx, "check if user exists", "id", callerID)
+	caller, err := s.userClient.FindById(ctx, callerID)
+	if err != nil {
+		util.TEL.Error(ctx, "user does not exist", err, "id", callerID)
+		return nil, userLookupError(err, "user", callerID)
+	}
+
+	util.TEL.Debug(ctx, "check if user is a host", "id", callerID)
+	if caller.Role != string(util.Host) {
+		util.TEL.Error(ctx, "user has a bad role", nil, "role", caller.Role)
+		return nil, ErrUnauthorized
+	}
+
+	room, err := s.findHostRoom(ctx, roomId)
 	if err != nil {
-		util.TEL.Error("could not create room (updating with photos failed)", err)
-		util.TEL.Warn("deleting room...")
-		s.repo.Delete(room)
+		util.TEL.Error(ctx, "room not found", err, "id", roomId)
+		return nil, err
+	}
+
+	if room.HostID != callerID {
+		util.TEL.Error(ctx, "user does not own the room", nil, "caller_id", callerID, "host_id", room.HostID)
+		return nil, ErrUnauthorized
+	}
+	span.End()
+
+	// The photos must be those of the room, in any order. Those left out are
+	// removed.
+
+	ctx, span = util.TEL.Start(reqCtx, "validate-photos")
+	defer span.End()
+
+	byKey := make(map[string]Photo, len(room.Photos))
+	for _, photo := range room.Photos {
+		byKey[photo.Key] = photo
+	}
+
+	photos := make([]Photo, 0, len(dto.Photos))
+	coverFound := dto.Cover == ""
+	for i, metadata := range dto.Photos {
+		photo, ok := byKey[metadata.Key]
+		if !ok {
+			return nil, ErrBadRequestCustom(fmt.Sprintf("Photo %d (%s) is not a photo of the room or is listed twice", i, metadata.Key))
+		}
+		delete(byKey, metadata.Key)
+
+		if utf8.RuneCountInString(metadata.Caption) > maxPhotoCaptionLength {
+			return nil, ErrBadRequestCustom(fmt.Sprintf("Caption of photo %d is longer than %d characters", i, maxPhotoCaptionLength))
+		}
+		if utf8.RuneCountInString(metadata.AltText) > maxPhotoAltTextLength {
+			return nil, ErrBadRequestCustom(fmt.Sprintf("Alt text of photo %d is longer than %d characters", i, maxPhotoAltTextLength))
+		}
+
+		photo.Caption = strings.TrimSpace(metadata.Caption)
+		photo.AltText = strings.TrimSpace(metadata.AltText)
+		photo.Cover = photo.Key == dto.Cover
+		coverFound = coverFound || photo.Cover
+		photos = append(photos, photo)
+	}
+
+	if !coverFound {
+		return nil, ErrBadRequestCustom(fmt.Sprintf("Cover %s is not a photo of the room", dto.Cover))
+	}
+
+	removed := make([]Photo, 0, len(byKey))
+	removedKeys := make([]string, 0, len(byKey))
+	for _, photo := range room.Photos {
+		if _, ok := byKey[photo.Key]; ok {
+			removed = append(removed, photo)
+			removedKeys = append(removedKeys, photo.Key)
+		}
+	}
+	span.End()
+
+	// Save the photos, then release those removed. If releasing fails, the
+	// photo reconciler deletes their images later.
+
+	ctx, span = util.TEL.Start(reqCtx, "update-photos-in-db")
+	defer span.End()
+
+	room.Photos = photos
+	if err := s.repo.UpdateDetachingPhotos(room, removedKeys); err != nil {
+		util.TEL.Error(ctx, "could not update photos of room", err, "id", room.ID)
 		return nil, err
 	}
 
+	s.searchCache.InvalidateRooms(room.ID)
+
+	releasePhotos(ctx, s.blobRepo, s.imageStore, removed)
+
 	return room, nil
 }
 
-func (s *service) FindById(context context.Context, id uint) (*Room, error) {
-	util.TEL.Info("find room", "id", id)
+// findStagedPhotos returns the photos with the given IDs, in that order, if
+// every one of them was uploaded by the caller and is not used by a room yet.
+func (s *service) findStagedPhotos(ctx context.Context, callerID uint, ids []uint) ([]RoomPhoto, error) {
+	if len(ids) == 0 {
+		return nil, nil
+	}
+
+	seen := make(map[uint]bool, len(ids))
+	for _, id := range ids {
+		if seen[id] {
+			return nil, ErrBadRequestCustom(fmt.Sprintf("Photo %d is listed more than once", id))
+		}
+		seen[id] = true
+	}
+
+	found, err := s.photoRepo.FindByIds(ids)
+	if err != nil {
+		util.TEL.Error(ctx, "could not find photos", err, "ids", ids)
+		return nil, err
+	}
+
+	byId := make(map[uint]RoomPhoto, len(found))
+	for _, photo := range found {
+		byId[photo.ID] = photo
+	}
+
+	photos := make([]RoomPhoto, 0, len(ids))
+	for _, id := range ids {
+		photo, ok := byId[id]
+		if !ok || photo.UploaderID != callerID {
+			return nil, ErrBadRequestCustom(fmt.Sprintf("Photo %d was not uploaded by you", id))
+		}
+		if photo.RoomID != nil {
+			return nil, ErrBadRequestCustom(fmt.Sprintf("Photo %d is already used by a room", id))
+		}
+		photos = append(photos, photo)
+	}
+	return photos, nil
+}
+
+// photoKey returns the storage key of an image: the SHA-256 of its content,
+// so that identical pictures share one copy, and its extension. Images are
+// re-encoded before, so the same upload always gets the same key.
+func photoKey(image *util.Image) string {
+	sum := sha256.Sum256(image.Data)
+	return hex.EncodeToString(sum[:]) + image.Extension
+}
+
+// hasDuplicatePhoto tells if a key is listed twice. A room holds every picture
+// once, since its photos are told apart by key.
+func hasDuplicatePhoto(keys []string) bool {
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			return true
+		}
+		seen[key] = true
+	}
+	return false
+}
+
+// processPhotosPayload decodes and validates the base64 photos of a request.
+func processPhotosPayload(ctx context.Context, payload []string) ([]*util.Image, error) {
+	images := make([]*util.Image, 0, len(payload))
+	for i, imageBase64 := range payload {
+		data, err := util.DecodeImageB64(imageBase64)
+		if err == nil {
+			var image *util.Image
+			image, err = util.ProcessImage(data)
+			images = append(images, image)
+		}
+		if err != nil {
+			util.TEL.Error(ctx, "invalid photo", err, "index", i)
+			return nil, ErrInvalidPhoto(i, err)
+		}
+	}
+	return images, nil
+}
+
+// storePhoto saves an image and its variants, then adds a reference to them
+// (see PhotoBlob). An image already stored is written again with the same
+// bytes. On failure the reference is released.
+func (s *service) storePhoto(ctx context.Context, image *util.Image) (Photo, error) {
+	photo := Photo{Key: photoKey(image)}
+	base := strings.TrimSuffix(photo.Key, image.Extension)
+
+	images := map[string]*util.Image{photo.Key: image}
+	for _, variant := range photoVariants {
+		resized, err := image.Resize(variant.MaxWidth, variant.MaxHeight)
+		if err != nil {
+			util.TEL.Error(ctx, "could not resize image", err, "key", photo.Key, "variant", variant.Name)
+			return Photo{}, err
+		}
+		if resized == nil {
+			continue
+		}
+
+		if photo.Variants == nil {
+			photo.Variants = map[string]string{}
+		}
+		photo.Variants[variant.Name] = base + "-" + variant.Name + resized.Extension
+		images[photo.Variants[variant.Name]] = resized
+	}
+
+	// Reference the images before storing them: releasing the last reference
+	// deletes them, which must not happen after they are stored again.
+
+	if err := s.blobRepo.Acquire(&PhotoBlob{Key: photo.Key, Variants: photo.Variants}); err != nil {
+		util.TEL.Error(ctx, "could not reference image", err, "key", photo.Key)
+		return Photo{}, err
+	}
+
+	for _, key := range photo.Keys() {
+		if err := s.imageStore.Put(ctx, key, images[key].Data, images[key].ContentType); err != nil {
+			util.TEL.Error(ctx, "could not save image", err, "key", key)
+			releasePhotos(ctx, s.blobRepo, s.imageStore, []Photo{photo})
+			return Photo{}, err
+		}
+	}
+
+	return photo, nil
+}
+
+// releasePhotos releases a reference to each photo, and deletes the images of
+// those nothing references anymore. Failures are only logged: the images are
+// orphaned but the photo reconciler deletes them later.
+func releasePhotos(ctx context.Context, blobRepo PhotoBlobRepo, imageStore storage.ImageStore, photos []Photo) {
+	for _, photo := range photos {
+		err := blobRepo.Release(photo.Key, func(blob *PhotoBlob) {
+			for _, key := range blob.Photo().Keys() {
+				if err := imageStore.Delete(ctx, key); err != nil {
+					util.TEL.Error(ctx, "could not delete image, it is orphaned", err, "key", key)
+				}
+			}
+		})
+		if err != nil {
+			util.TEL.Error(ctx, "could not release photo", err, "key", photo.Key)
+		}
+	}
+}
+
+func (s *service) FindById(ctx context.Context, id uint) (*Room, error) {
+	util.TEL.Info(ctx, "find room", "id", id)
 
-	util.TEL.Push(context, "find-room-in-db")
-	defer util.TEL.Pop()
+	ctx, span := util.TEL.Start(ctx, "find-room-in-db")
+	defer span.End()
 
 	room, err := s.repo.FindById(id)
 	if err != nil {
-		util.TEL.Error("room not found", err, "id", id)
+		util.TEL.Error(ctx, "room not found", err, "id", id)
 		return nil, ErrNotFound("room", id)
 	}
 
 	if room.Deleted {
-		util.TEL.Error("room is deleted", err, "id", id)
+		util.TEL.Error(ctx, "room is deleted", err, "id", id)
+		return nil, ErrNotFound("room", id)
+	}
+
+	if room.Status != RoomPublished {
+		util.TEL.Error(ctx, "room is not published", nil, "id", id, "status", room.Status)
+		return nil, ErrNotFound("room", id)
+	}
+
+	return room, nil
+}
+
+// findHostRoom finds a room for its host to prepare or change. Unlike
+// FindById, it finds rooms that are not published, except suspended ones.
+func (s *service) findHostRoom(ctx context.Context, id uint) (*Room, error) {
+	room, err := s.repo.FindById(id)
+	if err != nil || room.Deleted || room.Status == RoomSuspended {
+		util.TEL.Error(ctx, "room not found", err, "id", id)
+		return nil, ErrNotFound("room", id)
+	}
+	return room, nil
+}
+
+// findVisibleRoom finds a room the caller may read the lists of: a published
+// one, or any that is not deleted for its host and admins.
+func (s *service) findVisibleRoom(ctx context.Context, callerID *uint, id uint) (*Room, error) {
+	room, err := s.repo.FindById(id)
+	if err != nil || room.Deleted {
+		util.TEL.Error(ctx, "room not found", err, "id", id)
 		return nil, ErrNotFound("room", id)
 	}
+	if room.Status == RoomPublished {
+		return room, nil
+	}
 
+	seesAll, err := s.callerSeesAll(ctx, callerID, room.HostID)
+	if err != nil {
+		return nil, err
+	}
+	if !seesAll {
+		util.TEL.Error(ctx, "room is not published", nil, "id", id, "status", room.Status)
+		return nil, ErrNotFound("room", id)
+	}
 	return room, nil
 }
 
-func (s *service) FindByHost(context context.Context, hostId uint) ([]Room, error) {
-	util.TEL.Info("find rooms owned by host", "host_id", hostId)
+// callerSeesAll tells whether the caller may see the rooms of the host that
+// are not published: whether it is that host or an admin.
+func (s *service) callerSeesAll(ctx context.Context, callerID *uint, hostId uint) (bool, error) {
+	if callerID == nil {
+		return false, nil
+	}
+	if *callerID == hostId {
+		return true, nil
+	}
+
+	util.TEL.Debug(ctx, "check if caller exists", "id", *callerID)
+	caller, err := s.userClient.FindById(ctx, *callerID)
+	if err != nil {
+		util.TEL.Error(ctx, "caller does not exist", err, "id", *callerID)
+		return false, userLookupError(err, "user", *callerID)
+	}
+	return caller.Role == string(util.Admin), nil
+}
+
+func (s *service) FindByHost(ctx context.Context, callerID *uint, hostId uint) ([]Room, bool, error) {
+	util.TEL.Info(ctx, "find rooms owned by host", "host_id", hostId)
 
-	util.TEL.Push(context, "validate-user")
-	defer util.TEL.Pop()
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-user")
+	defer span.End()
 
 	// Check if user exists.
 
-	util.TEL.Debug("check if user exists", "id", hostId)
-	host, err := s.userClient.FindById(util.TEL.Ctx(), hostId)
+	util.TEL.Debug(ctx, "check if user exists", "id", hostId)
+	host, err := s.userClient.FindById(ctx, hostId)
 	if err != nil {
-		util.TEL.Error("user does not exist", err, "id", hostId)
-		return nil, ErrNotFound("host", hostId)
+		util.TEL.Error(ctx, "user does not exist", err, "id", hostId)
+		return nil, false, userLookupError(err, "host", hostId)
 	}
 
 	// Check if user is host.
 
-	util.TEL.Debug("check if user is a host", "id", hostId)
+	util.TEL.Debug(ctx, "check if user is a host", "id", hostId)
 	if host.Role != string(util.Host) {
-		util.TEL.Error("user has a bad role", nil, "role", host.Role)
-		return nil, ErrUnauthorized
+		util.TEL.Error(ctx, "user has a bad role", nil, "role", host.Role)
+		return nil, false, ErrUnauthorized
+	}
+
+	// Check what the caller may see.
+
+	seesAll, err := s.callerSeesAll(ctx, callerID, hostId)
+	if err != nil {
+		return nil, false, err
 	}
+	span.End()
 
 	// Fetch rooms.
 
-	util.TEL.Push(context, "find-rooms-in-db")
-	defer util.TEL.Pop()
+	ctx, span = util.TEL.Start(reqCtx, "find-rooms-in-db")
+	defer span.End()
 
 	rooms, err := s.repo.FindByHost(hostId)
 	if err != nil {
-		util.TEL.Error("could not find rooms by host", err)
-		return nil, ErrNotFound("rooms of host", hostId)
+		util.TEL.Error(ctx, "could not find rooms by host", err)
+		return nil, false, ErrNotFound("rooms of host", hostId)
 	}
-	return rooms, nil
+	if seesAll {
+		return rooms, true, nil
+	}
+
+	published := make([]Room, 0, len(rooms))
+	for _, room := range rooms {
+		if room.Status == RoomPublished && !room.Deleted {
+			published = append(published, room)
+		}
+	}
+	return published, false, nil
 }
 
-func (s *service) FindAvailabilityListById(context context.Context, id uint) (*RoomAvailabilityList, error) {
-	util.TEL.Info("find room availability list", "list_id", id)
+func (s *service) FindAvailabilityListById(ctx context.Context, callerID *uint, id uint) (*RoomAvailabilityList, error) {
+	util.TEL.Info(ctx, "find room availability list", "list_id", id)
 
-	util.TEL.Push(context, "find-availability-list-in-db")
-	defer util.TEL.Pop()
+	ctx, span := util.TEL.Start(ctx, "find-availability-list-in-db")
+	defer span.End()
 
 	li, err := s.availabiltyRepo.FindListById(id)
 	if err != nil {
-		util.TEL.Error("availability list not found", err, "list_id", id)
+		util.TEL.Error(ctx, "availability list not found", err, "list_id", id)
+		return nil, ErrNotFound("room availability list", id)
+	}
+
+	// A list of a room the caller may not see is not found either.
+	if _, err := s.findVisibleRoom(ctx, callerID, li.RoomID); err != nil {
 		return nil, ErrNotFound("room availability list", id)
 	}
 	return li, err
 }
 
-func (s *service) FindAvailabilityListsByRoomId(context context.Context, roomId uint) ([]RoomAvailabilityList, error) {
-	util.TEL.Info("find availability lists by room", "id", roomId)
-
-	// TODO: Should I push and pop here?
+func (s *service) FindAvailabilityListsByRoomId(ctx context.Context, callerID *uint, roomId uint) ([]RoomAvailabilityList, error) {
+	util.TEL.Info(ctx, "find availability lists by room", "id", roomId)
 
-	_, err := s.FindById(util.TEL.Ctx(), roomId)
-	if err != nil {
-		util.TEL.Error("room not found", err, "id", roomId)
-		return nil, ErrNotFound("room", roomId)
+	if _, err := s.findVisibleRoom(ctx, callerID, roomId); err != nil {
+		return nil, err
 	}
 
-	util.TEL.Push(context, "find-availability-lists-in-db")
-	defer util.TEL.Pop()
+	ctx, span := util.TEL.Start(ctx, "find-availability-lists-in-db")
+	defer span.End()
 
 	lists, err := s.availabiltyRepo.FindListsByRoomId(roomId)
 	if err != nil {
-		util.TEL.Error("availability lists not found", err)
+		util.TEL.Error(ctx, "availability lists not found", err)
 		return nil, ErrNotFound("room availability lists", roomId)
 	}
 	return lists, err
 }
 
-func (s *service) FindCurrentAvailabilityListOfRoom(context context.Context, roomId uint) (*RoomAvailabilityList, error) {
-	util.TEL.Info("find current availability list of room", "room_id", roomId)
+func (s *service) FindCurrentAvailabilityListOfRoom(ctx context.Context, callerID *uint, roomId uint) (*RoomAvailabilityList, error) {
+	util.TEL.Info(ctx, "find current availability list of room", "room_id", roomId)
+
+	if _, err := s.findVisibleRoom(ctx, callerID, roomId); err != nil {
+		return nil, err
+	}
+	return s.findCurrentAvailabilityList(ctx, roomId)
+}
 
-	util.TEL.Push(context, "find-current-availability-list-in-db")
-	defer util.TEL.Pop()
+// findCurrentAvailabilityList finds the current availability list of a room,
+// whatever its status, for the searches and quotes that found the room.
+func (s *service) findCurrentAvailabilityList(ctx context.Context, roomId uint) (*RoomAvailabilityList, error) {
+	ctx, span := util.TEL.Start(ctx, "find-current-availability-list-in-db")
+	defer span.End()
 
 	li, err := s.availabiltyRepo.FindCurrentListOfRoom(roomId)
 	if err != nil {
-		util.TEL.Error("availability list not found", err)
+		util.TEL.Error(ctx, "availability list not found", err)
 		return nil, ErrNotFound("room availability list", roomId)
 	}
 	return li, err
 }
 
-func (s *service) UpdateAvailability(context context.Context, callerID uint, dto CreateRoomAvailabilityListDTO) (*RoomAvailabilityList, error) {
+func (s *service) UpdateAvailability(ctx context.Context, callerID uint, dto CreateRoomAvailabilityListDTO) (*RoomAvailabilityList, error) {
 	// Idea:
 	//
 	// Each list is read-only, when you change it, you're actually creating a new one.
 	// Our API allows modifying a list by giving it the entire array of items.
 	// So this method does both updating and deleting.
 
-	util.TEL.Info("update availability of room", "room_id", dto.RoomID)
+	util.TEL.Info(ctx, "update availability of room", "room_id", dto.RoomID)
 
-	util.TEL.Push(context, "validate-room-and-user")
-	defer util.TEL.Pop()
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-room-and-user")
+	defer span.End()
 
-	util.TEL.Debug("check if user exists", "id", callerID)
-	caller, err := s.userClient.FindById(util.TEL.Ctx(), callerID)
+	util.TEL.Debug(ctx, "check if user exists", "id", callerID)
+	caller, err := s.userClient.FindById(ctx, callerID)
 	if err != nil {
-		util.TEL.Error("user does not exist", err, "id", callerID)
-		return nil, err
+		util.TEL.Error(ctx, "user does not exist", err, "id", callerID)
+		return nil, userLookupError(err, "user", callerID)
 	}
 
-	util.TEL.Debug("check if user is a host", "id", callerID)
+	util.TEL.Debug(ctx, "check if user is a host", "id", callerID)
 	if caller.Role != string(util.Host) {
-		util.TEL.Error("user has a bad role", nil, "role", caller.Role)
+		util.TEL.Error(ctx, "user has a bad role", nil, "role", caller.Role)
 		return nil, ErrUnauthorized
 	}
 
-	util.TEL.Debug("find room", "id", dto.RoomID)
-	// TODO: Should I push and pop here?
-	room, err := s.FindById(util.TEL.Ctx(), dto.RoomID)
+	util.TEL.Debug(ctx, "find room", "id", dto.RoomID)
+	room, err := s.findHostRoom(ctx, dto.RoomID)
 	if err != nil {
-		util.TEL.Error("room not found", err, "id", dto.RoomID)
+		util.TEL.Error(ctx, "room not found", err, "id", dto.RoomID)
 		return nil, err
 	}
 
-	util.TEL.Debug("caller must own the room")
+	util.TEL.Debug(ctx, "caller must own the room")
 	if room.HostID != callerID {
-		util.TEL.Error("user is not owner of this room", nil, "user_id", callerID, "owner_id", room.HostID)
+		util.TEL.Error(ctx, "user is not owner of this room", nil, "user_id", callerID, "owner_id", room.HostID)
 		return nil, ErrUnauthorized
 	}
+	span.End()
 
-	util.TEL.Push(context, "validate-availability-list")
-	defer util.TEL.Pop()
+	ctx, span = util.TEL.Start(reqCtx, "validate-availability-list")
+	defer span.End()
 
-	util.TEL.Debug("create availability list")
+	util.TEL.Debug(ctx, "create availability list")
 	newList := RoomAvailabilityList{
 		RoomID:        dto.RoomID,
 		EffectiveFrom: time.Now(),
 		Items:         make([]RoomAvailabilityItem, 0, len(dto.Items)),
 	}
 
-	util.TEL.Debug("validate and create items for the availability list")
+	util.TEL.Debug(ctx, "validate and create items for the availability list")
 	for i, item := range dto.Items {
 		from := util.ClearYear(item.DateFrom)
 		to := util.ClearYear(item.DateTo)
 
 		if from.After(to) {
-			util.TEL.Error("invalid date range", nil, "from", from, "to", to)
+			util.TEL.Error(ctx, "invalid date range", nil, "from", from, "to", to)
 			return nil, ErrBadRequestCustom(fmt.Sprintf("invalid date range: %v > %v", from, to))
 		}
 
@@ -334,7 +991,7 @@ func (s *service) UpdateAvailability(context context.Context, callerID uint, dto
 			to2 := util.ClearYear(item2.DateTo)
 
 			if from == from2 && to == to2 {
-				util.TEL.Error("duplicate availability rule", nil, "index1", i, "index2", j)
+				util.TEL.Error(ctx, "duplicate availability rule", nil, "index1", i, "index2", j)
 				return nil, ErrBadRequestCustom(fmt.Sprintf("duplicate availability rule at index %d and %d", i, j))
 			}
 		}
@@ -346,106 +1003,121 @@ func (s *service) UpdateAvailability(context context.Context, callerID uint, dto
 			Available: item.Available,
 		})
 	}
+	span.End()
 
-	util.TEL.Push(context, "save-availability-list-to-db")
-	defer util.TEL.Pop()
+	ctx, span = util.TEL.Start(reqCtx, "save-availability-list-to-db")
+	defer span.End()
 
 	err = s.availabiltyRepo.CreateList(&newList)
 	if err != nil {
-		util.TEL.Error("could not create availability list in db", err)
+		util.TEL.Error(ctx, "could not create availability list in db", err)
 		return nil, err
 	}
 
+	s.searchCache.InvalidateRooms(room.ID)
+	availabilityListUpdatesTotal.Inc()
+
 	return &newList, nil
 }
 
-func (s *service) FindPriceListById(context context.Context, id uint) (*RoomPriceList, error) {
-	util.TEL.Info("find room price list", "list_id", id)
+func (s *service) FindPriceListById(ctx context.Context, callerID *uint, id uint) (*RoomPriceList, error) {
+	util.TEL.Info(ctx, "find room price list", "list_id", id)
 
-	util.TEL.Push(context, "find-availability-list-in-db")
-	defer util.TEL.Pop()
+	ctx, span := util.TEL.Start(ctx, "find-availability-list-in-db")
+	defer span.End()
 
 	list, err := s.priceRepo.FindListById(id)
 	if err != nil {
-		util.TEL.Error("room price list not found", err, "list_id", id)
+		util.TEL.Error(ctx, "room price list not found", err, "list_id", id)
+		return nil, ErrNotFound("room price list", id)
+	}
+
+	// A list of a room the caller may not see is not found either.
+	if _, err := s.findVisibleRoom(ctx, callerID, list.RoomID); err != nil {
 		return nil, ErrNotFound("room price list", id)
 	}
 	return list, nil
 }
 
-func (s *service) FindPriceListsByRoomId(context context.Context, roomId uint) ([]RoomPriceList, error) {
-	util.TEL.Info("find room price lists by room", "room_id", roomId)
-
-	util.TEL.Push(context, "find-availability-lists-in-db")
-	defer util.TEL.Pop()
-
-	// TODO: Should I push and pop here?
+func (s *service) FindPriceListsByRoomId(ctx context.Context, callerID *uint, roomId uint) ([]RoomPriceList, error) {
+	util.TEL.Info(ctx, "find room price lists by room", "room_id", roomId)
 
-	_, err := s.FindById(util.TEL.Ctx(), roomId)
-	if err != nil {
-		util.TEL.Error("room not found", err, "id", roomId)
-		return nil, ErrNotFound("room", roomId)
+	if _, err := s.findVisibleRoom(ctx, callerID, roomId); err != nil {
+		return nil, err
 	}
 
+	ctx, span := util.TEL.Start(ctx, "find-price-lists-in-db")
+	defer span.End()
+
 	lists, err := s.priceRepo.FindListsByRoomId(roomId)
 	if err != nil {
-		util.TEL.Error("room price lists of room not found", err, "room_id", roomId)
+		util.TEL.Error(ctx, "room price lists of room not found", err, "room_id", roomId)
 		return nil, ErrNotFound("room price lists", roomId)
 	}
 	return lists, nil
 }
 
-func (s *service) FindCurrentPriceListOfRoom(context context.Context, roomId uint) (*RoomPriceList, error) {
-	util.TEL.Info("find current price list of room", nil, "room_id", roomId)
+func (s *service) FindCurrentPriceListOfRoom(ctx context.Context, callerID *uint, roomId uint) (*RoomPriceList, error) {
+	util.TEL.Info(ctx, "find current price list of room", "room_id", roomId)
 
-	util.TEL.Push(context, "find-current-price-list-in-db")
-	defer util.TEL.Pop()
+	if _, err := s.findVisibleRoom(ctx, callerID, roomId); err != nil {
+		return nil, err
+	}
+	return s.findCurrentPriceList(ctx, roomId)
+}
+
+// findCurrentPriceList finds the current price list of a room, whatever its
+// status, for the searches and quotes that found the room.
+func (s *service) findCurrentPriceList(ctx context.Context, roomId uint) (*RoomPriceList, error) {
+	ctx, span := util.TEL.Start(ctx, "find-current-price-list-in-db")
+	defer span.End()
 
 	list, err := s.priceRepo.FindCurrentListOfRoom(roomId)
 	if err != nil {
-		util.TEL.Error("room current price lists of room not found", err, "room_id", roomId)
+		util.TEL.Error(ctx, "room current price lists of room not found", err, "room_id", roomId)
 		return nil, ErrNotFound("room price list", roomId)
 	}
 	return list, nil
 }
 
-func (s *service) UpdatePriceList(context context.Context, callerID uint, dto CreateRoomPriceListDTO) (*RoomPriceList, error) {
-	util.TEL.Info("update price list of room %d", nil, "room_id", dto.RoomID)
+func (s *service) UpdatePriceList(ctx context.Context, callerID uint, dto CreateRoomPriceListDTO) (*RoomPriceList, error) {
+	util.TEL.Info(ctx, "update price list of room %d", nil, "room_id", dto.RoomID)
 
-	util.TEL.Push(context, "validate-room-and-user")
-	defer util.TEL.Pop()
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-room-and-user")
+	defer span.End()
 
-	util.TEL.Debug("check if user exists", "id", callerID)
-	caller, err := s.userClient.FindById(util.TEL.Ctx(), callerID)
+	util.TEL.Debug(ctx, "check if user exists", "id", callerID)
+	caller, err := s.userClient.FindById(ctx, callerID)
 	if err != nil {
-		util.TEL.Error("user does not exist", err, "id", callerID)
-		return nil, err
+		util.TEL.Error(ctx, "user does not exist", err, "id", callerID)
+		return nil, userLookupError(err, "user", callerID)
 	}
 
-	util.TEL.Debug("check if user is a host", "id", callerID)
+	util.TEL.Debug(ctx, "check if user is a host", "id", callerID)
 	if caller.Role != string(util.Host) {
-		util.TEL.Error("user has a bad role", nil, "role", caller.Role)
+		util.TEL.Error(ctx, "user has a bad role", nil, "role", caller.Role)
 		return nil, ErrUnauthorized
 	}
 
-	util.TEL.Debug("find room", "id", dto.RoomID)
-	// TODO: Should I push and pop here?
-	room, err := s.FindById(util.TEL.Ctx(), dto.RoomID)
+	util.TEL.Debug(ctx, "find room", "id", dto.RoomID)
+	room, err := s.findHostRoom(ctx, dto.RoomID)
 	if err != nil {
-		util.TEL.Error("room not found", err, "id", dto.RoomID)
+		util.TEL.Error(ctx, "room not found", err, "id", dto.RoomID)
 		return nil, err
 	}
 
-	util.TEL.Debug("caller must own the room")
+	util.TEL.Debug(ctx, "caller must own the room")
 	if room.HostID != callerID {
-		util.TEL.Error("user is not owner of this room", nil, "user_id", callerID, "owner_id", room.HostID)
+		util.TEL.Error(ctx, "user is not owner of this room", nil, "user_id", callerID, "owner_id", room.HostID)
 		return nil, ErrUnauthorized
 	}
+	span.End()
 
-	util.TEL.Push(context, "validate-availability-list")
-	defer util.TEL.Pop()
+	ctx, span = util.TEL.Start(reqCtx, "validate-availability-list")
+	defer span.End()
 
-	util.TEL.Debug("create price list")
+	util.TEL.Debug(ctx, "create price list")
 	newList := RoomPriceList{
 		RoomID:        dto.RoomID,
 		EffectiveFrom: time.Now(),
@@ -454,13 +1126,13 @@ func (s *service) UpdatePriceList(context context.Context, callerID uint, dto Cr
 		Items:         make([]RoomPriceItem, 0, len(dto.Items)),
 	}
 
-	util.TEL.Debug("validate and create items for the price list")
+	util.TEL.Debug(ctx, "validate and create items for the price list")
 	for i, item := range dto.Items {
 		from := util.ClearYear(item.DateFrom)
 		to := util.ClearYear(item.DateTo)
 
 		if from.After(to) {
-			util.TEL.Error("invalid date range", nil, "from", from, "to", to)
+			util.TEL.Error(ctx, "invalid date range", nil, "from", from, "to", to)
 			return nil, ErrBadRequestCustom(fmt.Sprintf("invalid date range: %v > %v", from, to))
 		}
 
@@ -473,7 +1145,7 @@ func (s *service) UpdatePriceList(context context.Context, callerID uint, dto Cr
 			to2 := util.ClearYear(item2.DateTo)
 
 			if !from.After(to2) && !from2.After(to) {
-				util.TEL.Error("price rules conflict (no intersections allowed)", nil, "item1", i, "item2", j)
+				util.TEL.Error(ctx, "price rules conflict (no intersections allowed)", nil, "item1", i, "item2", j)
 				return nil, ErrBadRequestCustom(fmt.Sprintf("price rules at index %d and %d conflict (no intersections allowed)", i, j))
 			}
 		}
@@ -485,72 +1157,82 @@ func (s *service) UpdatePriceList(context context.Context, callerID uint, dto Cr
 			Price:    item.Price,
 		})
 	}
+	span.End()
 
-	util.TEL.Push(context, "save-price-list-to-db")
-	defer util.TEL.Pop()
+	ctx, span = util.TEL.Start(reqCtx, "save-price-list-to-db")
+	defer span.End()
 
 	err = s.priceRepo.CreateList(&newList)
 	if err != nil {
-		util.TEL.Error("could not create price list in db", err)
+		util.TEL.Error(ctx, "could not create price list in db", err)
 		return nil, err
 	}
 
+	s.searchCache.InvalidateRooms(room.ID)
+	priceListUpdatesTotal.Inc()
+
 	return &newList, nil
 }
 
-func (s *service) ClearYear(context context.Context, dateFrom time.Time, dateTo time.Time) (time.Time, time.Time) {
-	util.TEL.Debug("Clearing year from date range", "from", dateFrom, "to", dateTo)
+func (s *service) ClearYear(ctx context.Context, dateFrom time.Time, dateTo time.Time) (time.Time, time.Time) {
+	util.TEL.Debug(ctx, "Clearing year from date range", "from", dateFrom, "to", dateTo)
 	dateFrom = util.ClearYear(dateFrom)
 	dateTo = util.ClearYear(dateTo)
-	util.TEL.Debug("Resulting date range", "from", dateFrom, "to", dateTo)
+	util.TEL.Debug(ctx, "Resulting date range", "from", dateFrom, "to", dateTo)
 	return dateFrom, dateTo
 }
 
-func (s *service) CalculatePriceForOneDay(context context.Context, day time.Time, guests uint, rules RoomPriceList) float32 {
-	util.TEL.Info("calculating price for one day", "day", day, "guests", guests, "room_id", rules.RoomID, "pricelist_id", rules.ID)
+func (s *service) CalculatePriceForOneDay(ctx context.Context, day time.Time, guests uint, rules RoomPriceList) float32 {
+	util.TEL.Info(ctx, "calculating price for one day", "day", day, "guests", guests, "room_id", rules.RoomID, "pricelist_id", rules.ID)
 
 	normalizedDay := util.ClearYear(day)
 	price := rules.BasePrice
 
 	for _, rule := range rules.Items {
-		rule.DateFrom, rule.DateTo = s.ClearYear(util.TEL.Ctx(), rule.DateFrom, rule.DateTo)
+		rule.DateFrom, rule.DateTo = s.ClearYear(ctx, rule.DateFrom, rule.DateTo)
 
 		if !normalizedDay.Before(rule.DateFrom) && !normalizedDay.After(rule.DateTo) {
 			price = rule.Price
 		}
 	}
-	util.TEL.Debug("unit price for this day is", "price", price)
+	util.TEL.Debug(ctx, "unit price for this day is", "price", price)
 
 	if rules.PerGuest {
-		util.TEL.Debug("price is per guest")
+		util.TEL.Debug(ctx, "price is per guest")
 		return float32(price * guests)
 	}
 
-	util.TEL.Debug("price is flat rate")
+	util.TEL.Debug(ctx, "price is flat rate")
 	return float32(price)
 }
 
-func (s *service) CalculatePrice(context context.Context, dateFrom time.Time, dateTo time.Time, guests uint, roomId uint) (float32, bool, error) {
-	util.TEL.Info("calculating price for a date range", "from", dateFrom, "to", dateTo, "guests", guests, "room_id", roomId)
+func (s *service) CalculatePrice(ctx context.Context, dateFrom time.Time, dateTo time.Time, guests uint, roomId uint) (float32, bool, error) {
+	util.TEL.Info(ctx, "calculating price for a date range", "from", dateFrom, "to", dateTo, "guests", guests, "room_id", roomId)
 
-	rules, err := s.FindCurrentPriceListOfRoom(util.TEL.Ctx(), roomId)
+	rules, err := s.findCurrentPriceList(ctx, roomId)
 	if err != nil {
 		return float32(0), false, err
 	}
 
-	dateFrom, dateTo = s.ClearYear(util.TEL.Ctx(), dateFrom, dateTo)
+	totalPrice := s.priceOfStay(ctx, dateFrom, dateTo, guests, *rules)
+	util.TEL.Debug(ctx, "result", "total_price", totalPrice, "price_is_per_guest", rules.PerGuest)
+
+	return totalPrice, rules.PerGuest, nil
+}
+
+// priceOfStay sums the price of every day between dateFrom and dateTo.
+func (s *service) priceOfStay(ctx context.Context, dateFrom time.Time, dateTo time.Time, guests uint, rules RoomPriceList) float32 {
+	dateFrom, dateTo = s.ClearYear(ctx, dateFrom, dateTo)
 	var totalPrice float32
 
 	for day := dateFrom; !day.After(dateTo); day = day.Add(24 * time.Hour) {
-		totalPrice += s.CalculatePriceForOneDay(util.TEL.Ctx(), day, guests, *rules)
+		totalPrice += s.CalculatePriceForOneDay(ctx, day, guests, rules)
 	}
-	util.TEL.Debug("result", "total_price", totalPrice, "price_is_per_guest", rules.PerGuest)
-
-	return totalPrice, rules.PerGuest, nil
+	return totalPrice
 }
 
-func (s *service) IsRoomAvailableForOneDay(context context.Context, day time.Time, rules []RoomAvailabilityItem) bool {
-	util.TEL.Info("is the room available on a specific day", "day", day)
+func (s *service) IsRoomAvailableForOneDay(ctx context.Context, day time.Time, rules []RoomAvailabilityItem) bool {
+	util.TEL.Info(ctx, "is the room available on a specific day", "day", day)
 
 	leastRule := RoomAvailabilityItem{
 		DateFrom:  time.Time{}, // The zero value represents the earliest possible time
@@ -561,7 +1243,7 @@ func (s *service) IsRoomAvailableForOneDay(context context.Context, day time.Tim
 	dayNormalized := util.ClearYear(day)
 
 	for _, rule := range rules {
-		rule.DateFrom, rule.DateTo = s.ClearYear(util.TEL.Ctx(), rule.DateFrom, rule.DateTo)
+		rule.DateFrom, rule.DateTo = s.ClearYear(ctx, rule.DateFrom, rule.DateTo)
 
 		if !dayNormalized.Before(rule.DateFrom) && !dayNormalized.After(rule.DateTo) {
 			if rule.DateTo.Sub(rule.DateFrom) < leastRule.DateTo.Sub(leastRule.DateFrom) {
@@ -573,28 +1255,34 @@ func (s *service) IsRoomAvailableForOneDay(context context.Context, day time.Tim
 	return leastRule.Available
 }
 
-func (s *service) IsRoomAvailable(context context.Context, dateFrom time.Time, dateTo time.Time, roomId uint) bool {
-	util.TEL.Info("is the room available between multiple days", "from", dateFrom, "to", dateTo, "room_id", roomId)
-
-	dateFrom, dateTo = s.ClearYear(util.TEL.Ctx(), dateFrom, dateTo)
+func (s *service) IsRoomAvailable(ctx context.Context, dateFrom time.Time, dateTo time.Time, roomId uint) bool {
+	util.TEL.Info(ctx, "is the room available between multiple days", "from", dateFrom, "to", dateTo, "room_id", roomId)
 
-	rules, err := s.FindCurrentAvailabilityListOfRoom(util.TEL.Ctx(), roomId)
+	rules, err := s.findCurrentAvailabilityList(ctx, roomId)
 	if err != nil {
-		util.TEL.Debug("no availability list => room is unavailable")
+		util.TEL.Debug(ctx, "no availability list => room is unavailable")
 		return false
 	}
 
+	return s.isAvailableForStay(ctx, dateFrom, dateTo, rules.Items)
+}
+
+// isAvailableForStay checks if every day between dateFrom and dateTo is
+// available according to the rules.
+func (s *service) isAvailableForStay(ctx context.Context, dateFrom time.Time, dateTo time.Time, rules []RoomAvailabilityItem) bool {
+	dateFrom, dateTo = s.ClearYear(ctx, dateFrom, dateTo)
+
 	for day := dateFrom; !day.After(dateTo); day = day.Add(24 * time.Hour) {
-		if s.IsRoomAvailableForOneDay(util.TEL.Ctx(), day, rules.Items) == false {
-			util.TEL.Debug("room is unavailable on this day", "day", day)
+		if s.IsRoomAvailableForOneDay(ctx, day, rules) == false {
+			util.TEL.Debug(ctx, "room is unavailable on this day", "day", day)
 			return false
 		}
 	}
 	return true
 }
 
-func (s *service) CalculateUnitPrice(context context.Context, perGuest bool, guestsNumber uint, dateFrom time.Time, dateTo time.Time, totalPrice float32) float32 {
-	util.TEL.Info("calculating unit price", "guests", guestsNumber, "per_guest", perGuest, "from", dateFrom, "to", dateTo, "total_price", totalPrice)
+func (s *service) CalculateUnitPrice(ctx context.Context, perGuest bool, guestsNumber uint, dateFrom time.Time, dateTo time.Time, totalPrice float32) float32 {
+	util.TEL.Info(ctx, "calculating unit price", "guests", guestsNumber, "per_guest", perGuest, "from", dateFrom, "to", dateTo, "total_price", totalPrice)
 
 	var unitPrice float32
 	interval := float32(dateTo.Sub(dateFrom).Hours()/24) + 1
@@ -605,48 +1293,38 @@ func (s *service) CalculateUnitPrice(context context.Context, perGuest bool, gue
 		unitPrice = totalPrice / interval
 	}
 
-	util.TEL.Info("unit price is", "price", unitPrice)
+	util.TEL.Info(ctx, "unit price is", "price", unitPrice)
 
 	return unitPrice
 }
 
-func (s *service) PreparePaginatedResult(context context.Context, hits []RoomResultDTO, pageNumber uint, pageSize uint) ([]RoomResultDTO, PaginatedResultInfoDTO) {
-	totalHits := len(hits)
-	totalPages := uint(math.Ceil(float64(totalHits) / float64(pageSize)))
-	startIdx := uint((pageNumber - 1) * pageSize)
-	endIdx := startIdx + pageSize
-	lastPage := totalPages - 1
-	lastPageStartIdx := lastPage * pageSize
-	lastPageEndIdx := uint(totalHits)
+func (s *service) PreparePaginatedResult(ctx context.Context, hits []RoomResultDTO, pageNumber uint, pageSize uint) ([]RoomResultDTO, PaginatedResultInfoDTO) {
+	resultInfo := newPaginatedResultInfo(len(hits), pageSize)
+	resultInfo.Page = pageNumber
 
-	resultInfo := PaginatedResultInfoDTO{
-		Page:       pageNumber,
-		PageSize:   pageSize,
-		TotalPages: totalPages,
-		TotalHits:  uint(totalHits),
+	if pageNumber < 1 || pageNumber > resultInfo.TotalPages {
+		util.TEL.Debug(ctx, "page is out of range", "page", pageNumber, "total_pages", resultInfo.TotalPages)
+		return []RoomResultDTO{}, resultInfo
 	}
 
-	if totalHits != 0 {
-		// Show last page result if page number exceeds total
-		if startIdx > lastPageEndIdx {
-			startIdx = lastPageStartIdx
-			endIdx = lastPageEndIdx
-		}
-
-		// Case when the last page is selected
-		if endIdx > lastPageEndIdx {
-			endIdx = lastPageEndIdx
-		}
+	startIdx := (pageNumber - 1) * pageSize
+	endIdx := min(startIdx+pageSize, uint(len(hits)))
+	return hits[startIdx:endIdx], resultInfo
+}
 
-		hits = hits[startIdx:endIdx]
+// newPaginatedResultInfo counts the pages of totalHits hits. The page and
+// cursors are left for the caller to fill in.
+func newPaginatedResultInfo(totalHits int, pageSize uint) PaginatedResultInfoDTO {
+	return PaginatedResultInfoDTO{
+		PageSize:   pageSize,
+		TotalPages: uint(math.Ceil(float64(totalHits) / float64(pageSize))),
+		TotalHits:  uint(totalHits),
 	}
-
-	return hits, resultInfo
 }
 
-func (s *service) ExcludeDeletedRooms(context context.Context, rooms []Room) []Room {
-	util.TEL.Push(context, "filter out deleted rooms")
-	defer util.TEL.Pop()
+func (s *service) ExcludeDeletedRooms(ctx context.Context, rooms []Room) []Room {
+	_, span := util.TEL.Start(ctx, "filter out deleted rooms")
+	defer span.End()
 
 	var notDeletedRooms []Room
 	for _, room := range rooms {
@@ -657,89 +1335,351 @@ func (s *service) ExcludeDeletedRooms(context context.Context, rooms []Room) []R
 	return notDeletedRooms
 }
 
-func (s *service) FindAvailableRooms(context context.Context, dto RoomsQueryDTO) ([]RoomResultDTO, *PaginatedResultInfoDTO, error) {
-	util.TEL.Info("find available rooms from query", "query", fmt.Sprintf("%+v", dto))
+func excludeUnpublishedRooms(rooms []Room) []Room {
+	var listed []Room
+	for _, room := range rooms {
+		if room.Status == RoomPublished {
+			listed = append(listed, room)
+		}
+	}
+	return listed
+}
+
+func (s *service) FindAvailableRooms(ctx context.Context, dto RoomsQueryDTO) ([]RoomResultDTO, *PaginatedResultInfoDTO, error) {
+	util.TEL.Info(ctx, "find available rooms from query", "query", fmt.Sprintf("%+v", dto))
+
+	dto.Address = strings.TrimSpace(dto.Address)
+	dto.Query = strings.TrimSpace(dto.Query)
 
 	from := util.ClearYear(dto.DateFrom)
 	to := util.ClearYear(dto.DateTo)
 
-	util.TEL.Push(context, "find by filters")
-	defer util.TEL.Pop()
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "find by filters")
+	defer span.End()
 
 	if from.After(to) {
-		util.TEL.Error("invalid date range", nil, "from", from, "to", to)
+		util.TEL.Error(ctx, "invalid date range", nil, "from", from, "to", to)
 		return nil, nil, ErrBadRequestCustom(fmt.Sprintf("invalid date range: %v > %v", from, to))
 	}
 
-	rooms, err := s.repo.FindByFilters(dto.GuestsNumber, strings.TrimSpace(dto.Address))
+	if dto.StayLength > daysBetween(from, to) {
+		util.TEL.Error(ctx, "stay does not fit in the search window", nil, "stay_length", dto.StayLength, "from", from, "to", to)
+		return nil, nil, ErrBadRequestCustom(fmt.Sprintf("stay of %d days does not fit between %v and %v", dto.StayLength, from, to))
+	}
+
+	cacheKey := SearchCacheKey(dto)
+	hits, cached := s.searchCache.Get(cacheKey)
+	if cached {
+		util.TEL.Debug(ctx, "search hits found in cache", "key", cacheKey)
+	} else {
+		var roomIDs []uint
+		var err error
+		hits, roomIDs, err = s.findAvailableRoomHits(ctx, dto)
+		if err != nil {
+			return nil, nil, err
+		}
+		s.searchCache.Set(cacheKey, hits, roomIDs)
+	}
+
+	searchMode, searchCacheResult := "fixed", "miss"
+	if dto.StayLength > 0 {
+		searchMode = "flexible"
+	}
+	if cached {
+		searchCacheResult = "hit"
+	}
+	searchesTotal.WithLabelValues(searchMode, searchCacheResult).Inc()
+	searchHits.Observe(float64(len(hits)))
+	span.End()
+
+	ctx, span = util.TEL.Start(reqCtx, "build result")
+	defer span.End()
+
+	order := searchHitOrder(dto)
+
+	if dto.Cursor != "" {
+		util.TEL.Debug(ctx, "paginating by cursor")
+		page, prev, next, err := PaginateByCursor(hits, order, dto.Cursor, dto.PageSize)
+		if err != nil {
+			util.TEL.Error(ctx, "could not paginate by cursor", err)
+			return nil, nil, err
+		}
+
+		resultInfo := newPaginatedResultInfo(len(hits), dto.PageSize)
+		resultInfo.PrevCursor = prev
+		resultInfo.NextCursor = next
+		return page, &resultInfo, nil
+	}
+
+	page, resultInfo := s.PreparePaginatedResult(ctx, hits, dto.PageNumber, dto.PageSize)
+	if len(page) > 0 {
+		// The page is a window of hits, find where it starts to issue cursors.
+		start := 0
+		for i := range hits {
+			if hits[i].ID == page[0].ID {
+				start = i
+				break
+			}
+		}
+		resultInfo.PrevCursor, resultInfo.NextCursor = windowCursors(hits, start, start+len(page), order)
+	}
+
+	return page, &resultInfo, nil
+}
+
+// findAvailableRoomHits evaluates every room matching the query filters and
+// returns the sorted hits, along with the IDs of all evaluated rooms.
+func (s *service) findAvailableRoomHits(ctx context.Context, dto RoomsQueryDTO) ([]RoomResultDTO, []uint, error) {
+	rooms, err := s.repo.FindByFilters(dto.GuestsNumber, dto.Address, dto.Query)
 	if err != nil {
-		util.TEL.Error("could not perform query", err)
+		util.TEL.Error(ctx, "could not perform query", err)
 		return nil, nil, err
 	}
 
-	rooms = s.ExcludeDeletedRooms(context, rooms)
+	roomIDs := make([]uint, 0, len(rooms))
+	for _, room := range rooms {
+		roomIDs = append(roomIDs, room.ID)
+	}
 
-	util.TEL.Push(context, "get price for each hit")
-	defer util.TEL.Pop()
+	rooms = s.ExcludeDeletedRooms(ctx, rooms)
+	rooms = excludeUnpublishedRooms(rooms)
 
-	var hits []RoomResultDTO
-	for _, room := range rooms {
-		canBook := s.IsRoomAvailable(util.TEL.Ctx(), from, to, room.ID)
+	ctx, span := util.TEL.Start(ctx, "get price for each hit")
+	defer span.End()
+
+	// Rooms are evaluated concurrently. Each worker writes to its own slot so
+	// that the order of the hits stays the order of the rooms.
+	results := make([]*RoomResultDTO, len(rooms))
+	group, groupCtx := errgroup.WithContext(ctx)
+	group.SetLimit(s.searchConcurrency)
+
+	for i, room := range rooms {
+		group.Go(func() error {
+			if err := groupCtx.Err(); err != nil {
+				return err
+			}
 
-		if canBook {
-			totalPrice, perGuest, err := s.CalculatePrice(util.TEL.Ctx(), from, to, dto.GuestsNumber, room.ID)
+			hit, err := s.evaluateRoom(groupCtx, room, dto)
 			if err != nil {
-				util.TEL.Error("could not calculate price", err)
-				continue
+				util.TEL.Error(ctx, "could not calculate price", err, "room_id", room.ID)
+				return nil
 			}
-			unitPrice := s.CalculateUnitPrice(util.TEL.Ctx(), perGuest, dto.GuestsNumber, from, to, totalPrice)
-			hits = append(hits, NewRoomResultDTO(room, perGuest, unitPrice, totalPrice))
+			results[i] = hit
+			return nil
+		})
+	}
+
+	if err := group.Wait(); err != nil {
+		util.TEL.Error(ctx, "search was cancelled", err)
+		return nil, nil, err
+	}
+
+	var hits []RoomResultDTO
+	for _, hit := range results {
+		if hit != nil {
+			hits = append(hits, *hit)
 		}
 	}
 
-	util.TEL.Push(context, "build result")
-	defer util.TEL.Pop()
+	// Hits are already ordered by relevance (or ID) by the repository.
+	if dto.SortBy == SortByPrice {
+		util.TEL.Debug(ctx, "sorting hits by price")
+		sort.SliceStable(hits, func(i, j int) bool {
+			if hits[i].TotalPrice != hits[j].TotalPrice {
+				return hits[i].TotalPrice < hits[j].TotalPrice
+			}
+			return hits[i].ID < hits[j].ID
+		})
+	}
 
-	hits, resultInfo := s.PreparePaginatedResult(util.TEL.Ctx(), hits, dto.PageNumber, dto.PageSize)
+	return hits, roomIDs, nil
+}
 
-	return hits, &resultInfo, nil
+// evaluateRoom checks if the room can be booked for the query and prices it.
+// Returns nil (and no error) if the room cannot be booked.
+//
+// It reads the rules from the repositories directly instead of going through
+// the service methods, so that a search does not open a pair of spans (and log
+// a pair of lookups) for every candidate room.
+func (s *service) evaluateRoom(ctx context.Context, room Room, dto RoomsQueryDTO) (*RoomResultDTO, error) {
+	availability, err := s.availabiltyRepo.FindCurrentListOfRoom(room.ID)
+	if err != nil {
+		util.TEL.Debug(ctx, "no availability list => room is unavailable", "room_id", room.ID)
+		return nil, nil
+	}
+
+	if dto.StayLength == 0 && !s.isAvailableForStay(ctx, dto.DateFrom, dto.DateTo, availability.Items) {
+		return nil, nil
+	}
+
+	prices, err := s.priceRepo.FindCurrentListOfRoom(room.ID)
+	if err != nil {
+		return nil, ErrNotFound("room price list", room.ID)
+	}
+
+	var hit RoomResultDTO
+	if dto.StayLength > 0 {
+		stay := s.findFlexibleStay(ctx, dto.DateFrom, dto.DateTo, dto.StayLength, dto.GuestsNumber, dto.FlexibleMode, *availability, *prices)
+		if stay == nil {
+			return nil, nil
+		}
+
+		stayFrom, stayTo := s.ClearYear(ctx, stay.DateFrom, stay.DateTo)
+		unitPrice := s.CalculateUnitPrice(ctx, stay.PerGuest, dto.GuestsNumber, stayFrom, stayTo, stay.TotalPrice)
+
+		hit = NewRoomResultDTO(room, stay.PerGuest, unitPrice, stay.TotalPrice)
+		hit.DateFrom = &stay.DateFrom
+		hit.DateTo = &stay.DateTo
+	} else {
+		from, to := s.ClearYear(ctx, dto.DateFrom, dto.DateTo)
+		totalPrice := s.priceOfStay(ctx, from, to, dto.GuestsNumber, *prices)
+		unitPrice := s.CalculateUnitPrice(ctx, prices.PerGuest, dto.GuestsNumber, from, to, totalPrice)
+
+		hit = NewRoomResultDTO(room, prices.PerGuest, unitPrice, totalPrice)
+	}
+
+	return &hit, nil
 }
 
-func (s *service) QueryForReservation(context context.Context, callerID uint, dto RoomReservationQueryDTO) (*RoomReservationQueryResponseDTO, error) {
-	util.TEL.Info("query room for reservation", "id", dto.RoomID)
+func (s *service) FindFlexibleStay(ctx context.Context, roomId uint, dateFrom time.Time, dateTo time.Time, stayLength uint, guests uint, mode string) (*FlexibleStay, error) {
+	util.TEL.Info(ctx, "find flexible stay", "room_id", roomId, "from", dateFrom, "to", dateTo, "stay_length", stayLength, "mode", mode)
 
-	util.TEL.Push(context, "validate-room-and-user")
-	defer util.TEL.Pop()
+	availability, err := s.findCurrentAvailabilityList(ctx, roomId)
+	if err != nil {
+		util.TEL.Debug(ctx, "no availability list => room is unavailable")
+		return nil, nil
+	}
 
-	util.TEL.Debug("check if user exists", "id", callerID)
-	caller, err := s.userClient.FindById(util.TEL.Ctx(), callerID)
+	prices, err := s.findCurrentPriceList(ctx, roomId)
 	if err != nil {
-		util.TEL.Error("user does not exist", err, "id", callerID)
 		return nil, err
 	}
 
-	util.TEL.Debug("check if user is a guest", "id", callerID)
+	return s.findFlexibleStay(ctx, dateFrom, dateTo, stayLength, guests, mode, *availability, *prices), nil
+}
+
+func (s *service) findFlexibleStay(
+	ctx context.Context,
+	dateFrom time.Time,
+	dateTo time.Time,
+	stayLength uint,
+	guests uint,
+	mode string,
+	availability RoomAvailabilityList,
+	prices RoomPriceList,
+) *FlexibleStay {
+	from, to := s.ClearYear(ctx, dateFrom, dateTo)
+	windowLength := daysBetween(from, to)
+	if stayLength == 0 || stayLength > windowLength {
+		util.TEL.Debug(ctx, "stay does not fit in the window", "window_length", windowLength)
+		return nil
+	}
+
+	// Evaluate every day of the window once, then slide a window of
+	// stayLength days over it.
+
+	available := make([]bool, windowLength)
+	dayPrices := make([]float32, windowLength)
+	for i := range windowLength {
+		day := from.Add(time.Duration(i) * 24 * time.Hour)
+		available[i] = s.IsRoomAvailableForOneDay(ctx, day, availability.Items)
+		dayPrices[i] = s.CalculatePriceForOneDay(ctx, day, guests, prices)
+	}
+
+	var best *FlexibleStay
+	var unavailableDays uint
+	var stayPrice float32
+	for i := range windowLength {
+		if !available[i] {
+			unavailableDays++
+		}
+		stayPrice += dayPrices[i]
+
+		if i >= stayLength {
+			if !available[i-stayLength] {
+				unavailableDays--
+			}
+			stayPrice -= dayPrices[i-stayLength]
+		}
+
+		if i+1 < stayLength || unavailableDays > 0 {
+			continue
+		}
+
+		if best == nil || stayPrice < best.TotalPrice {
+			start := i + 1 - stayLength
+			best = &FlexibleStay{
+				DateFrom:   dateFrom.AddDate(0, 0, int(start)),
+				DateTo:     dateFrom.AddDate(0, 0, int(i)),
+				TotalPrice: stayPrice,
+				PerGuest:   prices.PerGuest,
+			}
+		}
+
+		if mode == FlexibleModeEarliest {
+			break
+		}
+	}
+
+	if best == nil {
+		util.TEL.Debug(ctx, "room has no free stay in the window")
+	}
+	return best
+}
+
+// daysBetween returns the number of days in the (inclusive) date range.
+func daysBetween(from time.Time, to time.Time) uint {
+	if from.After(to) {
+		return 0
+	}
+	return uint(math.Round(to.Sub(from).Hours()/24)) + 1
+}
+
+func (s *service) QueryForReservation(ctx context.Context, callerID uint, dto RoomReservationQueryDTO) (*RoomReservationQueryResponseDTO, error) {
+	util.TEL.Info(ctx, "query room for reservation", "id", dto.RoomID)
+
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-room-and-user")
+	defer span.End()
+
+	util.TEL.Debug(ctx, "check if user exists", "id", callerID)
+	caller, err := s.userClient.FindById(ctx, callerID)
+	if err != nil {
+		util.TEL.Error(ctx, "user does not exist", err, "id", callerID)
+		if errors.Is(err, userclient.ErrUserServiceUnavailable) {
+			quoteFailuresTotal.WithLabelValues(quoteFailureUserServiceUnavailable).Inc()
+		} else {
+			quoteFailuresTotal.WithLabelValues(quoteFailureUserNotFound).Inc()
+		}
+		return nil, userLookupError(err, "user", callerID)
+	}
+
+	util.TEL.Debug(ctx, "check if user is a guest", "id", callerID)
 	if caller.Role != string(util.Guest) {
-		util.TEL.Error("user has a bad role", nil, "role", caller.Role)
+		util.TEL.Error(ctx, "user has a bad role", nil, "role", caller.Role)
+		quoteFailuresTotal.WithLabelValues(quoteFailureNotGuest).Inc()
 		return nil, ErrUnauthorized
 	}
 
-	util.TEL.Debug("find room", "id", dto.RoomID)
-	// TODO: Should I push and pop here? and elsewhere where i call subfunc
-	room, err := s.FindById(util.TEL.Ctx(), dto.RoomID)
+	util.TEL.Debug(ctx, "find room", "id", dto.RoomID)
+	room, err := s.FindById(ctx, dto.RoomID)
 	if err != nil {
-		util.TEL.Error("room not found", err, "id", dto.RoomID)
+		util.TEL.Error(ctx, "room not found", err, "id", dto.RoomID)
+		quoteFailuresTotal.WithLabelValues(quoteFailureRoomNotFound).Inc()
 		return nil, err
 	}
+	span.End()
 
-	util.TEL.Push(context, "query")
-	defer util.TEL.Pop()
+	ctx, span = util.TEL.Start(reqCtx, "query")
+	defer span.End()
 
-	util.TEL.Debug("find room availability", "id", room.ID)
-	isAvailable := s.IsRoomAvailable(util.TEL.Ctx(), dto.DateFrom, dto.DateTo, room.ID)
+	util.TEL.Debug(ctx, "find room availability", "id", room.ID)
+	isAvailable := s.IsRoomAvailable(ctx, dto.DateFrom, dto.DateTo, room.ID)
 
 	if !isAvailable {
-		util.TEL.Error("room cannot be booked at this date range - returning early", nil)
+		util.TEL.Error(ctx, "room cannot be booked at this date range - returning early", nil)
+		quoteFailuresTotal.WithLabelValues(quoteFailureUnavailable).Inc()
 
 		return &RoomReservationQueryResponseDTO{
 			Available: isAvailable,
@@ -747,10 +1687,11 @@ func (s *service) QueryForReservation(context context.Context, callerID uint, dt
 		}, nil
 	}
 
-	util.TEL.Debug("calculate price for this potential reservation")
-	fullPrice, _, err := s.CalculatePrice(util.TEL.Ctx(), dto.DateFrom, dto.DateTo, dto.GuestCount, room.ID)
+	util.TEL.Debug(ctx, "calculate price for this potential reservation")
+	fullPrice, _, err := s.CalculatePrice(ctx, dto.DateFrom, dto.DateTo, dto.GuestCount, room.ID)
 
 	if err != nil {
+		quoteFailuresTotal.WithLabelValues(quoteFailurePrice).Inc()
 		return nil, err
 	}
 
@@ -760,61 +1701,581 @@ func (s *service) QueryForReservation(context context.Context, callerID uint, dt
 	}, nil
 }
 
-func (s *service) DeleteRoomsByHostId(context context.Context, hostId uint) ([]Room, error) {
-	util.TEL.Info("delete rooms owned by host", "host_id", hostId)
+func (s *service) DeleteRoomsByHostId(ctx context.Context, hostId uint) ([]Room, error) {
+	util.TEL.Info(ctx, "delete rooms owned by host", "host_id", hostId)
 
-	util.TEL.Push(context, "validate-user")
-	defer util.TEL.Pop()
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-user")
+	defer span.End()
 
 	// Check if user exists.
 
-	util.TEL.Debug("check if user exists", "id", hostId)
-	host, err := s.userClient.FindById(util.TEL.Ctx(), hostId)
+	util.TEL.Debug(ctx, "check if user exists", "id", hostId)
+	host, err := s.userClient.FindById(ctx, hostId)
 	if err != nil {
-		util.TEL.Error("user does not exist", err, "id", hostId)
-		return nil, ErrNotFound("host", hostId)
+		util.TEL.Error(ctx, "user does not exist", err, "id", hostId)
+		return nil, userLookupError(err, "host", hostId)
 	}
 
 	// Check if user is host.
 
-	util.TEL.Debug("check if user is a host", "id", hostId)
+	util.TEL.Debug(ctx, "check if user is a host", "id", hostId)
 	if host.Role != string(util.Host) {
-		util.TEL.Error("user has a bad role", nil, "role", host.Role)
+		util.TEL.Error(ctx, "user has a bad role", nil, "role", host.Role)
 		return nil, ErrUnauthorized
 	}
+	span.End()
 
 	// Fetch rooms.
 
-	util.TEL.Push(context, "find-rooms-in-db")
-	defer util.TEL.Pop()
+	ctx, span = util.TEL.Start(reqCtx, "find-rooms-in-db")
+	defer span.End()
 
 	rooms, err := s.repo.FindByHost(hostId)
 	if err != nil {
-		util.TEL.Error("could not find rooms by host due db errors", err)
+		util.TEL.Error(ctx, "could not find rooms by host due db errors", err)
 		return nil, err
 	}
 	if len(rooms) == 0 {
 		return rooms, nil
 	}
+	span.End()
 
 	// Delete rooms.
 
-	util.TEL.Push(context, "delete-rooms-in-db")
-	defer util.TEL.Pop()
+	ctx, span = util.TEL.Start(reqCtx, "delete-rooms-in-db")
+	defer span.End()
 
 	err = s.repo.DeleteRoomsByHostId(hostId)
 	if err != nil {
-		util.TEL.Error("could not delete rooms by host", err)
+		util.TEL.Error(ctx, "could not delete rooms by host", err)
 		return nil, err
 	}
 
+	for _, room := range rooms {
+		s.searchCache.InvalidateRooms(room.ID)
+	}
+
 	// Refetch rooms after deletion.
 
 	rooms, err = s.repo.FindByHost(hostId)
 	if err != nil {
-		util.TEL.Error("could not re-fetch rooms after deletion", err)
+		util.TEL.Error(ctx, "could not re-fetch rooms after deletion", err)
+		return nil, err
+	}
+
+	return rooms, nil
+}
+
+// Limits of the commodity catalog entries, in characters.
+const (
+	maxCommodityKeyLength   = 50
+	maxCommodityIconLength  = 100
+	maxCommodityLabelLength = 100
+)
+
+// defaultCommodityLanguage is the language every commodity has a label in,
+// shown to users whose language has none.
+const defaultCommodityLanguage = "en"
+
+var (
+	// commodityKeyPattern matches commodity keys and categories: lowercase
+	// words joined by hyphens, e.g. "air-conditioning".
+	commodityKeyPattern = regexp.MustCompile(`^[a-z0-9]+(-[a-z0-9]+)*$`)
+	// languageTagPattern matches language tags such as "en" or "sr-Latn".
+	languageTagPattern = regexp.MustCompile(`^[a-z]{2,3}(-[A-Za-z0-9]{2,8})*$`)
+)
+
+func (s *service) FindCommodities(ctx context.Context) ([]Commodity, error) {
+	util.TEL.Info(ctx, "find commodities")
+
+	ctx, span := util.TEL.Start(ctx, "find-commodities-in-db")
+	defer span.End()
+
+	commodities, err := s.commodityRepo.FindAll()
+	if err != nil {
+		util.TEL.Error(ctx, "could not find commodities", err)
+		return nil, err
+	}
+	return commodities, nil
+}
+
+func (s *service) CreateCommodity(ctx context.Context, callerID uint, dto CommodityDTO) (*Commodity, error) {
+	util.TEL.Info(ctx, "admin creates commodity", "caller_id", callerID, "key", dto.Key)
+
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-user")
+	defer span.End()
+
+	if err := s.checkAdmin(ctx, callerID); err != nil {
+		return nil, err
+	}
+	span.End()
+
+	// The commodity must be valid and new.
+
+	ctx, span = util.TEL.Start(reqCtx, "validate-commodity")
+	defer span.End()
+
+	commodity := &Commodity{
+		Key:      strings.TrimSpace(dto.Key),
+		Category: strings.TrimSpace(dto.Category),
+		Icon:     strings.TrimSpace(dto.Icon),
+		Labels:   trimLabels(dto.Labels),
+	}
+	if err := validateCommodity(commodity); err != nil {
+		return nil, err
+	}
+
+	if _, err := s.commodityRepo.FindByKey(commodity.Key); err == nil {
+		util.TEL.Error(ctx, "commodity exists", nil, "key", commodity.Key)
+		return nil, ErrConflict(fmt.Sprintf("Commodity %s already exists", commodity.Key))
+	}
+	span.End()
+
+	// Save it.
+
+	ctx, span = util.TEL.Start(reqCtx, "create-commodity-in-db")
+	defer span.End()
+
+	if err := s.commodityRepo.Create(commodity); err != nil {
+		util.TEL.Error(ctx, "could not create commodity", err, "key", commodity.Key)
+		return nil, err
+	}
+
+	return commodity, nil
+}
+
+func (s *service) UpdateCommodity(ctx context.Context, callerID uint, key string, dto UpdateCommodityDTO) (*Commodity, error) {
+	util.TEL.Info(ctx, "admin updates commodity", "caller_id", callerID, "key", key)
+
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-user")
+	defer span.End()
+
+	if err := s.checkAdmin(ctx, callerID); err != nil {
+		return nil, err
+	}
+	span.End()
+
+	// The commodity must exist and stay valid.
+
+	ctx, span = util.TEL.Start(reqCtx, "validate-commodity")
+	defer span.End()
+
+	commodity, err := s.commodityRepo.FindByKey(key)
+	if err != nil {
+		util.TEL.Error(ctx, "commodity not found", err, "key", key)
+		return nil, ErrNotFoundByKey("commodity", key)
+	}
+
+	commodity.Category = strings.TrimSpace(dto.Category)
+	commodity.Icon = strings.TrimSpace(dto.Icon)
+	commodity.Labels = trimLabels(dto.Labels)
+	if err := validateCommodity(commodity); err != nil {
+		return nil, err
+	}
+	span.End()
+
+	// Save it.
+
+	ctx, span = util.TEL.Start(reqCtx, "update-commodity-in-db")
+	defer span.End()
+
+	if err := s.commodityRepo.Update(commodity); err != nil {
+		util.TEL.Error(ctx, "could not update commodity", err, "key", key)
+		return nil, err
+	}
+
+	return commodity, nil
+}
+
+func (s *service) DeleteCommodity(ctx context.Context, callerID uint, key string) error {
+	util.TEL.Info(ctx, "admin deletes commodity", "caller_id", callerID, "key", key)
+
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-user")
+	defer span.End()
+
+	if err := s.checkAdmin(ctx, callerID); err != nil {
+		return err
+	}
+	span.End()
+
+	// The commodity must exist and no room may have it.
+
+	ctx, span = util.TEL.Start(reqCtx, "validate-commodity")
+	defer span.End()
+
+	if _, err := s.commodityRepo.FindByKey(key); err != nil {
+		util.TEL.Error(ctx, "commodity not found", err, "key", key)
+		return ErrNotFoundByKey("commodity", key)
+	}
+
+	rooms, err := s.commodityRepo.CountRooms(key)
+	if err != nil {
+		util.TEL.Error(ctx, "could not count rooms with commodity", err, "key", key)
+		return err
+	}
+	if rooms > 0 {
+		util.TEL.Error(ctx, "commodity is in use", nil, "key", key, "rooms", rooms)
+		return ErrConflict(fmt.Sprintf("Commodity %s is used by %d rooms", key, rooms))
+	}
+	span.End()
+
+	// Delete it.
+
+	ctx, span = util.TEL.Start(reqCtx, "delete-commodity-in-db")
+	defer span.End()
+
+	if err := s.commodityRepo.Delete(key); err != nil {
+		util.TEL.Error(ctx, "could not delete commodity", err, "key", key)
+		return err
+	}
+
+	return nil
+}
+
+// checkAdmin makes sure that the caller exists and is an admin.
+func (s *service) checkAdmin(ctx context.Context, callerID uint) error {
+	util.TEL.Debug(ctx, "check if user exists", "id", callerID)
+	caller, err := s.userClient.FindById(ctx, callerID)
+	if err != nil {
+		util.TEL.Error(ctx, "user does not exist", err, "id", callerID)
+		return userLookupError(err, "user", callerID)
+	}
+
+	util.TEL.Debug(ctx, "check if user is an admin", "id", callerID)
+	if caller.Role != string(util.Admin) {
+		util.TEL.Error(ctx, "user has a bad role", nil, "role", caller.Role)
+		return ErrUnauthorized
+	}
+	return nil
+}
+
+// validateRoomCommodities checks that the commodities of a room are keys of
+// the catalog, each listed once.
+func (s *service) validateRoomCommodities(ctx context.Context, keys []string) error {
+	if len(keys) == 0 {
+		return nil
+	}
+
+	seen := make(map[string]bool, len(keys))
+	for _, key := range keys {
+		if seen[key] {
+			return ErrBadRequestCustom(fmt.Sprintf("Commodity %s is listed more than once", key))
+		}
+		seen[key] = true
+	}
+
+	found, err := s.commodityRepo.FindByKeys(keys)
+	if err != nil {
+		util.TEL.Error(ctx, "could not find commodities", err, "keys", keys)
+		return err
+	}
+
+	known := make(map[string]bool, len(found))
+	for _, commodity := range found {
+		known[commodity.Key] = true
+	}
+	for _, key := range keys {
+		if !known[key] {
+			util.TEL.Error(ctx, "unknown commodity", nil, "key", key)
+			return ErrBadRequestCustom(fmt.Sprintf("Unknown commodity %s", key))
+		}
+	}
+	return nil
+}
+
+func validateCommodity(commodity *Commodity) error {
+	if len(commodity.Key) > maxCommodityKeyLength || !commodityKeyPattern.MatchString(commodity.Key) {
+		return ErrBadRequestCustom(fmt.Sprintf("Commodity key must be lowercase words joined by hyphens, at most %d characters", maxCommodityKeyLength))
+	}
+	if len(commodity.Category) > maxCommodityKeyLength || !commodityKeyPattern.MatchString(commodity.Category) {
+		return ErrBadRequestCustom(fmt.Sprintf("Commodity category must be lowercase words joined by hyphens, at most %d characters", maxCommodityKeyLength))
+	}
+	if utf8.RuneCountInString(commodity.Icon) > maxCommodityIconLength {
+		return ErrBadRequestCustom(fmt.Sprintf("Commodity icon is longer than %d characters", maxCommodityIconLength))
+	}
+
+	if commodity.Labels[defaultCommodityLanguage] == "" {
+		return ErrBadRequestCustom(fmt.Sprintf("Commodity must have a label in %s", defaultCommodityLanguage))
+	}
+	for language, label := range commodity.Labels {
+		if !languageTagPattern.MatchString(language) {
+			return ErrBadRequestCustom(fmt.Sprintf("Invalid language tag %s", language))
+		}
+		if label == "" || utf8.RuneCountInString(label) > maxCommodityLabelLength {
+			return ErrBadRequestCustom(fmt.Sprintf("Label in %s must be 1 to %d characters long", language, maxCommodityLabelLength))
+		}
+	}
+	return nil
+}
+
+func trimLabels(labels map[string]string) map[string]string {
+	trimmed := make(map[string]string, len(labels))
+	for language, label := range labels {
+		trimmed[strings.TrimSpace(language)] = strings.TrimSpace(label)
+	}
+	return trimmed
+}
+
+// maxStatusCommentLength is the longest comment of an admin on a change of
+// status of a room, in characters.
+const maxStatusCommentLength = 500
+
+func (s *service) FindAllRooms(ctx context.Context, callerID uint, hostId *uint) ([]Room, error) {
+	util.TEL.Info(ctx, "admin lists rooms", "caller_id", callerID, "host_id", hostId)
+
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-user")
+	defer span.End()
+
+	if err := s.checkAdmin(ctx, callerID); err != nil {
+		return nil, err
+	}
+	span.End()
+
+	// Fetch rooms.
+
+	ctx, span = util.TEL.Start(reqCtx, "find-rooms-in-db")
+	defer span.End()
+
+	var rooms []Room
+	var err error
+	details := ""
+	if hostId != nil {
+		rooms, err = s.repo.FindByHost(*hostId)
+		details = fmt.Sprintf("host_id=%d", *hostId)
+	} else {
+		rooms, err = s.repo.FindAll()
+	}
+	if err != nil {
+		util.TEL.Error(ctx, "could not find rooms", err)
+		return nil, err
+	}
+	span.End()
+
+	// Record the action.
+
+	ctx, span = util.TEL.Start(reqCtx, "record-audit-entry")
+	defer span.End()
+
+	if err := s.auditRepo.Create(&AuditEntry{ActorID: callerID, Action: AuditListRooms, Details: details}); err != nil {
+		util.TEL.Error(ctx, "could not record audit entry", err, "action", AuditListRooms)
 		return nil, err
 	}
 
 	return rooms, nil
 }
+
+func (s *service) SubmitRoom(ctx context.Context, callerID uint, roomId uint) (*Room, error) {
+	util.TEL.Info(ctx, "host submits room for review", "caller_id", callerID, "room_id", roomId)
+	return s.changeStatusByHost(ctx, callerID, roomId, RoomPendingReview, AuditSubmitRoom)
+}
+
+func (s *service) ArchiveRoom(ctx context.Context, callerID uint, roomId uint) (*Room, error) {
+	util.TEL.Info(ctx, "host archives room", "caller_id", callerID, "room_id", roomId)
+	return s.changeStatusByHost(ctx, callerID, roomId, RoomArchived, AuditArchiveRoom)
+}
+
+func (s *service) ApproveRoom(ctx context.Context, callerID uint, roomId uint, comment string) (*Room, error) {
+	util.TEL.Info(ctx, "admin approves room", "caller_id", callerID, "room_id", roomId)
+	return s.changeStatusByAdmin(ctx, callerID, roomId, RoomPendingReview, RoomPublished, AuditApproveRoom, comment, false)
+}
+
+func (s *service) RejectRoom(ctx context.Context, callerID uint, roomId uint, comment string) (*Room, error) {
+	util.TEL.Info(ctx, "admin rejects room", "caller_id", callerID, "room_id", roomId)
+	return s.changeStatusByAdmin(ctx, callerID, roomId, RoomPendingReview, RoomDraft, AuditRejectRoom, comment, true)
+}
+
+func (s *service) SuspendRoom(ctx context.Context, callerID uint, roomId uint, reason string) (*Room, error) {
+	util.TEL.Info(ctx, "admin suspends room", "caller_id", callerID, "room_id", roomId)
+	return s.changeStatusByAdmin(ctx, callerID, roomId, RoomPublished, RoomSuspended, AuditSuspendRoom, reason, true)
+}
+
+func (s *service) RestoreRoom(ctx context.Context, callerID uint, roomId uint) (*Room, error) {
+	util.TEL.Info(ctx, "admin restores room", "caller_id", callerID, "room_id", roomId)
+	return s.changeStatusByAdmin(ctx, callerID, roomId, RoomSuspended, RoomPublished, AuditRestoreRoom, "", false)
+}
+
+// changeStatusByHost moves a room of the caller, who must be a host, to the
+// next status.
+func (s *service) changeStatusByHost(ctx context.Context, callerID uint, roomId uint, next RoomStatus, action string) (*Room, error) {
+	ctx, span := util.TEL.Start(ctx, "validate-room-and-user")
+	defer span.End()
+
+	util.TEL.Debug(ctx, "check if user exists", "id", callerID)
+	caller, err := s.userClient.FindById(ctx, callerID)
+	if err != nil {
+		util.TEL.Error(ctx, "user does not exist", err, "id", callerID)
+		return nil, userLookupError(err, "user", callerID)
+	}
+
+	util.TEL.Debug(ctx, "check if user is a host", "id", callerID)
+	if caller.Role != string(util.Host) {
+		util.TEL.Error(ctx, "user has a bad role", nil, "role", caller.Role)
+		return nil, ErrUnauthorized
+	}
+
+	room, err := s.repo.FindById(roomId)
+	if err != nil || room.Deleted {
+		util.TEL.Error(ctx, "room not found", err, "id", roomId)
+		return nil, ErrNotFound("room", roomId)
+	}
+
+	if room.HostID != callerID {
+		util.TEL.Error(ctx, "user does not own the room", nil, "caller_id", callerID, "host_id", room.HostID)
+		return nil, ErrUnauthorized
+	}
+
+	if err := s.changeStatus(ctx, room, next, callerID, action, ""); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// changeStatusByAdmin moves a room from one status to the next on behalf of
+// the caller, who must be an admin, with a comment for the host. The status
+// the room must be in tells apart actions leading to the same one, such as
+// approving and restoring.
+func (s *service) changeStatusByAdmin(
+	ctx context.Context,
+	callerID uint,
+	roomId uint,
+	from RoomStatus,
+	next RoomStatus,
+	action string,
+	comment string,
+	commentRequired bool,
+) (*Room, error) {
+	ctx, span := util.TEL.Start(ctx, "validate-user-and-room")
+	defer span.End()
+
+	if err := s.checkAdmin(ctx, callerID); err != nil {
+		return nil, err
+	}
+
+	comment = strings.TrimSpace(comment)
+	if commentRequired && comment == "" {
+		return nil, ErrBadRequestCustom(fmt.Sprintf("A comment of at most %d characters is required", maxStatusCommentLength))
+	}
+	if utf8.RuneCountInString(comment) > maxStatusCommentLength {
+		return nil, ErrBadRequestCustom(fmt.Sprintf("Comment must be at most %d characters long", maxStatusCommentLength))
+	}
+
+	room, err := s.repo.FindById(roomId)
+	if err != nil || room.Deleted {
+		util.TEL.Error(ctx, "room not found", err, "id", roomId)
+		return nil, ErrNotFound("room", roomId)
+	}
+
+	if room.Status != from {
+		util.TEL.Error(ctx, "room has a bad status", nil, "id", roomId, "status", room.Status)
+		return nil, ErrConflict(fmt.Sprintf("Room %d is %s, not %s", roomId, room.Status, from))
+	}
+
+	if err := s.changeStatus(ctx, room, next, callerID, action, comment); err != nil {
+		return nil, err
+	}
+	return room, nil
+}
+
+// changeStatus moves the room to the next status and records the action of
+// the actor along at once. The comment replaces that of the previous change.
+func (s *service) changeStatus(ctx context.Context, room *Room, next RoomStatus, actorID uint, action string, comment string) error {
+	if !room.Status.CanBecome(next) {
+		util.TEL.Error(ctx, "room cannot change status", nil, "id", room.ID, "status", room.Status, "next", next)
+		return ErrConflict(fmt.Sprintf("Room %d is %s and cannot become %s", room.ID, room.Status, next))
+	}
+
+	ctx, span := util.TEL.Start(ctx, "change-room-status-in-db")
+	defer span.End()
+
+	// The room changes only if it is still as it was read: another request
+	// may have changed its status since.
+
+	previous, previousComment := room.Status, room.StatusComment
+	room.Status = next
+	room.StatusComment = comment
+	entry := &AuditEntry{ActorID: actorID, Action: action, RoomID: &room.ID, Details: comment}
+	updated, err := s.repo.UpdateStatusWithAudit(room, previous, entry)
+	if err != nil || !updated {
+		room.Status, room.StatusComment = previous, previousComment
+	}
+	if err != nil {
+		util.TEL.Error(ctx, "could not change room status", err, "id", room.ID, "status", next)
+		return err
+	}
+	if !updated {
+		util.TEL.Error(ctx, "room status changed meanwhile", nil, "id", room.ID, "status", previous)
+		return ErrConflict(fmt.Sprintf("Room %d is not %s anymore", room.ID, previous))
+	}
+
+	// Only published rooms are searched.
+	if previous == RoomPublished {
+		s.searchCache.InvalidateRooms(room.ID)
+	}
+	if next == RoomPublished {
+		s.searchCache.InvalidateAll()
+	}
+
+	return nil
+}
+
+func (s *service) FindRoomHistory(ctx context.Context, callerID uint, roomId uint) (*RoomHistory, error) {
+	util.TEL.Info(ctx, "admin views room history", "caller_id", callerID, "room_id", roomId)
+
+	reqCtx := ctx
+	ctx, span := util.TEL.Start(reqCtx, "validate-user-and-room")
+	defer span.End()
+
+	if err := s.checkAdmin(ctx, callerID); err != nil {
+		return nil, err
+	}
+
+	room, err := s.repo.FindById(roomId)
+	if err != nil {
+		util.TEL.Error(ctx, "room not found", err, "id", roomId)
+		return nil, ErrNotFound("room", roomId)
+	}
+	span.End()
+
+	// Record the action first, so that the history includes it.
+
+	ctx, span = util.TEL.Start(reqCtx, "record-audit-entry")
+	defer span.End()
+
+	if err := s.auditRepo.Create(&AuditEntry{ActorID: callerID, Action: AuditViewRoomHistory, RoomID: &room.ID}); err != nil {
+		util.TEL.Error(ctx, "could not record audit entry", err, "action", AuditViewRoomHistory)
+		return nil, err
+	}
+	span.End()
+
+	// Fetch the history.
+
+	ctx, span = util.TEL.Start(reqCtx, "find-room-history-in-db")
+	defer span.End()
+
+	history := &RoomHistory{Room: room}
+
+	history.PriceLists, err = s.priceRepo.FindListsByRoomId(room.ID)
+	if err != nil {
+		util.TEL.Error(ctx, "could not find price lists", err, "room_id", room.ID)
+		return nil, err
+	}
+
+	history.AvailabilityLists, err = s.availabiltyRepo.FindListsByRoomId(room.ID)
+	if err != nil {
+		util.TEL.Error(ctx, "could not find availability lists", err, "room_id", room.ID)
+		return nil, err
+	}
+
+	history.Audit, err = s.auditRepo.FindByRoom(room.ID)
+	if err != nil {
+		util.TEL.Error(ctx, "could not find audit entries", err, "room_id", room.ID)
+		return nil, err
+	}
+
+	return history, nil
+}
@@ -2,14 +2,17 @@ package main
 
 import (
 	"bookem-room-service/client/userclient"
+	"bookem-room-service/config"
 	internal "bookem-room-service/internal"
+	"bookem-room-service/storage"
 	"bookem-room-service/util"
 	"context"
 	"database/sql"
-	"fmt"
 	"log"
 	"net/http"
 	"os"
+	"os/signal"
+	"syscall"
 	"time"
 
 	_ "github.com/lib/pq"
@@ -28,65 +31,139 @@ var (
 	rawDB  *sql.DB
 )
 
-func syncDatabase() {
-	dB.AutoMigrate(&internal.Room{})
-	dB.AutoMigrate(&internal.RoomAvailabilityList{})
-	dB.AutoMigrate(&internal.RoomAvailabilityItem{})
-	dB.AutoMigrate(&internal.RoomPriceList{})
-	dB.AutoMigrate(&internal.RoomPriceItem{})
-}
-
-func connectToDb() {
-	host := os.Getenv("DB_HOST")
-	port := os.Getenv("DB_PORT")
-	user := os.Getenv("DB_USER")
-	password := os.Getenv("DB_PASSWORD")
-	dbname := os.Getenv("DB_NAME")
-
-	dbURL := fmt.Sprintf(
-		"host=%s port=%s user=%s password=%s dbname=%s sslmode=disable",
-		host, port, user, password, dbname,
-	)
-
-	db, err := gorm.Open(postgres.Open(dbURL), &gorm.Config{})
+// openDb opens the DB and sets dB and rawDB.
+func openDb(cfg config.DBConfig) error {
+	db, err := gorm.Open(postgres.Open(cfg.DSN()), &gorm.Config{})
 	if err != nil {
-		log.Fatalf("Failed to open DB: %v", err)
+		return err
 	}
 
 	dB = db
 	rawDB, _ = db.DB()
+	return nil
+}
+
+func connectToDb(cfg config.DBConfig) {
+	if err := openDb(cfg); err != nil {
+		util.TEL.Error(context.Background(), "failed to open DB", err, "host", cfg.Host, "port", cfg.Port, "name", cfg.Name)
+		os.Exit(1)
+	}
 
-	log.Printf("Connected to DB!")
+	util.TEL.Info(context.Background(), "connected to DB", "host", cfg.Host, "port", cfg.Port, "name", cfg.Name)
+}
+
+func newImageStore(cfg config.ImagesConfig) storage.ImageStore {
+	if cfg.Backend != config.ImageBackendS3 {
+		return storage.NewLocalStore(cfg.Directory)
+	}
+
+	store, err := storage.NewS3Store(storage.S3Config{
+		Endpoint:  cfg.S3.Endpoint,
+		Region:    cfg.S3.Region,
+		Bucket:    cfg.S3.Bucket,
+		AccessKey: cfg.S3.AccessKey,
+		SecretKey: cfg.S3.SecretKey,
+		PathStyle: cfg.S3.PathStyle,
+	}, nil)
+	if err != nil {
+		util.TEL.Error(context.Background(), "failed to set up S3 image store", err)
+		os.Exit(1)
+	}
+	return store
 }
 
 func main() {
+	cfg, err := config.Load()
+	if err != nil {
+		// The logger is configured by cfg, so log.Fatalf instead of the logger here!
+		log.Fatalf("Could not load configuration: %v", err)
+	}
+
 	ctx := context.Background()
-	shutdown := util.TEL.Init(
+
+	if len(os.Args) > 1 && os.Args[1] == "migrate" {
+		// Telemetry is not set up for commands, so log.Fatalf here too.
+		if err := openDb(cfg.DB); err != nil {
+			log.Fatalf("Could not open DB %s:%d/%s: %v", cfg.DB.Host, cfg.DB.Port, cfg.DB.Name, err)
+		}
+		code := runMigrateCommand(ctx, os.Args[2:])
+		rawDB.Close()
+		os.Exit(code)
+	}
+
+	shutdownTelemetry := util.TEL.Init(
 		ctx,
-		os.Getenv("SERVICE_NAME"),
-		os.Getenv("DEPLOYMENT_ENV"),
+		cfg.ServiceName,
+		cfg.DeploymentEnv,
+		cfg.Log,
+		cfg.Tracing,
 	)
-	defer shutdown(ctx)
 
-	connectToDb()
-	defer rawDB.Close()
-	syncDatabase()
+	util.SetJWTPublicKeyPath(cfg.JWT.PublicKeyPath)
+	internal.SetUploadLimits(internal.UploadLimits{
+		MaxFileBytes:    int64(cfg.Images.MaxFileBytes),
+		MaxRequestBytes: int64(cfg.Images.MaxRequestBytes),
+		MaxFiles:        cfg.Images.MaxFiles,
+	})
+	internal.SetPhotoBaseURL(cfg.Images.PublicURL)
+	util.SetImageLimits(util.ImageLimits{
+		MaxBytes:  int64(cfg.Images.MaxFileBytes),
+		MaxWidth:  cfg.Images.MaxWidth,
+		MaxHeight: cfg.Images.MaxHeight,
+	})
+
+	connectToDb(cfg.DB)
+	migrateDatabase(ctx, cfg.DB.AutoMigrate)
+
+	if err := internal.RegisterDBMetrics(dB); err != nil {
+		util.TEL.Error(ctx, "failed to register DB metrics", err)
+	}
 
 	server = gin.Default()
 
 	server.Use(internal.PrometheusMiddleware())
-	server.Use(otelgin.Middleware(os.Getenv("SERVICE_NAME")))
+	server.Use(otelgin.Middleware(cfg.ServiceName))
+	server.Use(util.RequestIDMiddleware())
+	server.Use(util.IdentifyUserMiddleware())
 	server.Use(util.TEL.GetLoggingMiddleware())
 	server.Use(cors.New(cors.Config{
-		AllowOrigins:     []string{"http://localhost:5173", "http://localhost", "http://bookem.local"},
+		AllowOrigins:     cfg.CORS.AllowOrigins,
 		AllowMethods:     []string{"GET", "POST", "PUT", "DELETE", "OPTIONS"},
-		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type"},
-		ExposeHeaders:    []string{"Content-Length"},
+		AllowHeaders:     []string{"Origin", "Authorization", "Content-Type", util.RequestIDHeader},
+		ExposeHeaders:    []string{"Content-Length", "X-Next-Cursor", "X-Prev-Cursor", util.RequestIDHeader},
 		AllowCredentials: true,
 		MaxAge:           12 * time.Hour,
 	}))
 
 	server.GET("/metrics", gin.WrapH(promhttp.Handler()))
+	imageStore := newImageStore(cfg.Images)
+
+	userClient := userclient.NewUserClient(cfg.UserService, nil)
+	if cfg.UserService.CacheTTL > 0 {
+		userCache := userclient.NewCachingUserClient(
+			userClient,
+			time.Duration(cfg.UserService.CacheTTL),
+			time.Duration(cfg.UserService.CacheNegativeTTL),
+			cfg.UserService.CacheEntries,
+		)
+		userClient = userCache
+
+		if cfg.UserService.InvalidationToken != "" {
+			server.DELETE("/internal/user-cache/*id", userCache.InvalidationHandler(cfg.UserService.InvalidationToken))
+		} else {
+			util.TEL.Warn(ctx, "user cache invalidation is disabled, changed users are seen once their entry expires", "ttl", time.Duration(cfg.UserService.CacheTTL))
+		}
+	}
+
+	health := util.NewHealth(time.Duration(cfg.HTTP.HealthCheckTimeout))
+	health.AddCheck("db", rawDB.PingContext)
+	health.AddCheck("images", imageStore.Check)
+	health.AddCheck("jwt_key", util.CheckJWTPublicKey)
+	health.AddCheck("user_service", userClient.Ping)
+
+	server.GET("/livez", health.LivenessHandler())
+	server.GET("/readyz", health.ReadinessHandler())
+	// Deprecated: kept for existing probes, use /readyz.
 	server.GET("/healthz", func(ctx *gin.Context) {
 		err := rawDB.Ping()
 		if err != nil {
@@ -96,18 +173,137 @@ func main() {
 		ctx.JSON(http.StatusOK, nil)
 	})
 
-	userClient := userclient.NewUserClient()
-
 	roomRepo := internal.NewRepository(dB)
 	roomAvailRepo := internal.NewRoomAvailabilityRepo(dB)
 	roomPriceRepo := internal.NewRoomPriceRepo(dB)
+	roomPhotoRepo := internal.NewRoomPhotoRepo(dB)
+	photoBlobRepo := internal.NewPhotoBlobRepo(dB)
+	commodityRepo := internal.NewCommodityRepo(dB)
+	auditRepo := internal.NewAuditRepo(dB)
+
+	searchCache := internal.NewNoopSearchCache()
+	if cfg.Search.CacheTTL > 0 {
+		searchCache = internal.NewMemorySearchCache(time.Duration(cfg.Search.CacheTTL), cfg.Search.CacheEntries)
+	}
 
-	service := internal.NewService(roomRepo, roomAvailRepo, roomPriceRepo, userClient)
+	service := internal.NewService(internal.ServiceDeps{
+		Repo:              roomRepo,
+		AvailabilityRepo:  roomAvailRepo,
+		PriceRepo:         roomPriceRepo,
+		PhotoRepo:         roomPhotoRepo,
+		BlobRepo:          photoBlobRepo,
+		CommodityRepo:     commodityRepo,
+		AuditRepo:         auditRepo,
+		UserClient:        userClient,
+		ImageStore:        imageStore,
+		SearchCache:       searchCache,
+		SearchConcurrency: cfg.Search.Concurrency,
+	})
 	handler := internal.NewHandler(service)
 	route := *internal.NewRoute(handler)
 
 	rg := server.Group("/api")
 	route.Route(rg)
 
-	server.Run()
+	httpServer := &http.Server{
+		Addr:              cfg.HTTP.Addr,
+		Handler:           server,
+		ReadHeaderTimeout: time.Duration(cfg.HTTP.ReadHeaderTimeout),
+		ReadTimeout:       time.Duration(cfg.HTTP.ReadTimeout),
+		WriteTimeout:      time.Duration(cfg.HTTP.WriteTimeout),
+		IdleTimeout:       time.Duration(cfg.HTTP.IdleTimeout),
+	}
+
+	signalCtx, stop := signal.NotifyContext(ctx, syscall.SIGINT, syscall.SIGTERM)
+	defer stop()
+
+	// reconcilerDone is closed once the reconciler is not using the DB pool
+	// anymore.
+	reconcilerDone := make(chan struct{})
+	if cfg.Images.ReconcileInterval > 0 {
+		reconciler := internal.NewPhotoReconciler(
+			roomRepo,
+			roomPhotoRepo,
+			photoBlobRepo,
+			imageStore,
+			time.Duration(cfg.Images.OrphanGracePeriod),
+			time.Duration(cfg.Images.StagedPhotoTTL),
+		)
+		go func() {
+			defer close(reconcilerDone)
+			reconciler.Run(signalCtx, time.Duration(cfg.Images.ReconcileInterval))
+		}()
+	} else {
+		close(reconcilerDone)
+	}
+
+	serverErr := make(chan error, 1)
+	go func() {
+		util.TEL.Info(ctx, "listening", "addr", httpServer.Addr)
+		serverErr <- httpServer.ListenAndServe()
+	}()
+
+	failed := false
+	select {
+	case <-signalCtx.Done():
+		util.TEL.Info(ctx, "shutdown signal received")
+	case err := <-serverErr:
+		util.TEL.Error(ctx, "server stopped unexpectedly", err)
+		failed = true
+	}
+	stop() // A second signal kills the process.
+
+	shutdownGracefully(httpServer, health, reconcilerDone, shutdownTelemetry, time.Duration(cfg.HTTP.ShutdownDelay), time.Duration(cfg.HTTP.ShutdownTimeout))
+	if failed {
+		os.Exit(1)
+	}
+}
+
+// shutdownGracefully stops the service in order: readiness turns false for
+// delay while the server still serves, then the server stops accepting
+// connections and drains in-flight requests, then the background work, whose
+// context is already cancelled, is waited for until background is closed,
+// then buffered spans are flushed, then the DB pool is closed. All of it must
+// finish within timeout.
+func shutdownGracefully(httpServer *http.Server, health *util.Health, background <-chan struct{}, shutdownTelemetry func(context.Context) error, delay time.Duration, timeout time.Duration) {
+	ctx, cancel := context.WithTimeout(context.Background(), timeout)
+	defer cancel()
+
+	// [1] Turn unready, so that probes see it before the listener closes
+
+	health.ShuttingDown()
+	util.TEL.Info(ctx, "not ready anymore, waiting before draining", "delay", delay)
+	select {
+	case <-time.After(delay):
+	case <-ctx.Done():
+	}
+
+	// [2] Stop accepting and drain, within what is left of timeout
+
+	util.TEL.Info(ctx, "draining in-flight requests", "timeout", timeout-delay)
+	if err := httpServer.Shutdown(ctx); err != nil {
+		util.TEL.Error(ctx, "could not drain in-flight requests", err)
+	}
+
+	// [3] Wait for background work
+
+	select {
+	case <-background:
+	case <-ctx.Done():
+		util.TEL.Error(ctx, "background work did not stop in time", ctx.Err())
+	}
+
+	// [4] Flush telemetry
+
+	if err := shutdownTelemetry(ctx); err != nil {
+		util.TEL.Error(ctx, "could not flush telemetry", err)
+	}
+
+	// [5] Close DB pool
+
+	if err := rawDB.Close(); err != nil {
+		util.TEL.Error(ctx, "could not close DB pool", err)
+	}
+
+	util.TEL.Info(ctx, "shutdown complete")
 }
@@ -0,0 +1,129 @@
+package main
+
+import (
+	"bookem-room-service/migrations"
+	"bookem-room-service/util"
+	"context"
+	"errors"
+	"fmt"
+	"os"
+	"strconv"
+	"text/tabwriter"
+	"time"
+)
+
+const migrateUsage = "usage: room-service migrate up | down [N] | status"
+
+// migrateDatabase brings the schema up to date on startup, or, with
+// auto-migrate disabled, only checks it. The service refuses to start when
+// the schema is ahead of this build.
+func migrateDatabase(ctx context.Context, autoMigrate bool) {
+	migrator, err := migrations.NewMigrator(rawDB)
+	if err != nil {
+		util.TEL.Error(ctx, "could not load migrations", err)
+		os.Exit(1)
+	}
+
+	if !autoMigrate {
+		pending, err := migrator.Check(ctx)
+		if err != nil {
+			util.TEL.Error(ctx, "schema check failed", err)
+			os.Exit(1)
+		}
+		if pending > 0 {
+			util.TEL.Warn(ctx, "schema has pending migrations, run the migrate subcommand", "pending", pending)
+		}
+		return
+	}
+
+	applied, err := migrator.Up(ctx)
+	if err != nil {
+		util.TEL.Error(ctx, "migration failed", err)
+		os.Exit(1)
+	}
+	for _, migration := range applied {
+		util.TEL.Info(ctx, "applied migration", "version", migration.Version, "name", migration.Name)
+	}
+	util.TEL.Info(ctx, "schema is up to date", "version", migrator.Latest())
+}
+
+// runMigrateCommand runs `migrate up|down [N]|status` and returns the exit code.
+func runMigrateCommand(ctx context.Context, args []string) int {
+	migrator, err := migrations.NewMigrator(rawDB)
+	if err != nil {
+		fmt.Fprintln(os.Stderr, "could not load migrations:", err)
+		return 1
+	}
+
+	if len(args) == 0 {
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	switch args[0] {
+	case "up":
+		applied, err := migrator.Up(ctx)
+		for _, migration := range applied {
+			fmt.Printf("applied %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(applied) == 0 {
+			fmt.Println("nothing to apply")
+		}
+
+	case "down":
+		steps := 1
+		if len(args) > 1 {
+			steps, err = strconv.Atoi(args[1])
+			if err != nil || steps < 1 {
+				fmt.Fprintln(os.Stderr, migrateUsage)
+				return 2
+			}
+		}
+		reverted, err := migrator.Down(ctx, steps)
+		for _, migration := range reverted {
+			fmt.Printf("reverted %04d_%s\n", migration.Version, migration.Name)
+		}
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		if len(reverted) == 0 {
+			fmt.Println("nothing to revert")
+		}
+
+	case "status":
+		statuses, err := migrator.Status(ctx)
+		if err != nil {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+		w := tabwriter.NewWriter(os.Stdout, 0, 0, 2, ' ', 0)
+		fmt.Fprintln(w, "VERSION\tNAME\tSTATUS\tAPPLIED AT")
+		for _, status := range statuses {
+			state, appliedAt := "pending", ""
+			if status.Applied {
+				state, appliedAt = "applied", status.AppliedAt.Format(time.RFC3339)
+			}
+			if status.Unknown {
+				state = "unknown to this build"
+			}
+			fmt.Fprintf(w, "%04d\t%s\t%s\t%s\n", status.Version, status.Name, state, appliedAt)
+		}
+		w.Flush()
+
+		if _, err := migrator.Check(ctx); errors.Is(err, migrations.ErrSchemaAhead) {
+			fmt.Fprintln(os.Stderr, err)
+			return 1
+		}
+
+	default:
+		fmt.Fprintln(os.Stderr, migrateUsage)
+		return 2
+	}
+
+	return 0
+}
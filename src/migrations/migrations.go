@@ -0,0 +1,350 @@
+// Package migrations versions the database schema.
+//
+// Migrations are SQL files embedded from sql/, named
+// <version>_<name>.up.sql and <version>_<name>.down.sql. Applied versions are
+// recorded in the schema_migrations table. Each migration runs in its own
+// transaction, and a Postgres advisory lock keeps concurrently starting
+// instances from migrating at the same time.
+package migrations
+
+import (
+	"context"
+	"database/sql"
+	"embed"
+	"errors"
+	"fmt"
+	"io/fs"
+	"path"
+	"regexp"
+	"sort"
+	"strconv"
+	"time"
+)
+
+//go:embed sql/*.sql
+var files embed.FS
+
+// ErrSchemaAhead is returned when the database has migrations applied that
+// this build does not know about, i.e. it was migrated by a newer version.
+var ErrSchemaAhead = errors.New("database schema is newer than this build")
+
+// lockID is the key of the advisory lock held while migrating.
+const lockID = 7_240_531
+
+type Migration struct {
+	Version int
+	Name    string
+	Up      string
+	Down    string
+}
+
+type Status struct {
+	Version   int
+	Name      string
+	Applied   bool
+	AppliedAt time.Time
+	// Unknown is set for applied versions that this build has no files for.
+	Unknown bool
+}
+
+var fileNamePattern = regexp.MustCompile(`^(\d+)_([a-z0-9_]+)\.(up|down)\.sql$`)
+
+// All returns the embedded migrations sorted by version.
+func All() ([]Migration, error) {
+	sub, err := fs.Sub(files, "sql")
+	if err != nil {
+		return nil, err
+	}
+	return Parse(sub)
+}
+
+// Parse reads the migrations in the root of fsys, sorted by version. Every
+// version must have exactly one up and one down file.
+func Parse(fsys fs.FS) ([]Migration, error) {
+	entries, err := fs.ReadDir(fsys, ".")
+	if err != nil {
+		return nil, err
+	}
+
+	byVersion := make(map[int]*Migration)
+	for _, entry := range entries {
+		match := fileNamePattern.FindStringSubmatch(entry.Name())
+		if match == nil {
+			return nil, fmt.Errorf("migration file %s is not named <version>_<name>.(up|down).sql", entry.Name())
+		}
+
+		version, _ := strconv.Atoi(match[1])
+		name, direction := match[2], match[3]
+
+		content, err := fs.ReadFile(fsys, path.Clean(entry.Name()))
+		if err != nil {
+			return nil, err
+		}
+
+		migration, ok := byVersion[version]
+		if !ok {
+			migration = &Migration{Version: version, Name: name}
+			byVersion[version] = migration
+		}
+		if migration.Name != name {
+			return nil, fmt.Errorf("migration %d has files with different names (%s, %s)", version, migration.Name, name)
+		}
+
+		target := &migration.Up
+		if direction == "down" {
+			target = &migration.Down
+		}
+		if *target != "" {
+			return nil, fmt.Errorf("migration %d has more than one %s file", version, direction)
+		}
+		*target = string(content)
+	}
+
+	result := make([]Migration, 0, len(byVersion))
+	for _, migration := range byVersion {
+		if migration.Up == "" || migration.Down == "" {
+			return nil, fmt.Errorf("migration %04d_%s needs both an up and a down file", migration.Version, migration.Name)
+		}
+		result = append(result, *migration)
+	}
+	sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+
+	return result, nil
+}
+
+// ---------------------------------------------------------------
+
+type Migrator struct {
+	db         *sql.DB
+	migrations []Migration
+}
+
+// NewMigrator returns a migrator applying the embedded migrations to db.
+func NewMigrator(db *sql.DB) (*Migrator, error) {
+	migrations, err := All()
+	if err != nil {
+		return nil, err
+	}
+	return &Migrator{db, migrations}, nil
+}
+
+// Latest returns the version of the newest migration known to this build.
+func (m *Migrator) Latest() int {
+	if len(m.migrations) == 0 {
+		return 0
+	}
+	return m.migrations[len(m.migrations)-1].Version
+}
+
+// Up applies every pending migration in order and returns the applied ones.
+// It refuses to run if the schema is ahead of this build.
+func (m *Migrator) Up(ctx context.Context) ([]Migration, error) {
+	var applied []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		if err := m.checkNotAhead(versions); err != nil {
+			return err
+		}
+
+		for _, migration := range m.migrations {
+			if _, ok := versions[migration.Version]; ok {
+				continue
+			}
+
+			err := inTransaction(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Up); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx,
+					"INSERT INTO schema_migrations (version, name) VALUES ($1, $2)",
+					migration.Version, migration.Name)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			applied = append(applied, migration)
+		}
+		return nil
+	})
+
+	return applied, err
+}
+
+// Down reverts the last steps applied migrations, newest first, and returns
+// the reverted ones.
+func (m *Migrator) Down(ctx context.Context, steps int) ([]Migration, error) {
+	var reverted []Migration
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+		// Versions applied by a newer build cannot be reverted without their
+		// down files, and the older ones below may be depended on.
+		if err := m.checkNotAhead(versions); err != nil {
+			return err
+		}
+
+		for i := len(m.migrations) - 1; i >= 0 && len(reverted) < steps; i-- {
+			migration := m.migrations[i]
+			if _, ok := versions[migration.Version]; !ok {
+				continue
+			}
+
+			err := inTransaction(ctx, conn, func(tx *sql.Tx) error {
+				if _, err := tx.ExecContext(ctx, migration.Down); err != nil {
+					return err
+				}
+				_, err := tx.ExecContext(ctx, "DELETE FROM schema_migrations WHERE version = $1", migration.Version)
+				return err
+			})
+			if err != nil {
+				return fmt.Errorf("reverting migration %04d_%s failed: %w", migration.Version, migration.Name, err)
+			}
+			reverted = append(reverted, migration)
+		}
+		return nil
+	})
+
+	return reverted, err
+}
+
+// Status lists every known migration and every applied version.
+func (m *Migrator) Status(ctx context.Context) ([]Status, error) {
+	var result []Status
+
+	err := m.withLock(ctx, func(conn *sql.Conn) error {
+		versions, err := appliedVersions(ctx, conn)
+		if err != nil {
+			return err
+		}
+
+		known := make(map[int]bool)
+		for _, migration := range m.migrations {
+			known[migration.Version] = true
+			appliedAt, ok := versions[migration.Version]
+			result = append(result, Status{
+				Version:   migration.Version,
+				Name:      migration.Name,
+				Applied:   ok,
+				AppliedAt: appliedAt.at,
+			})
+		}
+		for version, applied := range versions {
+			if !known[version] {
+				result = append(result, Status{
+					Version:   version,
+					Name:      applied.name,
+					Applied:   true,
+					AppliedAt: applied.at,
+					Unknown:   true,
+				})
+			}
+		}
+		sort.Slice(result, func(i, j int) bool { return result[i].Version < result[j].Version })
+		return nil
+	})
+
+	return result, err
+}
+
+// Check returns the number of pending migrations, or ErrSchemaAhead if the
+// schema is ahead of this build. It does not change the schema.
+func (m *Migrator) Check(ctx context.Context) (int, error) {
+	statuses, err := m.Status(ctx)
+	if err != nil {
+		return 0, err
+	}
+
+	pending := 0
+	for _, status := range statuses {
+		if status.Unknown && status.Version > m.Latest() {
+			return 0, m.aheadError(status.Version)
+		}
+		if !status.Applied {
+			pending++
+		}
+	}
+	return pending, nil
+}
+
+func (m *Migrator) checkNotAhead(versions map[int]appliedVersion) error {
+	for version := range versions {
+		if version > m.Latest() {
+			return m.aheadError(version)
+		}
+	}
+	return nil
+}
+
+func (m *Migrator) aheadError(version int) error {
+	return fmt.Errorf("%w: version %d is applied, this build knows up to %d", ErrSchemaAhead, version, m.Latest())
+}
+
+// withLock runs fn on a single connection holding the migration lock, after
+// making sure the schema_migrations table exists.
+func (m *Migrator) withLock(ctx context.Context, fn func(conn *sql.Conn) error) error {
+	conn, err := m.db.Conn(ctx)
+	if err != nil {
+		return err
+	}
+	defer conn.Close()
+
+	if _, err := conn.ExecContext(ctx, "SELECT pg_advisory_lock($1)", lockID); err != nil {
+		return fmt.Errorf("could not acquire migration lock: %w", err)
+	}
+	defer conn.ExecContext(context.Background(), "SELECT pg_advisory_unlock($1)", lockID)
+
+	_, err = conn.ExecContext(ctx, `CREATE TABLE IF NOT EXISTS schema_migrations (
+		version    bigint PRIMARY KEY,
+		name       text NOT NULL,
+		applied_at timestamptz NOT NULL DEFAULT now()
+	)`)
+	if err != nil {
+		return fmt.Errorf("could not create schema_migrations: %w", err)
+	}
+
+	return fn(conn)
+}
+
+type appliedVersion struct {
+	name string
+	at   time.Time
+}
+
+func appliedVersions(ctx context.Context, conn *sql.Conn) (map[int]appliedVersion, error) {
+	rows, err := conn.QueryContext(ctx, "SELECT version, name, applied_at FROM schema_migrations")
+	if err != nil {
+		return nil, err
+	}
+	defer rows.Close()
+
+	versions := make(map[int]appliedVersion)
+	for rows.Next() {
+		var version int
+		var applied appliedVersion
+		if err := rows.Scan(&version, &applied.name, &applied.at); err != nil {
+			return nil, err
+		}
+		versions[version] = applied
+	}
+	return versions, rows.Err()
+}
+
+func inTransaction(ctx context.Context, conn *sql.Conn, fn func(tx *sql.Tx) error) error {
+	tx, err := conn.BeginTx(ctx, nil)
+	if err != nil {
+		return err
+	}
+	if err := fn(tx); err != nil {
+		tx.Rollback()
+		return err
+	}
+	return tx.Commit()
+}
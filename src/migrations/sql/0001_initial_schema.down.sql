@@ -0,0 +1,7 @@
+DROP TABLE IF EXISTS room_price_list_items;
+DROP TABLE IF EXISTS room_price_items;
+DROP TABLE IF EXISTS room_price_lists;
+DROP TABLE IF EXISTS room_availability_list_items;
+DROP TABLE IF EXISTS room_availability_items;
+DROP TABLE IF EXISTS room_availability_lists;
+DROP TABLE IF EXISTS rooms;
@@ -0,0 +1,80 @@
+-- Schema previously created by GORM AutoMigrate. IF NOT EXISTS keeps this
+-- migration a no-op on databases that were set up that way.
+
+CREATE TABLE IF NOT EXISTS rooms (
+    id                   bigserial PRIMARY KEY,
+    host_id              bigint       NOT NULL,
+    name                 varchar(50)  NOT NULL,
+    description          text,
+    address              varchar(150) NOT NULL,
+    min_guests           bigint       NOT NULL,
+    max_guests           bigint       NOT NULL,
+    photos               text,
+    commodities          text,
+    availability_list_id bigint,
+    price_list_id        bigint,
+    auto_approve         boolean      NOT NULL DEFAULT false,
+    deleted              boolean      NOT NULL DEFAULT false
+);
+
+CREATE TABLE IF NOT EXISTS room_availability_lists (
+    id             bigserial PRIMARY KEY,
+    room_id        bigint      NOT NULL,
+    effective_from timestamptz NOT NULL,
+    CONSTRAINT fk_room_availability_lists_room FOREIGN KEY (room_id) REFERENCES rooms (id)
+);
+CREATE INDEX IF NOT EXISTS idx_room_availability_lists_room_id ON room_availability_lists (room_id);
+
+CREATE TABLE IF NOT EXISTS room_availability_items (
+    id        bigserial PRIMARY KEY,
+    date_from timestamptz NOT NULL,
+    date_to   timestamptz NOT NULL,
+    available boolean
+);
+
+CREATE TABLE IF NOT EXISTS room_availability_list_items (
+    room_availability_list_id bigint,
+    room_availability_item_id bigint,
+    PRIMARY KEY (room_availability_list_id, room_availability_item_id),
+    CONSTRAINT fk_room_availability_list_items_room_availability_list
+        FOREIGN KEY (room_availability_list_id) REFERENCES room_availability_lists (id),
+    CONSTRAINT fk_room_availability_list_items_room_availability_item
+        FOREIGN KEY (room_availability_item_id) REFERENCES room_availability_items (id)
+);
+
+CREATE TABLE IF NOT EXISTS room_price_lists (
+    id             bigserial PRIMARY KEY,
+    room_id        bigint      NOT NULL,
+    effective_from timestamptz NOT NULL,
+    base_price     bigint      NOT NULL,
+    per_guest      boolean     NOT NULL,
+    CONSTRAINT fk_room_price_lists_room FOREIGN KEY (room_id) REFERENCES rooms (id)
+);
+CREATE INDEX IF NOT EXISTS idx_room_price_lists_room_id ON room_price_lists (room_id);
+
+CREATE TABLE IF NOT EXISTS room_price_items (
+    id        bigserial PRIMARY KEY,
+    date_from timestamptz NOT NULL,
+    date_to   timestamptz NOT NULL,
+    price     bigint      NOT NULL
+);
+
+CREATE TABLE IF NOT EXISTS room_price_list_items (
+    room_price_list_id bigint,
+    room_price_item_id bigint,
+    PRIMARY KEY (room_price_list_id, room_price_item_id),
+    CONSTRAINT fk_room_price_list_items_room_price_list
+        FOREIGN KEY (room_price_list_id) REFERENCES room_price_lists (id),
+    CONSTRAINT fk_room_price_list_items_room_price_item
+        FOREIGN KEY (room_price_item_id) REFERENCES room_price_items (id)
+);
+
+-- Full-text room search. The expression must match roomSearchVector in
+-- internal/repo.go.
+CREATE INDEX IF NOT EXISTS idx_rooms_search ON rooms USING GIN (
+    to_tsvector('english',
+        coalesce(name, '') || ' ' ||
+        coalesce(description, '') || ' ' ||
+        coalesce(commodities, '') || ' ' ||
+        coalesce(address, ''))
+);
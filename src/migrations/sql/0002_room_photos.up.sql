@@ -0,0 +1,16 @@
+-- Photos uploaded with POST /api/photos. A photo is staged (room_id NULL)
+-- until a room references it.
+
+CREATE TABLE room_photos (
+    id           bigserial PRIMARY KEY,
+    key          text        NOT NULL,
+    content_type text        NOT NULL,
+    size         bigint      NOT NULL,
+    uploader_id  bigint      NOT NULL,
+    room_id      bigint,
+    created_at   timestamptz NOT NULL DEFAULT now(),
+    CONSTRAINT uni_room_photos_key UNIQUE (key),
+    CONSTRAINT fk_room_photos_room FOREIGN KEY (room_id) REFERENCES rooms (id) ON DELETE SET NULL
+);
+CREATE INDEX idx_room_photos_room_id ON room_photos (room_id);
+CREATE INDEX idx_room_photos_staged ON room_photos (uploader_id, created_at) WHERE room_id IS NULL;
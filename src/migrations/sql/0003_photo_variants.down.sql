@@ -0,0 +1,10 @@
+-- The variant images stay in storage, orphaned.
+
+ALTER TABLE room_photos DROP COLUMN variants;
+
+UPDATE rooms
+SET photos = (
+    SELECT COALESCE(json_agg(p.photo ->> 'key' ORDER BY p.ord), '[]')::text
+    FROM json_array_elements(photos::json) WITH ORDINALITY AS p (photo, ord)
+)
+WHERE photos IS NOT NULL AND photos <> 'null';
@@ -0,0 +1,12 @@
+-- Room photos were a JSON array of keys and become an array of objects that
+-- also hold the keys of the thumbnail and medium variants. Existing photos
+-- have no variants; clients fall back to the original.
+
+UPDATE rooms
+SET photos = (
+    SELECT COALESCE(json_agg(json_build_object('key', p.key) ORDER BY p.ord), '[]')::text
+    FROM json_array_elements_text(photos::json) WITH ORDINALITY AS p (key, ord)
+)
+WHERE photos IS NOT NULL AND photos <> 'null';
+
+ALTER TABLE room_photos ADD COLUMN variants text;
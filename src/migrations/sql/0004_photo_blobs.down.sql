@@ -0,0 +1,7 @@
+-- Fails if identical photos were uploaded meanwhile; their staged rows must
+-- be removed first.
+
+DROP INDEX IF EXISTS idx_room_photos_key;
+ALTER TABLE room_photos ADD CONSTRAINT uni_room_photos_key UNIQUE (key);
+
+DROP TABLE IF EXISTS photo_blobs;
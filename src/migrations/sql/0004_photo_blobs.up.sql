@@ -0,0 +1,29 @@
+-- Images are stored once per content, under the hash of their bytes, and
+-- shared by every photo with that content. photo_blobs counts the room photos
+-- and staged uploads holding each image; it is deleted with the last one.
+-- Existing photos keep their keys and get one reference per holder.
+
+CREATE TABLE photo_blobs (
+    key        text        PRIMARY KEY,
+    variants   text,
+    ref_count  integer     NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+
+INSERT INTO photo_blobs (key, variants, ref_count)
+SELECT refs.key, min(refs.variants), count(*)
+FROM (
+    SELECT p.photo ->> 'key' AS key, (p.photo -> 'variants')::text AS variants
+    FROM rooms, json_array_elements(rooms.photos::json) AS p (photo)
+    WHERE rooms.photos IS NOT NULL AND rooms.photos <> 'null'
+    UNION ALL
+    SELECT key, variants
+    FROM room_photos
+    WHERE room_id IS NULL
+) AS refs
+GROUP BY refs.key;
+
+-- Identical uploads share a key.
+
+ALTER TABLE room_photos DROP CONSTRAINT uni_room_photos_key;
+CREATE INDEX idx_room_photos_key ON room_photos (key);
@@ -0,0 +1,3 @@
+-- Rooms keep the catalog keys, the free text they replaced is gone.
+
+DROP TABLE IF EXISTS commodities;
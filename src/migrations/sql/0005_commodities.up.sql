@@ -0,0 +1,92 @@
+-- Room commodities were free text ("WiFi", "wifi", "Wi-Fi") and become keys
+-- of a catalog administered by admins. Labels is a JSON object of the names
+-- by language tag.
+
+CREATE TABLE commodities (
+    key        text        PRIMARY KEY,
+    category   text        NOT NULL,
+    icon       text        NOT NULL DEFAULT '',
+    labels     text        NOT NULL,
+    created_at timestamptz NOT NULL DEFAULT now(),
+    updated_at timestamptz NOT NULL DEFAULT now()
+);
+
+INSERT INTO commodities (key, category, icon, labels) VALUES
+    ('wifi',             'connectivity', 'wifi',                  '{"en":"Wi-Fi"}'),
+    ('workspace',        'connectivity', 'desk',                  '{"en":"Dedicated workspace"}'),
+    ('kitchen',          'kitchen',      'kitchen',               '{"en":"Kitchen"}'),
+    ('coffee-maker',     'kitchen',      'coffee_maker',          '{"en":"Coffee maker"}'),
+    ('air-conditioning', 'comfort',      'ac_unit',               '{"en":"Air conditioning"}'),
+    ('heating',          'comfort',      'thermostat',            '{"en":"Heating"}'),
+    ('tv',               'comfort',      'tv',                    '{"en":"TV"}'),
+    ('washer',           'comfort',      'local_laundry_service', '{"en":"Washer"}'),
+    ('dryer',            'comfort',      'dry',                   '{"en":"Dryer"}'),
+    ('parking',          'outdoor',      'local_parking',         '{"en":"Free parking"}'),
+    ('pool',             'outdoor',      'pool',                  '{"en":"Pool"}'),
+    ('balcony',          'outdoor',      'balcony',               '{"en":"Balcony"}'),
+    ('garden',           'outdoor',      'yard',                  '{"en":"Garden"}'),
+    ('elevator',         'accessibility','elevator',              '{"en":"Elevator"}'),
+    ('pets',             'policies',     'pets',                  '{"en":"Pets allowed"}'),
+    ('smoke-alarm',      'safety',       'detector_smoke',        '{"en":"Smoke alarm"}');
+
+-- Free-text values are matched by their letters and digits only, against the
+-- keys and a few common spellings.
+
+CREATE TEMPORARY TABLE commodity_aliases (
+    alias text PRIMARY KEY,
+    key   text NOT NULL
+) ON COMMIT DROP;
+
+INSERT INTO commodity_aliases (alias, key)
+SELECT regexp_replace(key, '[^a-z0-9]+', '', 'g'), key FROM commodities;
+
+INSERT INTO commodity_aliases (alias, key) VALUES
+    ('wireless', 'wifi'), ('wirelessinternet', 'wifi'), ('wlan', 'wifi'), ('internet', 'wifi'),
+    ('desk', 'workspace'), ('dedicatedworkspace', 'workspace'),
+    ('kitchenette', 'kitchen'),
+    ('coffee', 'coffee-maker'), ('coffeemachine', 'coffee-maker'),
+    ('ac', 'air-conditioning'), ('aircon', 'air-conditioning'), ('klima', 'air-conditioning'),
+    ('centralheating', 'heating'),
+    ('television', 'tv'), ('cabletv', 'tv'), ('smarttv', 'tv'),
+    ('washingmachine', 'washer'), ('laundry', 'washer'),
+    ('freeparking', 'parking'), ('garage', 'parking'), ('parkingspace', 'parking'),
+    ('swimmingpool', 'pool'),
+    ('terrace', 'balcony'),
+    ('yard', 'garden'),
+    ('lift', 'elevator'),
+    ('petsallowed', 'pets'), ('petfriendly', 'pets'),
+    ('smokedetector', 'smoke-alarm');
+
+CREATE TEMPORARY TABLE legacy_commodities ON COMMIT DROP AS
+SELECT DISTINCT trim(c.value) AS value, regexp_replace(lower(c.value), '[^a-z0-9]+', '', 'g') AS alias
+FROM rooms, json_array_elements_text(rooms.commodities::json) AS c (value)
+WHERE rooms.commodities IS NOT NULL AND rooms.commodities <> 'null';
+
+-- Values matching nothing become commodities of their own, in the "other"
+-- category and labelled as first written, for admins to sort out. Values
+-- without a letter or digit are dropped.
+
+INSERT INTO commodity_aliases (alias, key)
+SELECT DISTINCT ON (alias) alias, trim(both '-' from regexp_replace(lower(value), '[^a-z0-9]+', '-', 'g'))
+FROM legacy_commodities
+WHERE alias <> '' AND alias NOT IN (SELECT alias FROM commodity_aliases)
+ORDER BY alias, value;
+
+INSERT INTO commodities (key, category, labels)
+SELECT DISTINCT ON (a.key) a.key, 'other', json_build_object('en', l.value)::text
+FROM legacy_commodities l
+JOIN commodity_aliases a ON a.alias = l.alias
+WHERE a.key NOT IN (SELECT key FROM commodities)
+ORDER BY a.key, l.value;
+
+UPDATE rooms
+SET commodities = (
+    SELECT COALESCE(json_agg(m.key ORDER BY m.first), '[]')::text
+    FROM (
+        SELECT a.key, min(c.ord) AS first
+        FROM json_array_elements_text(rooms.commodities::json) WITH ORDINALITY AS c (value, ord)
+        JOIN commodity_aliases a ON a.alias = regexp_replace(lower(c.value), '[^a-z0-9]+', '', 'g')
+        GROUP BY a.key
+    ) AS m
+)
+WHERE commodities IS NOT NULL AND commodities <> 'null';
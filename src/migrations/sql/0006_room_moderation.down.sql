@@ -0,0 +1,7 @@
+-- Suspended rooms become visible again and the audit log is lost.
+
+DROP TABLE IF EXISTS audit_entries;
+
+ALTER TABLE rooms
+    DROP COLUMN suspension_reason,
+    DROP COLUMN suspended;
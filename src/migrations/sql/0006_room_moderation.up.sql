@@ -0,0 +1,17 @@
+-- Admins can suspend rooms, which hides them like deleted ones, and every
+-- action of an admin is recorded in audit_entries.
+
+ALTER TABLE rooms
+    ADD COLUMN suspended         boolean NOT NULL DEFAULT false,
+    ADD COLUMN suspension_reason text    NOT NULL DEFAULT '';
+
+CREATE TABLE audit_entries (
+    id         bigserial   PRIMARY KEY,
+    actor_id   bigint      NOT NULL,
+    action     text        NOT NULL,
+    room_id    bigint,
+    details    text        NOT NULL DEFAULT '',
+    created_at timestamptz NOT NULL DEFAULT now()
+);
+CREATE INDEX idx_audit_entries_actor_id ON audit_entries (actor_id);
+CREATE INDEX idx_audit_entries_room_id ON audit_entries (room_id);
@@ -0,0 +1,12 @@
+-- Only suspended rooms stay hidden; drafts, rooms pending review and
+-- archived ones are listed again.
+
+DROP INDEX IF EXISTS idx_rooms_status;
+
+ALTER TABLE rooms RENAME COLUMN status_comment TO suspension_reason;
+ALTER TABLE rooms ADD COLUMN suspended boolean NOT NULL DEFAULT false;
+
+UPDATE rooms SET suspended = (status = 'suspended');
+UPDATE rooms SET suspension_reason = '' WHERE status <> 'suspended';
+
+ALTER TABLE rooms DROP COLUMN status;
@@ -0,0 +1,14 @@
+-- Rooms get a lifecycle status instead of the suspended flag. Rooms that
+-- exist already went live when created, so they are published unless an
+-- admin suspended them.
+
+ALTER TABLE rooms
+    ADD COLUMN status text NOT NULL DEFAULT 'draft'
+        CHECK (status IN ('draft', 'pending_review', 'published', 'suspended', 'archived'));
+
+UPDATE rooms SET status = CASE WHEN suspended THEN 'suspended' ELSE 'published' END;
+
+ALTER TABLE rooms DROP COLUMN suspended;
+ALTER TABLE rooms RENAME COLUMN suspension_reason TO status_comment;
+
+CREATE INDEX idx_rooms_status ON rooms (status);
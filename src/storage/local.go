@@ -0,0 +1,97 @@
+package storage
+
+import (
+	"context"
+	"fmt"
+	"io/fs"
+	"os"
+	"path/filepath"
+	"strings"
+)
+
+type localStore struct {
+	directory string
+}
+
+// NewLocalStore returns a store keeping images as files in directory.
+func NewLocalStore(directory string) ImageStore {
+	return &localStore{directory}
+}
+
+func (s *localStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	path := filepath.Join(s.directory, filepath.FromSlash(key))
+	if err := os.MkdirAll(filepath.Dir(path), 0755); err != nil {
+		return err
+	}
+
+	// Write to a temporary file first so that a reader never sees a partial
+	// image.
+	file, err := os.CreateTemp(filepath.Dir(path), ".upload-*")
+	if err != nil {
+		return err
+	}
+	defer os.Remove(file.Name())
+
+	if _, err := file.Write(data); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Chmod(0644); err != nil {
+		file.Close()
+		return err
+	}
+	if err := file.Close(); err != nil {
+		return err
+	}
+	return os.Rename(file.Name(), path)
+}
+
+func (s *localStore) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	err := os.Remove(filepath.Join(s.directory, filepath.FromSlash(key)))
+	if err != nil && !os.IsNotExist(err) {
+		return err
+	}
+	return nil
+}
+
+func (s *localStore) List(ctx context.Context) ([]StoredImage, error) {
+	var images []StoredImage
+	err := filepath.WalkDir(s.directory, func(path string, entry fs.DirEntry, err error) error {
+		if err != nil {
+			return err
+		}
+		// Skip the temporary files of Put and Check.
+		if entry.IsDir() || strings.HasPrefix(entry.Name(), ".") {
+			return nil
+		}
+
+		info, err := entry.Info()
+		if err != nil {
+			return err
+		}
+		rel, err := filepath.Rel(s.directory, path)
+		if err != nil {
+			return err
+		}
+		images = append(images, StoredImage{Key: filepath.ToSlash(rel), LastModified: info.ModTime()})
+		return ctx.Err()
+	})
+	return images, err
+}
+
+func (s *localStore) Check(ctx context.Context) error {
+	file, err := os.CreateTemp(s.directory, ".readyz-*")
+	if err != nil {
+		return fmt.Errorf("image directory %s is not writable: %w", s.directory, err)
+	}
+	file.Close()
+	return os.Remove(file.Name())
+}
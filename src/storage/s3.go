@@ -0,0 +1,202 @@
+package storage
+
+import (
+	"bytes"
+	"context"
+	"encoding/xml"
+	"fmt"
+	"io"
+	"net/http"
+	"net/url"
+	"strings"
+	"time"
+)
+
+type S3Config struct {
+	// Endpoint is the base URL of the S3 API, e.g. https://s3.eu-central-1.amazonaws.com
+	// or http://minio:9000.
+	Endpoint  string
+	Region    string
+	Bucket    string
+	AccessKey string
+	SecretKey string
+	// PathStyle addresses the bucket as <endpoint>/<bucket>/<key> instead of
+	// <bucket>.<endpoint host>/<key>. MinIO and most stand-ins need it.
+	PathStyle bool
+}
+
+type s3Store struct {
+	config     S3Config
+	endpoint   *url.URL
+	httpClient *http.Client
+}
+
+// NewS3Store returns a store keeping images in an S3-compatible bucket.
+// httpClient may be nil.
+func NewS3Store(config S3Config, httpClient *http.Client) (ImageStore, error) {
+	endpoint, err := url.Parse(strings.TrimSuffix(config.Endpoint, "/"))
+	if err != nil || endpoint.Host == "" {
+		return nil, fmt.Errorf("invalid S3 endpoint %q", config.Endpoint)
+	}
+	if httpClient == nil {
+		httpClient = &http.Client{Timeout: 30 * time.Second}
+	}
+	return &s3Store{config, endpoint, httpClient}, nil
+}
+
+func (s *s3Store) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodPut, key, data)
+	if err != nil {
+		return err
+	}
+	req.Header.Set("Content-Type", contentType)
+
+	return s.do(req, data, http.StatusOK)
+}
+
+func (s *s3Store) Delete(ctx context.Context, key string) error {
+	if err := ValidateKey(key); err != nil {
+		return err
+	}
+
+	req, err := s.newRequest(ctx, http.MethodDelete, key, nil)
+	if err != nil {
+		return err
+	}
+
+	// S3 answers 204 whether or not the key existed; stand-ins may say 404.
+	return s.do(req, nil, http.StatusNoContent, http.StatusOK, http.StatusNotFound)
+}
+
+// listBucketResult is the part of a ListObjectsV2 response that List reads.
+type listBucketResult struct {
+	Contents []struct {
+		Key          string
+		LastModified time.Time
+	}
+	IsTruncated           bool
+	NextContinuationToken string
+}
+
+func (s *s3Store) List(ctx context.Context) ([]StoredImage, error) {
+	var images []StoredImage
+	token := ""
+	for {
+		req, err := s.newRequest(ctx, http.MethodGet, "", nil)
+		if err != nil {
+			return nil, err
+		}
+		query := url.Values{"list-type": {"2"}}
+		if token != "" {
+			query.Set("continuation-token", token)
+		}
+		req.URL.RawQuery = query.Encode()
+
+		var page listBucketResult
+		if err := s.doXML(req, &page); err != nil {
+			return nil, err
+		}
+		for _, object := range page.Contents {
+			images = append(images, StoredImage{Key: object.Key, LastModified: object.LastModified})
+		}
+
+		if !page.IsTruncated || page.NextContinuationToken == "" {
+			return images, nil
+		}
+		token = page.NextContinuationToken
+	}
+}
+
+func (s *s3Store) Check(ctx context.Context) error {
+	req, err := s.newRequest(ctx, http.MethodHead, "", nil)
+	if err != nil {
+		return err
+	}
+	return s.do(req, nil, http.StatusOK)
+}
+
+// newRequest builds an unsigned request for key, or for the bucket itself if
+// key is empty.
+func (s *s3Store) newRequest(ctx context.Context, method string, key string, body []byte) (*http.Request, error) {
+	path := s.endpoint.EscapedPath()
+	host := s.endpoint.Host
+	if s.config.PathStyle {
+		path += "/" + uriEncode(s.config.Bucket)
+	} else {
+		host = s.config.Bucket + "." + host
+	}
+	if key != "" {
+		path += "/" + escapePath(key)
+	}
+	if path == "" {
+		path = "/"
+	}
+
+	var reader io.Reader
+	if body != nil {
+		reader = bytes.NewReader(body)
+	}
+	return http.NewRequestWithContext(ctx, method, s.endpoint.Scheme+"://"+host+path, reader)
+}
+
+func (s *s3Store) do(req *http.Request, body []byte, expected ...int) error {
+	resp, err := s.send(req, body, expected...)
+	if err != nil {
+		return err
+	}
+	return resp.Body.Close()
+}
+
+// doXML sends a body-less request expecting 200 and decodes the response into
+// result.
+func (s *s3Store) doXML(req *http.Request, result any) error {
+	resp, err := s.send(req, nil, http.StatusOK)
+	if err != nil {
+		return err
+	}
+	defer resp.Body.Close()
+
+	if err := xml.NewDecoder(resp.Body).Decode(result); err != nil {
+		return fmt.Errorf("S3 %s %s returned an invalid response: %w", req.Method, req.URL.Path, err)
+	}
+	return nil
+}
+
+// send signs and sends req. The caller must close the body of the response,
+// which is only returned if its status is one of expected.
+func (s *s3Store) send(req *http.Request, body []byte, expected ...int) (*http.Response, error) {
+	payloadHash := EmptyPayloadHash
+	if body != nil {
+		payloadHash = hexSHA256(body)
+	}
+	SignV4(req, payloadHash, s.config.AccessKey, s.config.SecretKey, s.config.Region, time.Now())
+
+	resp, err := s.httpClient.Do(req)
+	if err != nil {
+		return nil, err
+	}
+
+	for _, status := range expected {
+		if resp.StatusCode == status {
+			return resp, nil
+		}
+	}
+
+	// S3 errors are an XML document; its code and message are enough.
+	defer resp.Body.Close()
+	detail, _ := io.ReadAll(io.LimitReader(resp.Body, 1024))
+	return nil, fmt.Errorf("S3 %s %s failed with status %d: %s", req.Method, req.URL.Path, resp.StatusCode, strings.TrimSpace(string(detail)))
+}
+
+// escapePath escapes every segment of a slash-separated key.
+func escapePath(key string) string {
+	segments := strings.Split(key, "/")
+	for i, segment := range segments {
+		segments[i] = uriEncode(segment)
+	}
+	return strings.Join(segments, "/")
+}
@@ -0,0 +1,42 @@
+// Package storage stores room images.
+//
+// Images are addressed by the hex SHA-256 of their bytes and their extension,
+// like "<hash>.jpg"; the downscaled variants add their name before the
+// extension, like "<hash>-thumbnail.jpg". Where the bytes live depends on the
+// ImageStore: a local directory (served by the nginx side container) or an
+// S3-compatible bucket.
+package storage
+
+import (
+	"context"
+	"fmt"
+	"path"
+	"strings"
+	"time"
+)
+
+type ImageStore interface {
+	// Put stores data under key, replacing any image stored under it.
+	Put(ctx context.Context, key string, data []byte, contentType string) error
+	// Delete removes the image under key. Deleting a missing key is not an
+	// error.
+	Delete(ctx context.Context, key string) error
+	// Check reports whether images can be stored.
+	Check(ctx context.Context) error
+	// List returns every stored image.
+	List(ctx context.Context) ([]StoredImage, error)
+}
+
+type StoredImage struct {
+	Key          string
+	LastModified time.Time
+}
+
+// ValidateKey rejects keys that could escape the store's root, such as
+// absolute paths or paths containing "..".
+func ValidateKey(key string) error {
+	if key == "" || strings.HasPrefix(key, "/") || strings.Contains(key, `\`) || path.Clean(key) != key || strings.HasPrefix(key, "..") {
+		return fmt.Errorf("invalid image key %q", key)
+	}
+	return nil
+}
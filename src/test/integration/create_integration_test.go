@@ -3,7 +3,6 @@ package integration
 import (
 	test "bookem-room-service/test/unit"
 	"bookem-room-service/util"
-	"fmt"
 	"testing"
 
 	"github.com/stretchr/testify/require"
@@ -23,7 +22,7 @@ func TestIntegration_Create_Success(t *testing.T) {
 	require.NoError(t, err)
 
 	result := responseToRoom(resp)
-	require.Equal(t, fmt.Sprintf("room-%d-%d.jpg", result.ID, 0), result.Photos[0])
+	require.Regexp(t, `^[0-9a-f]{64}\.jpg$`, result.Photos[0].Key)
 	require.Equal(t, result.Name, dto.Name)
 	require.Equal(t, result.Address, dto.Address)
 	require.Equal(t, result.Commodities, dto.Commodities)
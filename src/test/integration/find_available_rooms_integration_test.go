@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"bookem-room-service/internal"
 	test "bookem-room-service/test/unit"
 	"net/http"
 	"testing"
@@ -137,3 +138,92 @@ func TestIntegration_FindAvailableRooms_InvalidDate(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusBadRequest, resp.StatusCode)
 }
+
+func TestIntegration_FindAvailableRooms_FullText_Success(t *testing.T) {
+	cleanup("room")
+	cleanup("user")
+	setupRooms(3)
+
+	query := *test.DefaultRoomsQueryDTO
+	query.Address = ""
+	query.DateFrom = time.Date(2025, 8, 22, 0, 0, 0, 0, time.UTC)
+	query.DateTo = time.Date(2025, 8, 23, 0, 0, 0, 0, time.UTC)
+
+	// [1] Matches the commodities of every room
+	query.Query = "wifi"
+	query.SortBy = internal.SortByRelevance
+
+	resp, err := findAvailableRooms(query)
+	result := responseToFindAvailableRooms(resp)
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 3, int(result.Info.TotalHits))
+
+	// [2] Matches nothing
+	query.Query = "sea view jacuzzi"
+
+	resp, err = findAvailableRooms(query)
+	result = responseToFindAvailableRooms(resp)
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 0, int(result.Info.TotalHits))
+}
+
+func TestIntegration_FindAvailableRooms_Flexible_Success(t *testing.T) {
+	cleanup("room")
+	cleanup("user")
+	setupRooms(2)
+
+	// Rooms are available from Aug 20 to Aug 25.
+	query := *test.DefaultRoomsQueryDTO
+	query.Address = "Room Address"
+	query.DateFrom = time.Date(2025, 8, 15, 0, 0, 0, 0, time.UTC)
+	query.DateTo = time.Date(2025, 8, 30, 0, 0, 0, 0, time.UTC)
+	query.StayLength = 3
+	query.FlexibleMode = internal.FlexibleModeEarliest
+
+	resp, err := findAvailableRooms(query)
+	result := responseToFindAvailableRooms(resp)
+
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, 2, int(result.Info.TotalHits))
+	require.Equal(t, time.Date(2025, 8, 20, 0, 0, 0, 0, time.UTC), result.Hits[0].DateFrom.UTC())
+	require.Equal(t, time.Date(2025, 8, 22, 0, 0, 0, 0, time.UTC), result.Hits[0].DateTo.UTC())
+}
+
+func TestIntegration_FindAvailableRooms_Cursor_Success(t *testing.T) {
+	cleanup("room")
+	cleanup("user")
+	setupRooms(5)
+
+	query := *test.DefaultRoomsQueryDTO
+	query.Address = "Room Address"
+	query.DateFrom = time.Date(2025, 8, 22, 0, 0, 0, 0, time.UTC)
+	query.DateTo = time.Date(2025, 8, 23, 0, 0, 0, 0, time.UTC)
+	query.PageNumber = 1
+	query.PageSize = 2
+
+	seen := map[uint]bool{}
+	for page := 0; page < 3; page++ {
+		resp, err := findAvailableRooms(query)
+		result := responseToFindAvailableRooms(resp)
+
+		require.NoError(t, err)
+		require.Equal(t, http.StatusOK, resp.StatusCode)
+		for _, hit := range result.Hits {
+			require.False(t, seen[hit.ID])
+			seen[hit.ID] = true
+		}
+
+		query.Cursor = result.Info.NextCursor
+		if page < 2 {
+			require.NotEmpty(t, query.Cursor)
+		} else {
+			require.Empty(t, query.Cursor)
+		}
+	}
+	require.Equal(t, 5, len(seen))
+}
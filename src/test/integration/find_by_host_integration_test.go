@@ -22,15 +22,25 @@ func TestIntegration_FindByHost_Success(t *testing.T) {
 
 	resp, _ := createRoom(jwt, roomCreateDTO)
 	room := responseToRoom(resp)
+	publishRoom(jwt, &room)
 	resp, _ = createRoom(jwt, roomCreateDTO)
 	room2 := responseToRoom(resp)
-	roomsExpect := []internal.RoomDTO{room, room2}
 
-	resp, err := findRoomsByHostId(hostId)
+	// [1] The host sees every room
+
+	resp, err := findRoomsByHostIdAs(jwt, hostId)
 
 	require.NoError(t, err)
 	roomsGot := responseToRooms(resp)
-	require.Equal(t, roomsExpect, roomsGot)
+	require.Equal(t, []internal.RoomDTO{room, room2}, roomsGot)
+
+	// [2] Anyone else sees the published ones only
+
+	resp, err = findRoomsByHostId(hostId)
+
+	require.NoError(t, err)
+	roomsGot = responseToRooms(resp)
+	require.Equal(t, []internal.RoomDTO{room}, roomsGot)
 }
 
 func TestIntegration_FindByHost_MissingId(t *testing.T) {
@@ -22,6 +22,7 @@ func TestIntegration_FindById_Success(t *testing.T) {
 
 	resp, _ := createRoom(jwt, roomCreateDTO)
 	room := responseToRoom(resp)
+	publishRoom(jwt, &room)
 
 	roomId := room.ID
 
@@ -62,3 +63,32 @@ func TestIntegration_FindById_Deleted(t *testing.T) {
 	require.NoError(t, err)
 	require.Equal(t, http.StatusNotFound, resp.StatusCode)
 }
+
+func TestIntegration_FindById_Draft(t *testing.T) {
+	cleanup("room")
+	cleanup("user")
+
+	registerUser("user2", "1234", util.Host)
+	jwt := loginUser2("user2", "1234")
+	jwtObj, _ := util.GetJwtFromString(jwt)
+
+	roomCreateDTO := test.DefaultRoomCreateDTO
+	roomCreateDTO.HostID = jwtObj.ID
+
+	resp, _ := createRoom(jwt, roomCreateDTO)
+	room := responseToRoom(resp)
+	require.Equal(t, "draft", room.Status)
+
+	resp, err := findRoomById(room.ID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+
+	resp, err = submitRoom(jwt, room.ID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusOK, resp.StatusCode)
+	require.Equal(t, "pending_review", responseToRoom(resp).Status)
+
+	resp, err = findRoomById(room.ID)
+	require.NoError(t, err)
+	require.Equal(t, http.StatusNotFound, resp.StatusCode)
+}
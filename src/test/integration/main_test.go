@@ -1,6 +1,7 @@
 package integration
 
 import (
+	"bookem-room-service/util"
 	"fmt"
 	"log"
 	"os"
@@ -41,8 +42,9 @@ func cleanup(service string) {
 
 	var db *gorm.DB = getConnection(service)
 
+	// The commodities catalog is seeded by a migration, rooms need it.
 	var tables []string
-	err := db.Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = 'public'").Scan(&tables).Error
+	err := db.Raw("SELECT table_name FROM information_schema.tables WHERE table_schema = 'public' AND table_name NOT IN ('schema_migrations', 'commodities')").Scan(&tables).Error
 	if err != nil {
 		log.Fatalf("error fetching table names: %v", err)
 	}
@@ -111,6 +113,7 @@ func closeDBConnections() {
 
 func TestMain(m *testing.M) {
 
+	util.SetJWTPublicKeyPath(os.Getenv("JWT_PUBLIC_KEY_PATH"))
 	connectToDBs()
 
 	code := m.Run()
@@ -103,6 +103,60 @@ func createRoom(jwt string, dto internal.CreateRoomDTO) (*http.Response, error)
 	return http.DefaultClient.Do(req)
 }
 
+func submitRoom(jwt string, id uint) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%s%d/submit", url_room, id), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+jwt)
+	return http.DefaultClient.Do(req)
+}
+
+// approveRoom approves a room pending review, as the admin.
+func approveRoom(adminJwt string, id uint) (*http.Response, error) {
+	jsonBytes, err := json.Marshal(internal.ReviewRoomDTO{})
+	if err != nil {
+		return nil, err
+	}
+
+	req, err := http.NewRequest(http.MethodPost, fmt.Sprintf("%sadmin/rooms/%d/approve", url_room, id), bytes.NewBuffer(jsonBytes))
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+adminJwt)
+	return http.DefaultClient.Do(req)
+}
+
+// loginAdmin logs in as the admin that reviews rooms, registering it first.
+// Registering again fails harmlessly once it exists.
+func loginAdmin() string {
+	registerUser("room_admin", "1234", util.Admin)
+	return loginUser2("room_admin", "1234")
+}
+
+// publishRoom submits the room for review as its host and approves it as
+// the admin, so that it is listed.
+func publishRoom(jwt string, room *internal.RoomDTO) {
+	resp, err := submitRoom(jwt, room.ID)
+	if err != nil {
+		panic(err)
+	}
+	resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		panic(fmt.Sprintf("failed to submit room %d: %s", room.ID, resp.Status))
+	}
+
+	resp, err = approveRoom(loginAdmin(), room.ID)
+	if err != nil {
+		panic(err)
+	}
+	defer resp.Body.Close()
+	if resp.StatusCode != http.StatusOK {
+		panic(fmt.Sprintf("failed to approve room %d: %s", room.ID, resp.Status))
+	}
+	*room = responseToRoom(resp)
+}
+
 func findRoomById(id uint) (*http.Response, error) {
 	resp, err := http.Get(fmt.Sprintf("%s%d", url_room, id)) // No forward slash between them, it's in `URL`
 	return resp, err
@@ -115,8 +169,22 @@ func findAvailableRooms(dto internal.RoomsQueryDTO) (*http.Response, error) {
 	val, _ = dto.DateTo.UTC().MarshalText()
 	params.Add("dateTo", string(val))
 	params.Add("address", dto.Address)
+	if dto.Query != "" {
+		params.Add("q", dto.Query)
+	}
+	if dto.SortBy != "" {
+		params.Add("sortBy", dto.SortBy)
+	}
+	if dto.StayLength != 0 {
+		params.Add("stayLength", fmt.Sprintf("%d", dto.StayLength))
+		params.Add("flexibleMode", dto.FlexibleMode)
+	}
 	params.Add("guestsNumber", fmt.Sprintf("%d", dto.GuestsNumber))
-	params.Add("pageNumber", fmt.Sprintf("%d", dto.PageNumber))
+	if dto.Cursor != "" {
+		params.Add("cursor", dto.Cursor)
+	} else {
+		params.Add("pageNumber", fmt.Sprintf("%d", dto.PageNumber))
+	}
 	params.Add("pageSize", fmt.Sprintf("%d", dto.PageSize))
 
 	req, err := http.NewRequest(http.MethodGet, url_room+"all?"+params.Encode(), nil)
@@ -131,6 +199,15 @@ func findRoomsByHostId(hostId uint) (*http.Response, error) {
 	return resp, err
 }
 
+func findRoomsByHostIdAs(jwt string, hostId uint) (*http.Response, error) {
+	req, err := http.NewRequest(http.MethodGet, fmt.Sprintf("%shost/%d", url_room, hostId), nil)
+	if err != nil {
+		return nil, err
+	}
+	req.Header.Add("Authorization", "Bearer "+jwt)
+	return http.DefaultClient.Do(req)
+}
+
 func responseToRoom(resp *http.Response) internal.RoomDTO {
 	bodyBytes, err := io.ReadAll(resp.Body)
 	if err != nil {
@@ -294,6 +371,7 @@ func createUserAndRoom(username string) (string, *util.Jwt, internal.RoomDTO) {
 	dto.HostID = jwtObj.ID
 	resp, _ := createRoom(jwt, dto)
 	room := responseToRoom(resp)
+	publishRoom(jwt, &room)
 
 	return jwt, jwtObj, room
 }
@@ -321,6 +399,7 @@ func createUserAndRoomForPrice(username string) (string, *util.Jwt, internal.Roo
 	dto.HostID = jwtObj.ID
 	resp, _ := createRoom(jwt, dto)
 	room := responseToRoom(resp)
+	publishRoom(jwt, &room)
 
 	return jwt, jwtObj, room
 }
@@ -0,0 +1,207 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+)
+
+func suspendedRoom() *internal.Room {
+	room := *DefaultRoom
+	room.Status = internal.RoomSuspended
+	room.StatusComment = "misleading photos"
+	return &room
+}
+
+func Test_SuspendRoom_Success(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	room := *DefaultRoom
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+	mockRepo.On("FindById", room.ID).Return(&room, nil)
+	mockRepo.On("UpdateStatusWithAudit", mock.AnythingOfType("*internal.Room"), mock.AnythingOfType("internal.RoomStatus"), mock.AnythingOfType("*internal.AuditEntry")).Return(true, nil)
+
+	roomGot, err := svc.SuspendRoom(context.Background(), DefaultUser_Admin.Id, room.ID, " misleading photos ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, internal.RoomSuspended, roomGot.Status)
+	assert.Equal(t, "misleading photos", roomGot.StatusComment)
+
+	entry := mockRepo.Calls[1].Arguments.Get(2).(*internal.AuditEntry)
+	assert.Equal(t, DefaultUser_Admin.Id, entry.ActorID)
+	assert.Equal(t, internal.AuditSuspendRoom, entry.Action)
+	assert.Equal(t, room.ID, *entry.RoomID)
+	assert.Equal(t, "misleading photos", entry.Details)
+}
+
+func Test_SuspendRoom_Rejects(t *testing.T) {
+	tests := map[string]struct {
+		room   *internal.Room
+		reason string
+		code   int
+	}{
+		"no reason":         {DefaultRoom, "  ", http.StatusBadRequest},
+		"long reason":       {DefaultRoom, strings.Repeat("a", 501), http.StatusBadRequest},
+		"already suspended": {suspendedRoom(), "spam", http.StatusConflict},
+	}
+
+	for name, test := range tests {
+		svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+		room := *test.room
+		mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+		mockRepo.On("FindById", room.ID).Return(&room, nil)
+
+		_, err := svc.SuspendRoom(context.Background(), DefaultUser_Admin.Id, room.ID, test.reason)
+
+		code, _ := internal.MapErrorToHTTP(err)
+		assert.Equal(t, test.code, code, name)
+		mockRepo.AssertNumberOfCalls(t, "UpdateStatusWithAudit", 0)
+	}
+}
+
+func Test_SuspendRoom_NotAdmin(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	_, err := svc.SuspendRoom(context.Background(), DefaultUser_Host.Id, DefaultRoom.ID, "spam")
+
+	assert.Equal(t, internal.ErrUnauthorized, err)
+	mockRepo.AssertNumberOfCalls(t, "FindById", 0)
+}
+
+func Test_RestoreRoom(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	room := suspendedRoom()
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockRepo.On("UpdateStatusWithAudit", room, mock.AnythingOfType("internal.RoomStatus"), mock.AnythingOfType("*internal.AuditEntry")).Return(true, nil)
+
+	roomGot, err := svc.RestoreRoom(context.Background(), DefaultUser_Admin.Id, room.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, internal.RoomPublished, roomGot.Status)
+	assert.Empty(t, roomGot.StatusComment)
+
+	_, err = svc.RestoreRoom(context.Background(), DefaultUser_Admin.Id, room.ID)
+
+	code, _ := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusConflict, code, "the room is not suspended anymore")
+	mockRepo.AssertNumberOfCalls(t, "UpdateStatusWithAudit", 1)
+}
+
+func Test_FindById_Suspended(t *testing.T) {
+	svc, mockRepo, _, _, _ := CreateTestRoomService()
+	room := suspendedRoom()
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+
+	roomGot, err := svc.FindById(context.Background(), room.ID)
+
+	code, _ := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Nil(t, roomGot)
+}
+
+func Test_FindAvailableRooms_ExcludesSuspended(t *testing.T) {
+	svc, mockRepo, mockAvailRepo, mockPriceRepo, _ := CreateTestRoomService()
+
+	d := *DefaultRoomsQueryDTO
+	mockRepo.On("FindByFilters", d.GuestsNumber, d.Address, d.Query).Return([]internal.Room{*suspendedRoom()}, nil)
+
+	roomsGot, _, err := svc.FindAvailableRooms(context.Background(), d)
+
+	assert.NoError(t, err)
+	assert.Empty(t, roomsGot)
+	mockAvailRepo.AssertNumberOfCalls(t, "FindCurrentListOfRoom", 0)
+	mockPriceRepo.AssertNumberOfCalls(t, "FindCurrentListOfRoom", 0)
+}
+
+func Test_FindAllRooms_RecordsAudit(t *testing.T) {
+	audit := NewFakeAuditRepo()
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithAuditRepo(audit))
+	hostId := DefaultUser_Host.Id
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+	mockRepo.On("FindByHost", hostId).Return([]internal.Room{*DefaultRoom, *suspendedRoom()}, nil)
+
+	rooms, err := svc.FindAllRooms(context.Background(), DefaultUser_Admin.Id, &hostId)
+
+	assert.NoError(t, err)
+	assert.Len(t, rooms, 2)
+	assert.Len(t, audit.Entries, 1)
+	assert.Equal(t, internal.AuditListRooms, audit.Entries[0].Action)
+	assert.Equal(t, fmt.Sprintf("host_id=%d", hostId), audit.Entries[0].Details)
+}
+
+// An admin action that could not be recorded must not happen.
+func Test_FindAllRooms_AuditFailed(t *testing.T) {
+	audit := NewFakeAuditRepo()
+	audit.CreateErr = fmt.Errorf("some error")
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithAuditRepo(audit))
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+	mockRepo.On("FindAll").Return([]internal.Room{*DefaultRoom}, nil)
+
+	rooms, err := svc.FindAllRooms(context.Background(), DefaultUser_Admin.Id, nil)
+
+	assert.Error(t, err)
+	assert.Nil(t, rooms)
+}
+
+func Test_FindRoomHistory(t *testing.T) {
+	audit := NewFakeAuditRepo()
+	svc, mockRepo, mockAvailRepo, mockPriceRepo, mockUserClient := CreateTestRoomService(WithAuditRepo(audit))
+	room := suspendedRoom()
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockPriceRepo.On("FindListsByRoomId", room.ID).Return([]internal.RoomPriceList{*DefaultPriceList}, nil)
+	mockAvailRepo.On("FindListsByRoomId", room.ID).Return([]internal.RoomAvailabilityList{*DefaultAvailabilityList}, nil)
+
+	history, err := svc.FindRoomHistory(context.Background(), DefaultUser_Admin.Id, room.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, room, history.Room, "suspended rooms are visible to admins")
+	assert.Len(t, history.PriceLists, 1)
+	assert.Len(t, history.AvailabilityLists, 1)
+	assert.Len(t, history.Audit, 1)
+	assert.Equal(t, internal.AuditViewRoomHistory, history.Audit[0].Action)
+}
+
+func Test_AdminRoutes(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	send := newUploadServer(t, svc, internal.UploadLimits{MaxFileBytes: 1, MaxRequestBytes: 1, MaxFiles: 1})
+	room := *DefaultRoom
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Host.Id).Return(DefaultUser_Admin, nil)
+	mockRepo.On("FindById", room.ID).Return(&room, nil)
+	mockRepo.On("UpdateStatusWithAudit", &room, mock.AnythingOfType("internal.RoomStatus"), mock.AnythingOfType("*internal.AuditEntry")).Return(true, nil)
+
+	// [1] Only admins can suspend rooms
+
+	body, _ := json.Marshal(internal.SuspendRoomDTO{Reason: "spam"})
+	path := fmt.Sprintf("/api/admin/rooms/%d/suspend", room.ID)
+	for _, role := range []string{"guest", "host"} {
+		req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+role)
+		assert.Equal(t, http.StatusUnauthorized, send(req).Code, role)
+	}
+
+	req := httptest.NewRequest(http.MethodPost, path, bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer admin")
+	w := send(req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var dto internal.ManagedRoomDTO
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &dto))
+	assert.Equal(t, "suspended", dto.Status)
+	assert.Equal(t, "spam", dto.StatusComment)
+
+	// [2] The room is gone for everyone else
+
+	w = send(httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/%d", room.ID), nil))
+	assert.Equal(t, http.StatusNotFound, w.Code)
+}
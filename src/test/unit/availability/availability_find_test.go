@@ -11,13 +11,14 @@ import (
 )
 
 func Test_FindAvailabilityListById_Success(t *testing.T) {
-	svc, _, mockAvailRepo, _, _ := CreateTestRoomService()
+	svc, mockRepo, mockAvailRepo, _, _ := CreateTestRoomService()
 
 	li := DefaultAvailabilityList
 
 	mockAvailRepo.On("FindListById", li.ID).Return(li, nil)
+	mockRepo.On("FindById", li.RoomID).Return(DefaultRoom, nil)
 
-	liGot, err := svc.FindAvailabilityListById(context.Background(), li.ID)
+	liGot, err := svc.FindAvailabilityListById(context.Background(), nil, li.ID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, li, liGot)
@@ -32,7 +33,7 @@ func Test_FindAvailabilityListById_NotFound(t *testing.T) {
 
 	mockAvailRepo.On("FindListById", li.ID).Return(nil, fmt.Errorf("not found"))
 
-	liGot, err := svc.FindAvailabilityListById(context.Background(), li.ID)
+	liGot, err := svc.FindAvailabilityListById(context.Background(), nil, li.ID)
 
 	assert.Error(t, err)
 	assert.Nil(t, liGot)
@@ -57,7 +58,7 @@ func Test_FindAvailabilityListsByRoomId_Success(t *testing.T) {
 	mockAvailRepo.On("FindListsByRoomId", li1.RoomID).Return(lists, nil)
 	mockRepo.On("FindById", room.ID).Return(room, nil)
 
-	listsGot, err := svc.FindAvailabilityListsByRoomId(context.Background(), room.ID)
+	listsGot, err := svc.FindAvailabilityListsByRoomId(context.Background(), nil, room.ID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, lists, listsGot)
@@ -77,7 +78,7 @@ func Test_FindAvailabilityListsByRoomId_Success_NoLists(t *testing.T) {
 	mockAvailRepo.On("FindListsByRoomId", uint(999)).Return(lists, nil)
 	mockRepo.On("FindById", room.ID).Return(room, nil)
 
-	listsGot, err := svc.FindAvailabilityListsByRoomId(context.Background(), uint(999))
+	listsGot, err := svc.FindAvailabilityListsByRoomId(context.Background(), nil, uint(999))
 
 	assert.NoError(t, err)
 	assert.Equal(t, lists, listsGot)
@@ -98,7 +99,7 @@ func Test_FindAvailabilityListsByRoomId_NotFound(t *testing.T) {
 	mockAvailRepo.On("FindListsByRoomId", li.RoomID).Return(nil, fmt.Errorf("not found"))
 	mockRepo.On("FindById", room.ID).Return(room, nil)
 
-	liGot, err := svc.FindAvailabilityListsByRoomId(context.Background(), li.RoomID)
+	liGot, err := svc.FindAvailabilityListsByRoomId(context.Background(), nil, li.RoomID)
 
 	assert.Error(t, err)
 	assert.Nil(t, liGot)
@@ -114,7 +115,7 @@ func Test_FindAvailabilityListsByRoomId_RoomNotFound(t *testing.T) {
 
 	mockRepo.On("FindById", li.RoomID).Return(nil, fmt.Errorf("not found"))
 
-	liGot, err := svc.FindAvailabilityListsByRoomId(context.Background(), li.RoomID)
+	liGot, err := svc.FindAvailabilityListsByRoomId(context.Background(), nil, li.RoomID)
 
 	assert.Error(t, err)
 	assert.Nil(t, liGot)
@@ -122,13 +123,14 @@ func Test_FindAvailabilityListsByRoomId_RoomNotFound(t *testing.T) {
 }
 
 func Test_FindCurrentAvailabilityListOfRoom_Success(t *testing.T) {
-	svc, _, mockAvailRepo, _, _ := CreateTestRoomService()
+	svc, mockRepo, mockAvailRepo, _, _ := CreateTestRoomService()
 
 	li := DefaultAvailabilityList
 
 	mockAvailRepo.On("FindCurrentListOfRoom", li.RoomID).Return(li, nil)
+	mockRepo.On("FindById", li.RoomID).Return(DefaultRoom, nil)
 
-	liGot, err := svc.FindCurrentAvailabilityListOfRoom(context.Background(), li.RoomID)
+	liGot, err := svc.FindCurrentAvailabilityListOfRoom(context.Background(), nil, li.RoomID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, li, liGot)
@@ -137,13 +139,14 @@ func Test_FindCurrentAvailabilityListOfRoom_Success(t *testing.T) {
 }
 
 func Test_FindCurrentAvailabilityListOfRoom_NotFound(t *testing.T) {
-	svc, _, mockAvailRepo, _, _ := CreateTestRoomService()
+	svc, mockRepo, mockAvailRepo, _, _ := CreateTestRoomService()
 
 	li := DefaultAvailabilityList
 
 	mockAvailRepo.On("FindCurrentListOfRoom", li.RoomID).Return(nil, fmt.Errorf("not found"))
+	mockRepo.On("FindById", li.RoomID).Return(DefaultRoom, nil)
 
-	liGot, err := svc.FindCurrentAvailabilityListOfRoom(context.Background(), li.RoomID)
+	liGot, err := svc.FindCurrentAvailabilityListOfRoom(context.Background(), nil, li.RoomID)
 
 	assert.Error(t, err)
 	assert.Nil(t, liGot)
@@ -0,0 +1,198 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	"bytes"
+	"context"
+	"encoding/json"
+	"net/http"
+	"net/http/httptest"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+)
+
+func Test_Create_UnknownCommodity(t *testing.T) {
+	tests := map[string][]string{
+		"unknown":      {"wifi", "WiFi"},
+		"listed twice": {"wifi", "parking", "wifi"},
+	}
+
+	for name, commodities := range tests {
+		svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+		mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+		dto := DefaultRoomCreateDTO
+		dto.Commodities = commodities
+
+		_, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
+
+		code, _ := internal.MapErrorToHTTP(err)
+		assert.Equal(t, http.StatusBadRequest, code, name)
+		mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 0)
+	}
+}
+
+func Test_CreateCommodity_Success(t *testing.T) {
+	repo := NewFakeCommodityRepo(DefaultCommodities...)
+	svc, _, _, _, mockUserClient := CreateTestRoomService(WithCommodityRepo(repo))
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+
+	commodity, err := svc.CreateCommodity(context.Background(), DefaultUser_Admin.Id, internal.CommodityDTO{
+		Key:      "hot-tub",
+		Category: "outdoor",
+		Icon:     " hot_tub ",
+		Labels:   map[string]string{"en": " Hot tub ", "sr-Latn": "Džakuzi"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "hot_tub", commodity.Icon)
+	assert.Equal(t, map[string]string{"en": "Hot tub", "sr-Latn": "Džakuzi"}, commodity.Labels)
+	assert.Contains(t, repo.Commodities, "hot-tub")
+}
+
+func Test_CreateCommodity_Rejects(t *testing.T) {
+	valid := internal.CommodityDTO{Key: "hot-tub", Category: "outdoor", Labels: map[string]string{"en": "Hot tub"}}
+	with := func(change func(dto *internal.CommodityDTO)) internal.CommodityDTO {
+		dto := valid
+		dto.Labels = map[string]string{"en": "Hot tub"}
+		change(&dto)
+		return dto
+	}
+
+	tests := map[string]internal.CommodityDTO{
+		"uppercase key":    with(func(dto *internal.CommodityDTO) { dto.Key = "Hot-Tub" }),
+		"spaced key":       with(func(dto *internal.CommodityDTO) { dto.Key = "hot tub" }),
+		"long key":         with(func(dto *internal.CommodityDTO) { dto.Key = strings.Repeat("a", 51) }),
+		"no category":      with(func(dto *internal.CommodityDTO) { dto.Category = "" }),
+		"no English label": with(func(dto *internal.CommodityDTO) { dto.Labels = map[string]string{"sr": "Džakuzi"} }),
+		"bad language":     with(func(dto *internal.CommodityDTO) { dto.Labels["serbian"] = "Džakuzi" }),
+		"empty label":      with(func(dto *internal.CommodityDTO) { dto.Labels["sr"] = " " }),
+		"long label":       with(func(dto *internal.CommodityDTO) { dto.Labels["sr"] = strings.Repeat("a", 101) }),
+	}
+
+	for name, dto := range tests {
+		repo := NewFakeCommodityRepo(DefaultCommodities...)
+		svc, _, _, _, mockUserClient := CreateTestRoomService(WithCommodityRepo(repo))
+		mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+
+		_, err := svc.CreateCommodity(context.Background(), DefaultUser_Admin.Id, dto)
+
+		code, _ := internal.MapErrorToHTTP(err)
+		assert.Equal(t, http.StatusBadRequest, code, name)
+		assert.Len(t, repo.Commodities, len(DefaultCommodities), name)
+	}
+}
+
+func Test_CreateCommodity_Exists(t *testing.T) {
+	svc, _, _, _, mockUserClient := CreateTestRoomService()
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+
+	_, err := svc.CreateCommodity(context.Background(), DefaultUser_Admin.Id, internal.CommodityDTO{
+		Key:      "wifi",
+		Category: "connectivity",
+		Labels:   map[string]string{"en": "Wireless"},
+	})
+
+	code, _ := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusConflict, code)
+}
+
+func Test_CreateCommodity_NotAdmin(t *testing.T) {
+	repo := NewFakeCommodityRepo()
+	svc, _, _, _, mockUserClient := CreateTestRoomService(WithCommodityRepo(repo))
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	_, err := svc.CreateCommodity(context.Background(), DefaultUser_Host.Id, internal.CommodityDTO{
+		Key:      "hot-tub",
+		Category: "outdoor",
+		Labels:   map[string]string{"en": "Hot tub"},
+	})
+
+	assert.Equal(t, internal.ErrUnauthorized, err)
+	assert.Empty(t, repo.Commodities)
+}
+
+func Test_UpdateCommodity_Success(t *testing.T) {
+	repo := NewFakeCommodityRepo(DefaultCommodities...)
+	svc, _, _, _, mockUserClient := CreateTestRoomService(WithCommodityRepo(repo))
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+
+	commodity, err := svc.UpdateCommodity(context.Background(), DefaultUser_Admin.Id, "wifi", internal.UpdateCommodityDTO{
+		Category: "internet",
+		Icon:     "wifi",
+		Labels:   map[string]string{"en": "Wi-Fi", "sr": "Bežični internet"},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, "wifi", commodity.Key)
+	assert.Equal(t, "internet", repo.Commodities["wifi"].Category)
+	assert.Equal(t, "Bežični internet", repo.Commodities["wifi"].Labels["sr"])
+}
+
+func Test_UpdateCommodity_NotFound(t *testing.T) {
+	svc, _, _, _, mockUserClient := CreateTestRoomService()
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+
+	_, err := svc.UpdateCommodity(context.Background(), DefaultUser_Admin.Id, "sauna", internal.UpdateCommodityDTO{
+		Category: "comfort",
+		Labels:   map[string]string{"en": "Sauna"},
+	})
+
+	code, _ := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusNotFound, code)
+}
+
+func Test_DeleteCommodity(t *testing.T) {
+	repo := NewFakeCommodityRepo(DefaultCommodities...)
+	repo.Rooms["wifi"] = 3
+	svc, _, _, _, mockUserClient := CreateTestRoomService(WithCommodityRepo(repo))
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+
+	err := svc.DeleteCommodity(context.Background(), DefaultUser_Admin.Id, "wifi")
+	code, message := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Contains(t, message, "3 rooms")
+
+	assert.NoError(t, svc.DeleteCommodity(context.Background(), DefaultUser_Admin.Id, "parking"))
+	assert.Equal(t, []string{"wifi"}, keysOf(repo))
+}
+
+func Test_CommodityRoutes(t *testing.T) {
+	svc, _, _, _, mockUserClient := CreateTestRoomService()
+	send := newUploadServer(t, svc, internal.UploadLimits{MaxFileBytes: 1, MaxRequestBytes: 1, MaxFiles: 1})
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Host.Id).Return(DefaultUser_Admin, nil)
+
+	// [1] Anyone can list the catalog
+
+	w := send(httptest.NewRequest(http.MethodGet, "/api/commodities", nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	var catalog []internal.CommodityDTO
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &catalog))
+	assert.Equal(t, "wifi", catalog[0].Key)
+	assert.Equal(t, "parking", catalog[1].Key)
+
+	// [2] Only admins can change it
+
+	body, _ := json.Marshal(internal.CommodityDTO{Key: "pool", Category: "outdoor", Labels: map[string]string{"en": "Pool"}})
+	for role, code := range map[string]int{"host": http.StatusUnauthorized, "admin": http.StatusCreated} {
+		req := httptest.NewRequest(http.MethodPost, "/api/commodities", bytes.NewReader(body))
+		req.Header.Set("Authorization", "Bearer "+role)
+		assert.Equal(t, code, send(req).Code, role)
+	}
+
+	req := httptest.NewRequest(http.MethodDelete, "/api/commodities/pool", nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	assert.Equal(t, http.StatusNoContent, send(req).Code)
+}
+
+func keysOf(repo *FakeCommodityRepo) []string {
+	commodities, _ := repo.FindAll()
+	keys := []string{}
+	for _, commodity := range commodities {
+		keys = append(keys, commodity.Key)
+	}
+	return keys
+}
@@ -0,0 +1,119 @@
+package test
+
+import (
+	"bookem-room-service/config"
+	"bookem-room-service/util"
+	"os"
+	"path/filepath"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// setRequiredEnv sets the settings that have no defaults.
+func setRequiredEnv(t *testing.T) {
+	t.Setenv("CONFIG_FILE", "")
+	t.Setenv("DB_HOST", "room-db")
+	t.Setenv("DB_USER", "user")
+	t.Setenv("DB_NAME", "rooms")
+	t.Setenv("JWT_PUBLIC_KEY_PATH", "/app/keys/public_key.pem")
+}
+
+func Test_Load_FromEnv(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DB_PORT", "6543")
+	t.Setenv("USER_SERVICE_URL", "http://users:9000/api")
+	t.Setenv("CORS_ALLOW_ORIGINS", "http://a.com, http://b.com")
+	t.Setenv("LOG_FILE", "")
+	t.Setenv("SEARCH_CACHE_TTL", "30s")
+
+	cfg, err := config.Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "host=room-db port=6543 user=user password= dbname=rooms sslmode=disable", cfg.DB.DSN())
+	assert.Equal(t, "http://users:9000/api", cfg.UserService.BaseURL)
+	assert.Equal(t, []string{"http://a.com", "http://b.com"}, cfg.CORS.AllowOrigins)
+	assert.Equal(t, "", cfg.Log.File)
+	assert.Equal(t, config.Duration(30*time.Second), cfg.Search.CacheTTL)
+	assert.Equal(t, "/app/images/", cfg.Images.Directory)
+}
+
+func Test_Load_FileThenEnv(t *testing.T) {
+	setRequiredEnv(t)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	err := os.WriteFile(path, []byte(`{
+		"db": {"host": "file-db", "port": 7000},
+		"log": {"level": "warn"},
+		"tracing": {"exporter": "stdout", "sampleRatio": 0.5},
+		"search": {"concurrency": 2, "cacheTtl": "5m"}
+	}`), 0644)
+	assert.NoError(t, err)
+
+	t.Setenv("CONFIG_FILE", path)
+	t.Setenv("DB_HOST", "env-db")
+
+	cfg, err := config.Load()
+
+	assert.NoError(t, err)
+	assert.Equal(t, "env-db", cfg.DB.Host)
+	assert.Equal(t, 7000, cfg.DB.Port)
+	assert.Equal(t, "warn", cfg.Log.Level)
+	assert.Equal(t, util.TracingConfig{Exporter: util.ExporterStdout, SampleRatio: 0.5}, cfg.Tracing)
+	assert.Equal(t, 2, cfg.Search.Concurrency)
+	assert.Equal(t, config.Duration(5*time.Minute), cfg.Search.CacheTTL)
+}
+
+func Test_Load_UnknownFileField(t *testing.T) {
+	setRequiredEnv(t)
+
+	path := filepath.Join(t.TempDir(), "config.json")
+	assert.NoError(t, os.WriteFile(path, []byte(`{"db": {"hots": "typo"}}`), 0644))
+	t.Setenv("CONFIG_FILE", path)
+
+	_, err := config.Load()
+
+	assert.ErrorContains(t, err, "hots")
+}
+
+func Test_Load_ReportsEveryInvalidSetting(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DB_HOST", "")
+	t.Setenv("USER_SERVICE_URL", "user-service")
+	t.Setenv("LOG_LEVEL", "loud")
+	t.Setenv("TRACE_SAMPLE_RATIO", "1.5")
+	t.Setenv("HTTP_SHUTDOWN_TIMEOUT", "0s")
+	t.Setenv("IMG_ORPHAN_GRACE_PERIOD", "2h")
+	t.Setenv("IMG_STAGED_PHOTO_TTL", "1h")
+
+	_, err := config.Load()
+
+	assert.ErrorContains(t, err, "DB_HOST")
+	assert.ErrorContains(t, err, "USER_SERVICE_URL")
+	assert.ErrorContains(t, err, "LOG_LEVEL")
+	assert.ErrorContains(t, err, "TRACE_SAMPLE_RATIO")
+	assert.ErrorContains(t, err, "HTTP_SHUTDOWN_TIMEOUT")
+	assert.ErrorContains(t, err, "IMG_STAGED_PHOTO_TTL")
+}
+
+func Test_Load_UnparsableEnv(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("DB_PORT", "five")
+	t.Setenv("SEARCH_CACHE_TTL", "soon")
+
+	_, err := config.Load()
+
+	assert.ErrorContains(t, err, "DB_PORT")
+	assert.ErrorContains(t, err, "SEARCH_CACHE_TTL")
+}
+
+func Test_Load_ShutdownDelayWithinTimeout(t *testing.T) {
+	setRequiredEnv(t)
+	t.Setenv("HTTP_SHUTDOWN_TIMEOUT", "10s")
+	t.Setenv("HTTP_SHUTDOWN_DELAY", "10s")
+
+	_, err := config.Load()
+
+	assert.ErrorContains(t, err, "HTTP_SHUTDOWN_DELAY")
+}
@@ -1,9 +1,15 @@
 package test
 
 import (
+	"bookem-room-service/client/userclient"
+	"bookem-room-service/internal"
 	"bookem-room-service/util"
 	"context"
+	"crypto/sha256"
+	"encoding/base64"
+	"encoding/hex"
 	"fmt"
+	"net/http"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
@@ -11,24 +17,26 @@ import (
 )
 
 func Test_Create_Success(t *testing.T) {
-	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	images := NewFakeImageStore()
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images))
 
-	room := DefaultRoom
+	room := *DefaultRoom
 	dto := DefaultRoomCreateDTO
 
-	mockRepo.On("Create", mock.AnythingOfType("*internal.Room")).Return(nil)
-	mockRepo.On("Update", mock.AnythingOfType("*internal.Room")).Return(nil)
+	mockRepo.On("CreateWithPhotos", mock.AnythingOfType("*internal.Room"), []uint(nil)).Return(nil)
 	mockUserClient.On("FindById", context.Background(), mock.AnythingOfType("uint")).Return(DefaultUser_Host, nil)
-	util.SaveImageB64 = func(base64Image string, filename string) (string, string, error) {
-		return "foo/" + room.Photos[0], room.Photos[0], nil
-	}
 
 	roomGot, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
 
 	assert.NoError(t, err)
-	assert.Equal(t, room, roomGot)
-	mockRepo.AssertNumberOfCalls(t, "Create", 1)
-	mockRepo.AssertNumberOfCalls(t, "Update", 1)
+	assert.Len(t, roomGot.Photos, 1)
+	assert.Regexp(t, `^[0-9a-f]{64}\.jpg$`, roomGot.Photos[0].Key)
+	assert.Equal(t, []string{roomGot.Photos[0].Key}, images.Keys())
+	room.Photos = roomGot.Photos
+	room.Status = internal.RoomDraft
+	assert.Equal(t, &room, roomGot)
+	mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 1)
+	mockRepo.AssertNumberOfCalls(t, "Update", 0)
 	mockRepo.AssertNumberOfCalls(t, "Delete", 0)
 	mockRepo.AssertExpectations(t)
 	mockUserClient.AssertNumberOfCalls(t, "FindById", 1)
@@ -36,19 +44,20 @@ func Test_Create_Success(t *testing.T) {
 }
 
 func Test_Create_InsertFailed(t *testing.T) {
-	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	images := NewFakeImageStore()
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images))
 
 	dto := DefaultRoomCreateDTO
 
-	mockRepo.On("Create", mock.AnythingOfType("*internal.Room")).Return(fmt.Errorf("db error"))
+	mockRepo.On("CreateWithPhotos", mock.AnythingOfType("*internal.Room"), []uint(nil)).Return(fmt.Errorf("db error"))
 	mockUserClient.On("FindById", context.Background(), mock.AnythingOfType("uint")).Return(DefaultUser_Host, nil)
 
 	roomGot, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
 
 	assert.Error(t, err)
 	assert.Nil(t, roomGot)
-	mockRepo.AssertNumberOfCalls(t, "Create", 1)
-	mockRepo.AssertNumberOfCalls(t, "Update", 0)
+	assert.Empty(t, images.Keys(), "the photos stored before the insert are cleaned up")
+	mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 1)
 	mockRepo.AssertNumberOfCalls(t, "Delete", 0)
 	mockRepo.AssertExpectations(t)
 	mockUserClient.AssertNumberOfCalls(t, "FindById", 1)
@@ -56,54 +65,108 @@ func Test_Create_InsertFailed(t *testing.T) {
 }
 
 func Test_Create_ImageSaveFailed(t *testing.T) {
-	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	images := NewFakeImageStore()
+	images.PutErr = fmt.Errorf("some error")
+	images.FailAfter = 1
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images))
 
 	dto := DefaultRoomCreateDTO
+	dto.PhotosPayload = []string{
+		SMALL_IMG,
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString(encodeTestImage(t, "png", 2, 2)),
+	}
 
-	mockRepo.On("Create", mock.AnythingOfType("*internal.Room")).Return(nil)
-	mockRepo.On("Delete", mock.AnythingOfType("*internal.Room")).Return(nil)
 	mockUserClient.On("FindById", context.Background(), mock.AnythingOfType("uint")).Return(DefaultUser_Host, nil)
 
-	util.SaveImageB64 = func(base64Image string, filename string) (string, string, error) {
-		return "", "", fmt.Errorf("some error")
-	}
-
 	roomGot, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
 
 	assert.Error(t, err)
 	assert.Nil(t, roomGot)
-	mockRepo.AssertNumberOfCalls(t, "Create", 1)
-	mockRepo.AssertNumberOfCalls(t, "Update", 0)
-	mockRepo.AssertNumberOfCalls(t, "Delete", 1)
+	assert.Empty(t, images.Keys(), "the photo saved before the failure is cleaned up")
+	mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 0)
 	mockRepo.AssertExpectations(t)
 	mockUserClient.AssertNumberOfCalls(t, "FindById", 1)
 	mockUserClient.AssertExpectations(t)
 }
 
-func Test_Create_UpdateFailed(t *testing.T) {
-	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+func Test_Create_InsertFailedKeepsUploadedPhotos(t *testing.T) {
+	images := NewFakeImageStore()
+	images.Images["photo-uploaded.jpg"] = []byte("jpeg")
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithPhotoRepo(photoRepo))
 
-	room := DefaultRoom
 	dto := DefaultRoomCreateDTO
+	dto.PhotoIDs = []uint{3}
 
-	mockRepo.On("Create", mock.AnythingOfType("*internal.Room")).Return(nil)
-	mockRepo.On("Update", mock.AnythingOfType("*internal.Room")).Return(fmt.Errorf("error"))
-	mockRepo.On("Delete", mock.AnythingOfType("*internal.Room")).Return(nil)
+	photoRepo.On("FindByIds", []uint{3}).Return([]internal.RoomPhoto{
+		{ID: 3, Key: "photo-uploaded.jpg", UploaderID: DefaultUser_Host.Id},
+	}, nil)
+	mockRepo.On("CreateWithPhotos", mock.AnythingOfType("*internal.Room"), []uint{3}).Return(fmt.Errorf("error"))
 	mockUserClient.On("FindById", context.Background(), mock.AnythingOfType("uint")).Return(DefaultUser_Host, nil)
-	util.SaveImageB64 = func(base64Image string, filename string) (string, string, error) {
-		return "foo/" + room.Photos[0], room.Photos[0], nil
-	}
 
 	roomGot, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
 
 	assert.Error(t, err)
 	assert.Nil(t, roomGot)
-	mockRepo.AssertNumberOfCalls(t, "Create", 1)
-	mockRepo.AssertNumberOfCalls(t, "Update", 1)
-	mockRepo.AssertNumberOfCalls(t, "Delete", 1)
+	assert.Equal(t, []string{"photo-uploaded.jpg"}, images.Keys(), "uploaded photos stay staged")
 	mockRepo.AssertExpectations(t)
-	mockUserClient.AssertNumberOfCalls(t, "FindById", 1)
-	mockUserClient.AssertExpectations(t)
+}
+
+func Test_Create_SamePictureIsStoredOnce(t *testing.T) {
+	images := NewFakeImageStore()
+	blobs := NewFakePhotoBlobRepo()
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithBlobRepo(blobs))
+
+	mockRepo.On("CreateWithPhotos", mock.AnythingOfType("*internal.Room"), []uint(nil)).Return(nil).Once()
+	mockRepo.On("CreateWithPhotos", mock.AnythingOfType("*internal.Room"), []uint(nil)).Return(fmt.Errorf("db error")).Once()
+	mockUserClient.On("FindById", context.Background(), mock.AnythingOfType("uint")).Return(DefaultUser_Host, nil)
+
+	first, err := svc.Create(context.Background(), DefaultUser_Host.Id, DefaultRoomCreateDTO)
+	assert.NoError(t, err)
+	key := first.Photos[0].Key
+	assert.Equal(t, 1, blobs.RefCount(key))
+
+	_, err = svc.Create(context.Background(), DefaultUser_Host.Id, DefaultRoomCreateDTO)
+	assert.Error(t, err)
+
+	assert.Equal(t, []string{key}, images.Keys(), "the failed room does not delete the image of the first one")
+	assert.Equal(t, 1, blobs.RefCount(key))
+}
+
+func Test_Create_SamePictureTwice(t *testing.T) {
+	images := NewFakeImageStore()
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithPhotoRepo(photoRepo))
+
+	data, _ := util.DecodeImageB64(SMALL_IMG)
+	image, err := util.ProcessImage(data)
+	assert.NoError(t, err)
+	sum := sha256.Sum256(image.Data)
+	uploadedKey := hex.EncodeToString(sum[:]) + image.Extension
+
+	tests := map[string]internal.CreateRoomDTO{
+		"sent twice":             {PhotosPayload: []string{SMALL_IMG, SMALL_IMG}},
+		"sent and uploaded":      {PhotosPayload: []string{SMALL_IMG}, PhotoIDs: []uint{3}},
+		"uploaded twice as well": {PhotoIDs: []uint{3, 4}},
+	}
+	photoRepo.On("FindByIds", mock.Anything).Return([]internal.RoomPhoto{
+		{ID: 3, Key: uploadedKey, UploaderID: DefaultUser_Host.Id},
+		{ID: 4, Key: uploadedKey, UploaderID: DefaultUser_Host.Id},
+	}, nil)
+	mockUserClient.On("FindById", context.Background(), mock.AnythingOfType("uint")).Return(DefaultUser_Host, nil)
+
+	for name, photos := range tests {
+		dto := DefaultRoomCreateDTO
+		dto.PhotosPayload = photos.PhotosPayload
+		dto.PhotoIDs = photos.PhotoIDs
+
+		_, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
+
+		code, _ := internal.MapErrorToHTTP(err)
+		assert.Equal(t, http.StatusBadRequest, code, name)
+	}
+	assert.Empty(t, images.Keys())
+	mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 0)
 }
 
 func Test_Create_HostNotFound(t *testing.T) {
@@ -116,7 +179,7 @@ func Test_Create_HostNotFound(t *testing.T) {
 
 	assert.Error(t, err)
 	assert.Nil(t, roomGot)
-	mockRepo.AssertNumberOfCalls(t, "Create", 0)
+	mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 0)
 	mockRepo.AssertNumberOfCalls(t, "Update", 0)
 	mockRepo.AssertNumberOfCalls(t, "Delete", 0)
 	mockRepo.AssertExpectations(t)
@@ -134,10 +197,22 @@ func Test_Create_HostHasBadRole(t *testing.T) {
 
 	assert.Error(t, err)
 	assert.Nil(t, roomGot)
-	mockRepo.AssertNumberOfCalls(t, "Create", 0)
+	mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 0)
 	mockRepo.AssertNumberOfCalls(t, "Update", 0)
 	mockRepo.AssertNumberOfCalls(t, "Delete", 0)
 	mockRepo.AssertExpectations(t)
 	mockUserClient.AssertNumberOfCalls(t, "FindById", 1)
 	mockUserClient.AssertExpectations(t)
 }
+
+func Test_Create_UnknownUserIsNotFound(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(nil, fmt.Errorf("%w: %d", userclient.ErrUserNotFound, DefaultUser_Host.Id))
+	roomGot, err := svc.Create(context.Background(), DefaultUser_Host.Id, DefaultRoomCreateDTO)
+
+	code, _ := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusNotFound, code)
+	assert.Nil(t, roomGot)
+	mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 0)
+}
@@ -433,7 +433,7 @@ func Test_PreparePaginatedResult_Success(t *testing.T) {
 }
 
 func Test_PreparePaginatedResult_OutOfMargin(t *testing.T) {
-	// Show last page result if page number exceeds total
+	// A page past the last one is empty, not the last page again
 	svc, _, _, _, _ := CreateTestRoomService()
 
 	pageNumber := uint(99999)
@@ -450,7 +450,8 @@ func Test_PreparePaginatedResult_OutOfMargin(t *testing.T) {
 	assert.Equal(t, pageSize, resultInfo.PageSize)
 	assert.Equal(t, uint(2), resultInfo.TotalPages)
 	assert.Equal(t, uint(15), resultInfo.TotalHits)
-	assert.Equal(t, int(5), len(hitsResult))
+	assert.Empty(t, hitsResult)
+	assert.NotNil(t, hitsResult)
 }
 
 func Test_PreparePaginatedResult_LastPage(t *testing.T) {
@@ -496,7 +497,7 @@ func Test_FindAvailableRooms_DbError(t *testing.T) {
 
 	d := *DefaultRoomsQueryDTO
 
-	mockRepo.On("FindByFilters", d.GuestsNumber, d.Address).Return(nil, fmt.Errorf("db error"))
+	mockRepo.On("FindByFilters", d.GuestsNumber, d.Address, d.Query).Return(nil, fmt.Errorf("db error"))
 
 	roomsGot, infoGot, err := svc.FindAvailableRooms(context.Background(), d)
 
@@ -518,6 +519,7 @@ func Test_FindAvailableRooms_Success(t *testing.T) {
 		Address:   "address1",
 		MinGuests: 2,
 		MaxGuests: 5,
+		Status:    internal.RoomPublished,
 	}
 	room2 := internal.Room{
 		ID:        2,
@@ -526,6 +528,7 @@ func Test_FindAvailableRooms_Success(t *testing.T) {
 		Address:   "address2",
 		MinGuests: 1,
 		MaxGuests: 4,
+		Status:    internal.RoomPublished,
 	}
 	rooms = append(rooms, room1, room2)
 
@@ -624,7 +627,7 @@ func Test_FindAvailableRooms_Success(t *testing.T) {
 
 	// [1] none address
 	query.Address = "none"
-	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address).Return(nil, nil)
+	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address, query.Query).Return(nil, nil)
 
 	roomsGot, infoGot, err := svc.FindAvailableRooms(context.Background(), query)
 
@@ -641,7 +644,7 @@ func Test_FindAvailableRooms_Success(t *testing.T) {
 	query.PageNumber = uint(5)
 	query.PageSize = uint(2)
 	query.Address = "none"
-	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address).Return(nil, nil)
+	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address, query.Query).Return(nil, nil)
 
 	roomsGot, infoGot, err = svc.FindAvailableRooms(context.Background(), query)
 
@@ -658,7 +661,7 @@ func Test_FindAvailableRooms_Success(t *testing.T) {
 	query.PageSize = 1
 	query.PageNumber = 1
 	query.Address = "address"
-	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address).Return(rooms, nil)
+	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address, query.Query).Return(rooms, nil)
 	mockAvailRepo.On("FindCurrentListOfRoom", room1.ID).Return(&availRules1, nil)
 	mockAvailRepo.On("FindCurrentListOfRoom", room2.ID).Return(&availRules2, nil)
 	mockPriceRepo.On("FindCurrentListOfRoom", room1.ID).Return(&priceRules1, nil)
@@ -679,7 +682,7 @@ func Test_FindAvailableRooms_Success(t *testing.T) {
 	query.PageNumber = 1
 	query.DateFrom = time.Date(2025, 8, 6, 0, 0, 0, 0, time.UTC)
 	query.DateTo = time.Date(2025, 8, 7, 0, 0, 0, 0, time.UTC)
-	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address).Return(rooms, nil)
+	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address, query.Query).Return(rooms, nil)
 	mockAvailRepo.On("FindCurrentListOfRoom", room1.ID).Return(&availRules1, nil)
 	mockAvailRepo.On("FindCurrentListOfRoom", room2.ID).Return(&availRules2, nil)
 	mockPriceRepo.On("FindCurrentListOfRoom", room1.ID).Return(&priceRules1, nil)
@@ -708,6 +711,7 @@ func Test_ExcludeDeletedRooms_Success(t *testing.T) {
 		MinGuests: 2,
 		MaxGuests: 5,
 		Deleted:   false,
+		Status:    internal.RoomPublished,
 	}
 	room2 := internal.Room{
 		ID:        2,
@@ -717,6 +721,7 @@ func Test_ExcludeDeletedRooms_Success(t *testing.T) {
 		MinGuests: 1,
 		MaxGuests: 4,
 		Deleted:   true,
+		Status:    internal.RoomPublished,
 	}
 	rooms = append(rooms, room1, room2)
 
@@ -725,3 +730,128 @@ func Test_ExcludeDeletedRooms_Success(t *testing.T) {
 	assert.Equal(t, 1, len(roomsGot))
 	assert.Equal(t, room1, roomsGot[0])
 }
+
+func Test_FindAvailableRooms_FullTextQuery_PassedToRepo(t *testing.T) {
+	svc, mockRepo, _, _, _ := CreateTestRoomService()
+
+	d := *DefaultRoomsQueryDTO
+	d.Query = "  sea view jacuzzi "
+
+	mockRepo.On("FindByFilters", d.GuestsNumber, d.Address, "sea view jacuzzi").Return(nil, nil)
+
+	roomsGot, infoGot, err := svc.FindAvailableRooms(context.Background(), d)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, len(roomsGot))
+	assert.Equal(t, uint(0), infoGot.TotalHits)
+	mockRepo.AssertNumberOfCalls(t, "FindByFilters", 1)
+	mockRepo.AssertExpectations(t)
+}
+
+func Test_FindAvailableRooms_SortByPrice(t *testing.T) {
+	svc, mockRepo, mockAvailRepo, mockPriceRepo, _ := CreateTestRoomService()
+
+	// The repository returns rooms in relevance order, the expensive room first.
+	room1 := internal.Room{ID: 1, Name: "expensive", MinGuests: 1, MaxGuests: 4, Rank: 0.9, Status: internal.RoomPublished}
+	room2 := internal.Room{ID: 2, Name: "cheap", MinGuests: 1, MaxGuests: 4, Rank: 0.1, Status: internal.RoomPublished}
+	rooms := []internal.Room{room1, room2}
+
+	avail := func(roomId uint) *internal.RoomAvailabilityList {
+		return &internal.RoomAvailabilityList{
+			RoomID: roomId,
+			Items: []internal.RoomAvailabilityItem{{
+				DateFrom:  time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+				DateTo:    time.Date(2025, 8, 30, 0, 0, 0, 0, time.UTC),
+				Available: true,
+			}},
+		}
+	}
+	price := func(roomId uint, basePrice uint) *internal.RoomPriceList {
+		return &internal.RoomPriceList{RoomID: roomId, BasePrice: basePrice}
+	}
+
+	query := *DefaultRoomsQueryDTO
+	query.Query = "room"
+
+	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address, query.Query).Return(rooms, nil)
+	mockAvailRepo.On("FindCurrentListOfRoom", room1.ID).Return(avail(room1.ID), nil)
+	mockAvailRepo.On("FindCurrentListOfRoom", room2.ID).Return(avail(room2.ID), nil)
+	mockPriceRepo.On("FindCurrentListOfRoom", room1.ID).Return(price(room1.ID, 500), nil)
+	mockPriceRepo.On("FindCurrentListOfRoom", room2.ID).Return(price(room2.ID, 100), nil)
+
+	// [1] Relevance keeps the repository order.
+	query.SortBy = internal.SortByRelevance
+	roomsGot, _, err := svc.FindAvailableRooms(context.Background(), query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(roomsGot))
+	assert.Equal(t, room1.ID, roomsGot[0].ID)
+	assert.Equal(t, room2.ID, roomsGot[1].ID)
+
+	// [2] Price puts the cheapest room first.
+	query.SortBy = internal.SortByPrice
+	roomsGot, _, err = svc.FindAvailableRooms(context.Background(), query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(roomsGot))
+	assert.Equal(t, room2.ID, roomsGot[0].ID)
+	assert.Equal(t, room1.ID, roomsGot[1].ID)
+}
+
+func Test_FindAvailableRooms_ManyRooms_KeepsOrder(t *testing.T) {
+	svc, mockRepo, mockAvailRepo, mockPriceRepo, _ := CreateTestRoomService()
+
+	var rooms []internal.Room
+	for id := uint(1); id <= 50; id++ {
+		rooms = append(rooms, internal.Room{ID: id, MinGuests: 1, MaxGuests: 5, Status: internal.RoomPublished})
+	}
+
+	query := *DefaultRoomsQueryDTO
+	query.PageSize = 100
+
+	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address, query.Query).Return(rooms, nil)
+	for _, room := range rooms {
+		// Every third room has no availability list and is not a hit.
+		if room.ID%3 == 0 {
+			mockAvailRepo.On("FindCurrentListOfRoom", room.ID).Return(nil, fmt.Errorf("not found"))
+			continue
+		}
+		avail := *DefaultAvailabilityList
+		avail.Items = []internal.RoomAvailabilityItem{{
+			DateFrom:  query.DateFrom,
+			DateTo:    query.DateTo,
+			Available: true,
+		}}
+		mockAvailRepo.On("FindCurrentListOfRoom", room.ID).Return(&avail, nil)
+		mockPriceRepo.On("FindCurrentListOfRoom", room.ID).Return(DefaultPriceList, nil)
+	}
+
+	roomsGot, infoGot, err := svc.FindAvailableRooms(context.Background(), query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(34), infoGot.TotalHits)
+	var prevID uint
+	for _, hit := range roomsGot {
+		assert.NotZero(t, hit.ID%3)
+		assert.Greater(t, hit.ID, prevID)
+		prevID = hit.ID
+	}
+}
+
+func Test_FindAvailableRooms_Cancelled(t *testing.T) {
+	svc, mockRepo, mockAvailRepo, mockPriceRepo, _ := CreateTestRoomService()
+
+	query := *DefaultRoomsQueryDTO
+	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address, query.Query).Return([]internal.Room{*DefaultRoom}, nil)
+
+	ctx, cancel := context.WithCancel(context.Background())
+	cancel()
+
+	roomsGot, infoGot, err := svc.FindAvailableRooms(ctx, query)
+
+	assert.ErrorIs(t, err, context.Canceled)
+	assert.Nil(t, roomsGot)
+	assert.Nil(t, infoGot)
+	mockAvailRepo.AssertNumberOfCalls(t, "FindCurrentListOfRoom", 0)
+	mockPriceRepo.AssertNumberOfCalls(t, "FindCurrentListOfRoom", 0)
+}
@@ -1,12 +1,17 @@
 package test
 
 import (
+	"bookem-room-service/client/userclient"
 	"bookem-room-service/internal"
 	"context"
+	"encoding/json"
 	"fmt"
+	"net/http"
+	"net/http/httptest"
 	"testing"
 
 	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
 )
 
 func Test_FindByHost_Success(t *testing.T) {
@@ -26,7 +31,7 @@ func Test_FindByHost_Success(t *testing.T) {
 	mockUserClient.On("FindById", context.Background(), host.Id).Return(host, nil)
 	mockRepo.On("FindByHost", host.Id).Return(rooms, nil)
 
-	roomsGot, err := svc.FindByHost(context.Background(), host.Id)
+	roomsGot, _, err := svc.FindByHost(context.Background(), nil, host.Id)
 
 	assert.NoError(t, err)
 	assert.Equal(t, rooms, roomsGot)
@@ -42,7 +47,7 @@ func Test_FindByHost_UserNotFound(t *testing.T) {
 	hostId := uint(123)
 	mockUserClient.On("FindById", context.Background(), hostId).Return(nil, fmt.Errorf("user not found"))
 
-	roomsGot, err := svc.FindByHost(context.Background(), hostId)
+	roomsGot, _, err := svc.FindByHost(context.Background(), nil, hostId)
 
 	assert.Error(t, err)
 	assert.Nil(t, roomsGot)
@@ -56,7 +61,7 @@ func Test_FindByHost_UserNotHost(t *testing.T) {
 	notHost := DefaultUser_Guest
 	mockUserClient.On("FindById", context.Background(), notHost.Id).Return(notHost, nil)
 
-	roomsGot, err := svc.FindByHost(context.Background(), notHost.Id)
+	roomsGot, _, err := svc.FindByHost(context.Background(), nil, notHost.Id)
 
 	assert.Error(t, err)
 	assert.Nil(t, roomsGot)
@@ -72,7 +77,7 @@ func Test_FindByHost_DbError(t *testing.T) {
 	mockUserClient.On("FindById", context.Background(), host.Id).Return(host, nil)
 	mockRepo.On("FindByHost", host.Id).Return(nil, fmt.Errorf("db error"))
 
-	roomsGot, err := svc.FindByHost(context.Background(), host.Id)
+	roomsGot, _, err := svc.FindByHost(context.Background(), nil, host.Id)
 
 	assert.Error(t, err)
 	assert.Nil(t, roomsGot)
@@ -81,3 +86,107 @@ func Test_FindByHost_DbError(t *testing.T) {
 	mockRepo.AssertNumberOfCalls(t, "FindByHost", 1)
 	mockRepo.AssertExpectations(t)
 }
+
+func Test_FindByHost_UserServiceUnavailable(t *testing.T) {
+	svc, _, _, _, mockUserClient := CreateTestRoomService()
+
+	hostId := uint(123)
+	mockUserClient.On("FindById", context.Background(), hostId).Return(nil, fmt.Errorf("%w: status 502", userclient.ErrUserServiceUnavailable))
+
+	roomsGot, _, err := svc.FindByHost(context.Background(), nil, hostId)
+
+	code, _ := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Nil(t, roomsGot)
+	mockUserClient.AssertExpectations(t)
+}
+
+// hostRooms returns a published, a draft and a deleted room of the host.
+func hostRooms() []internal.Room {
+	published := *DefaultRoom
+	published.StatusComment = "Looks great"
+	draft := *DefaultRoom
+	draft.ID = 2
+	draft.Status = internal.RoomDraft
+	deleted := *DefaultRoom
+	deleted.ID = 3
+	deleted.Deleted = true
+	return []internal.Room{published, draft, deleted}
+}
+
+func Test_FindByHost_OthersSeePublishedOnly(t *testing.T) {
+	rooms := hostRooms()
+	callers := map[string]*uint{"anonymous": nil, "guest": &DefaultUser_Guest.Id}
+
+	for name, callerID := range callers {
+		svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+		mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+		mockUserClient.On("FindById", context.Background(), DefaultUser_Guest.Id).Return(DefaultUser_Guest, nil)
+		mockRepo.On("FindByHost", DefaultUser_Host.Id).Return(rooms, nil)
+
+		roomsGot, seesAll, err := svc.FindByHost(context.Background(), callerID, DefaultUser_Host.Id)
+
+		assert.NoError(t, err, name)
+		assert.Equal(t, rooms[:1], roomsGot, name)
+		assert.False(t, seesAll, name)
+	}
+}
+
+func Test_FindByHost_HostAndAdminSeeAll(t *testing.T) {
+	rooms := hostRooms()
+	callers := map[string]uint{"host": DefaultUser_Host.Id, "admin": DefaultUser_Admin.Id}
+
+	for name, callerID := range callers {
+		svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+		mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+		mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+		mockRepo.On("FindByHost", DefaultUser_Host.Id).Return(rooms, nil)
+
+		roomsGot, seesAll, err := svc.FindByHost(context.Background(), &callerID, DefaultUser_Host.Id)
+
+		assert.NoError(t, err, name)
+		assert.Equal(t, rooms, roomsGot, name)
+		assert.True(t, seesAll, name)
+	}
+}
+
+func Test_FindByHost_StatusCommentIsForTheHost(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	send := newUploadServer(t, svc, internal.UploadLimits{MaxFileBytes: 1, MaxRequestBytes: 1, MaxFiles: 1})
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+	mockRepo.On("FindByHost", DefaultUser_Host.Id).Return(hostRooms(), nil)
+	path := fmt.Sprintf("/api/host/%d", DefaultUser_Host.Id)
+
+	w := send(httptest.NewRequest(http.MethodGet, path, nil))
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "statusComment")
+	assert.NotContains(t, w.Body.String(), "Looks great")
+
+	req := httptest.NewRequest(http.MethodGet, path, nil)
+	req.Header.Set("Authorization", "Bearer host")
+	w = send(req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var rooms []internal.ManagedRoomDTO
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &rooms))
+	assert.Len(t, rooms, 3)
+	assert.Equal(t, "Looks great", rooms[0].StatusComment)
+}
+
+// The token of the caller says admin, but to the user service the caller is
+// a host, of other rooms: the rooms and the response both follow the user
+// service.
+func Test_FindByHost_StatusCommentFollowsTheUserService(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	send := newUploadServer(t, svc, internal.UploadLimits{MaxFileBytes: 1, MaxRequestBytes: 1, MaxFiles: 1})
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+	mockRepo.On("FindByHost", DefaultUser_Guest.Id).Return(hostRooms(), nil)
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Guest.Id).Return(DefaultUser_Host, nil)
+
+	req := httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/host/%d", DefaultUser_Guest.Id), nil)
+	req.Header.Set("Authorization", "Bearer admin")
+	w := send(req)
+
+	assert.Equal(t, http.StatusOK, w.Code)
+	assert.NotContains(t, w.Body.String(), "statusComment")
+}
@@ -0,0 +1,167 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	"context"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// flexibleStayRules makes a room available from Aug 1 to Aug 30 except for
+// Aug 10-12, with a flat base price of 100 and Aug 1-5 priced at 300.
+func flexibleStayRules(roomId uint) (*internal.RoomAvailabilityList, *internal.RoomPriceList) {
+	avail := &internal.RoomAvailabilityList{
+		ID:     1,
+		RoomID: roomId,
+		Items: []internal.RoomAvailabilityItem{
+			{
+				ID:        1,
+				DateFrom:  time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+				DateTo:    time.Date(2025, 8, 30, 0, 0, 0, 0, time.UTC),
+				Available: true,
+			},
+			{
+				ID:        2,
+				DateFrom:  time.Date(2025, 8, 10, 0, 0, 0, 0, time.UTC),
+				DateTo:    time.Date(2025, 8, 12, 0, 0, 0, 0, time.UTC),
+				Available: false,
+			},
+		},
+	}
+
+	price := &internal.RoomPriceList{
+		ID:        1,
+		RoomID:    roomId,
+		BasePrice: 100,
+		PerGuest:  false,
+		Items: []internal.RoomPriceItem{
+			{
+				ID:       1,
+				DateFrom: time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC),
+				DateTo:   time.Date(2025, 8, 5, 0, 0, 0, 0, time.UTC),
+				Price:    300,
+			},
+		},
+	}
+
+	return avail, price
+}
+
+func Test_FindFlexibleStay_Cheapest(t *testing.T) {
+	svc, _, mockAvailRepo, mockPriceRepo, _ := CreateTestRoomService()
+	roomId := uint(1)
+	avail, price := flexibleStayRules(roomId)
+
+	mockAvailRepo.On("FindCurrentListOfRoom", roomId).Return(avail, nil)
+	mockPriceRepo.On("FindCurrentListOfRoom", roomId).Return(price, nil)
+
+	from := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 8, 14, 0, 0, 0, 0, time.UTC)
+
+	stay, err := svc.FindFlexibleStay(context.Background(), roomId, from, to, 3, 2, internal.FlexibleModeCheapest)
+
+	// Aug 6-8 is the first stay that avoids both the expensive days and the
+	// unavailable days.
+	assert.NoError(t, err)
+	assert.NotNil(t, stay)
+	assert.Equal(t, time.Date(2025, 8, 6, 0, 0, 0, 0, time.UTC), stay.DateFrom)
+	assert.Equal(t, time.Date(2025, 8, 8, 0, 0, 0, 0, time.UTC), stay.DateTo)
+	assert.Equal(t, float32(300), stay.TotalPrice)
+	assert.Equal(t, false, stay.PerGuest)
+}
+
+func Test_FindFlexibleStay_Earliest(t *testing.T) {
+	svc, _, mockAvailRepo, mockPriceRepo, _ := CreateTestRoomService()
+	roomId := uint(1)
+	avail, price := flexibleStayRules(roomId)
+
+	mockAvailRepo.On("FindCurrentListOfRoom", roomId).Return(avail, nil)
+	mockPriceRepo.On("FindCurrentListOfRoom", roomId).Return(price, nil)
+
+	from := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 8, 14, 0, 0, 0, 0, time.UTC)
+
+	stay, err := svc.FindFlexibleStay(context.Background(), roomId, from, to, 3, 2, internal.FlexibleModeEarliest)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, stay)
+	assert.Equal(t, time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC), stay.DateFrom)
+	assert.Equal(t, time.Date(2025, 8, 3, 0, 0, 0, 0, time.UTC), stay.DateTo)
+	assert.Equal(t, float32(900), stay.TotalPrice)
+}
+
+func Test_FindFlexibleStay_NoFreeStay(t *testing.T) {
+	svc, _, mockAvailRepo, mockPriceRepo, _ := CreateTestRoomService()
+	roomId := uint(1)
+	avail, price := flexibleStayRules(roomId)
+
+	mockAvailRepo.On("FindCurrentListOfRoom", roomId).Return(avail, nil)
+	mockPriceRepo.On("FindCurrentListOfRoom", roomId).Return(price, nil)
+
+	// Every 2-day stay in Aug 9-13 touches Aug 10-12.
+	from := time.Date(2025, 8, 9, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 8, 13, 0, 0, 0, 0, time.UTC)
+
+	stay, err := svc.FindFlexibleStay(context.Background(), roomId, from, to, 2, 2, internal.FlexibleModeCheapest)
+
+	assert.NoError(t, err)
+	assert.Nil(t, stay)
+}
+
+func Test_FindFlexibleStay_NoAvailabilityList(t *testing.T) {
+	svc, _, mockAvailRepo, mockPriceRepo, _ := CreateTestRoomService()
+	roomId := uint(1)
+
+	mockAvailRepo.On("FindCurrentListOfRoom", roomId).Return(nil, fmt.Errorf("not found"))
+
+	from := time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	to := time.Date(2025, 8, 14, 0, 0, 0, 0, time.UTC)
+
+	stay, err := svc.FindFlexibleStay(context.Background(), roomId, from, to, 3, 2, internal.FlexibleModeCheapest)
+
+	assert.NoError(t, err)
+	assert.Nil(t, stay)
+	mockPriceRepo.AssertNumberOfCalls(t, "FindCurrentListOfRoom", 0)
+}
+
+func Test_FindAvailableRooms_Flexible_StayLongerThanWindow(t *testing.T) {
+	svc, mockRepo, _, _, _ := CreateTestRoomService()
+
+	query := *DefaultRoomsQueryDTO
+	query.StayLength = 5
+
+	roomsGot, infoGot, err := svc.FindAvailableRooms(context.Background(), query)
+
+	assert.Error(t, err)
+	assert.Nil(t, roomsGot)
+	assert.Nil(t, infoGot)
+	mockRepo.AssertNumberOfCalls(t, "FindByFilters", 0)
+}
+
+func Test_FindAvailableRooms_Flexible_Success(t *testing.T) {
+	svc, mockRepo, mockAvailRepo, mockPriceRepo, _ := CreateTestRoomService()
+	room := internal.Room{ID: 1, Name: "room", MinGuests: 1, MaxGuests: 4, Status: internal.RoomPublished}
+	avail, price := flexibleStayRules(room.ID)
+
+	query := *DefaultRoomsQueryDTO
+	query.DateFrom = time.Date(2025, 8, 1, 0, 0, 0, 0, time.UTC)
+	query.DateTo = time.Date(2025, 8, 14, 0, 0, 0, 0, time.UTC)
+	query.StayLength = 3
+
+	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address, query.Query).Return([]internal.Room{room}, nil)
+	mockAvailRepo.On("FindCurrentListOfRoom", room.ID).Return(avail, nil)
+	mockPriceRepo.On("FindCurrentListOfRoom", room.ID).Return(price, nil)
+
+	roomsGot, infoGot, err := svc.FindAvailableRooms(context.Background(), query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, uint(1), infoGot.TotalHits)
+	assert.Equal(t, 1, len(roomsGot))
+	assert.Equal(t, time.Date(2025, 8, 6, 0, 0, 0, 0, time.UTC), *roomsGot[0].DateFrom)
+	assert.Equal(t, time.Date(2025, 8, 8, 0, 0, 0, 0, time.UTC), *roomsGot[0].DateTo)
+	assert.Equal(t, float32(300), roomsGot[0].TotalPrice)
+	assert.Equal(t, float32(100), roomsGot[0].UnitPrice)
+}
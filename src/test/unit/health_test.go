@@ -0,0 +1,114 @@
+package test
+
+import (
+	"bookem-room-service/client/userclient"
+	"bookem-room-service/config"
+	"bookem-room-service/util"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+func serveHealth(health *util.Health, path string) (int, util.HealthReport) {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.GET("/livez", health.LivenessHandler())
+	server.GET("/readyz", health.ReadinessHandler())
+
+	w := httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, path, nil))
+
+	var report util.HealthReport
+	json.Unmarshal(w.Body.Bytes(), &report)
+	return w.Code, report
+}
+
+func Test_Readyz_AllChecksPass(t *testing.T) {
+	health := util.NewHealth(time.Second)
+	health.AddCheck("db", func(ctx context.Context) error { return nil })
+	health.AddCheck("images", func(ctx context.Context) error { return nil })
+
+	code, report := serveHealth(health, "/readyz")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, util.HealthStatusOK, report.Status)
+	assert.Equal(t, util.HealthStatusOK, report.Checks["db"].Status)
+	assert.Equal(t, util.HealthStatusOK, report.Checks["images"].Status)
+}
+
+func Test_Readyz_FailingCheck(t *testing.T) {
+	health := util.NewHealth(time.Second)
+	health.AddCheck("db", func(ctx context.Context) error { return nil })
+	health.AddCheck("user_service", func(ctx context.Context) error { return fmt.Errorf("connection refused") })
+
+	code, report := serveHealth(health, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, util.HealthStatusUnavailable, report.Status)
+	assert.Equal(t, util.HealthStatusOK, report.Checks["db"].Status)
+	assert.Equal(t, util.HealthStatusUnavailable, report.Checks["user_service"].Status)
+	assert.Equal(t, "connection refused", report.Checks["user_service"].Error)
+}
+
+func Test_Readyz_SlowCheckTimesOut(t *testing.T) {
+	health := util.NewHealth(10 * time.Millisecond)
+	health.AddCheck("db", func(ctx context.Context) error {
+		<-ctx.Done()
+		return nil
+	})
+
+	code, report := serveHealth(health, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Contains(t, report.Checks["db"].Error, "deadline exceeded")
+}
+
+func Test_Readyz_ShuttingDown(t *testing.T) {
+	health := util.NewHealth(time.Second)
+	health.AddCheck("db", func(ctx context.Context) error { return nil })
+	health.ShuttingDown()
+
+	code, report := serveHealth(health, "/readyz")
+
+	assert.Equal(t, http.StatusServiceUnavailable, code)
+	assert.Equal(t, "shutting down", report.Reason)
+
+	// The process is still alive while draining.
+	code, _ = serveHealth(health, "/livez")
+	assert.Equal(t, http.StatusOK, code)
+}
+
+func Test_Livez_IgnoresDependencies(t *testing.T) {
+	health := util.NewHealth(time.Second)
+	health.AddCheck("db", func(ctx context.Context) error { return fmt.Errorf("down") })
+
+	code, report := serveHealth(health, "/livez")
+
+	assert.Equal(t, http.StatusOK, code)
+	assert.Equal(t, util.HealthStatusOK, report.Status)
+}
+
+func Test_UserClient_Ping(t *testing.T) {
+	status := http.StatusOK
+	var path string
+	userService := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		path = r.URL.Path
+		w.WriteHeader(status)
+	}))
+	defer userService.Close()
+
+	client := userclient.NewUserClient(config.UserServiceConfig{BaseURL: userService.URL + "/api"}, nil)
+
+	assert.NoError(t, client.Ping(context.Background()))
+	assert.Equal(t, "/healthz", path)
+
+	status = http.StatusServiceUnavailable
+	assert.Error(t, client.Ping(context.Background()))
+}
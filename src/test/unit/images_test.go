@@ -0,0 +1,222 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	"bookem-room-service/util"
+	"bytes"
+	"context"
+	"encoding/base64"
+	"encoding/binary"
+	"fmt"
+	"hash/crc32"
+	"image"
+	"image/color"
+	"image/gif"
+	"image/jpeg"
+	"image/png"
+	"maps"
+	"net/http"
+	"slices"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+)
+
+func encodeTestImage(t *testing.T, format string, width int, height int) []byte {
+	img := image.NewRGBA(image.Rect(0, 0, width, height))
+	img.Set(0, 0, color.RGBA{R: 255, A: 255})
+
+	var buf bytes.Buffer
+	var err error
+	switch format {
+	case "png":
+		err = png.Encode(&buf, img)
+	case "jpeg":
+		err = jpeg.Encode(&buf, img, nil)
+	case "gif":
+		err = gif.Encode(&buf, img, nil)
+	}
+	assert.NoError(t, err)
+	return buf.Bytes()
+}
+
+// withExif inserts an APP1 (EXIF) segment holding payload right after the
+// start-of-image marker of a JPEG.
+func withExif(data []byte, payload string) []byte {
+	segment := append([]byte("Exif\x00\x00"), payload...)
+	header := []byte{0xFF, 0xE1, 0, 0}
+	binary.BigEndian.PutUint16(header[2:], uint16(len(segment)+2))
+
+	out := append([]byte{}, data[:2]...)
+	out = append(out, header...)
+	out = append(out, segment...)
+	return append(out, data[2:]...)
+}
+
+func withImageLimits(t *testing.T, limits util.ImageLimits) {
+	util.SetImageLimits(limits)
+	t.Cleanup(func() {
+		util.SetImageLimits(util.ImageLimits{MaxBytes: 10 << 20, MaxWidth: 8000, MaxHeight: 8000})
+	})
+}
+
+func Test_ProcessImage_Png(t *testing.T) {
+	image, err := util.ProcessImage(encodeTestImage(t, "png", 4, 3))
+
+	assert.NoError(t, err)
+	assert.Equal(t, "image/png", image.ContentType)
+	assert.Equal(t, ".png", image.Extension)
+	assert.Equal(t, 4, image.Width)
+	assert.Equal(t, 3, image.Height)
+}
+
+func Test_ProcessImage_StripsExif(t *testing.T) {
+	data := withExif(encodeTestImage(t, "jpeg", 4, 4), "GPSLatitude=45.2671")
+
+	image, err := util.ProcessImage(data)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "image/jpeg", image.ContentType)
+	assert.Equal(t, ".jpg", image.Extension)
+	assert.NotContains(t, string(image.Data), "Exif")
+	assert.NotContains(t, string(image.Data), "GPSLatitude")
+}
+
+func Test_ProcessImage_DropsTrailingData(t *testing.T) {
+	data := append(encodeTestImage(t, "png", 2, 2), []byte("<?php system($_GET['c']); ?>")...)
+
+	image, err := util.ProcessImage(data)
+
+	assert.NoError(t, err)
+	assert.NotContains(t, string(image.Data), "<?php")
+}
+
+func Test_ProcessImage_Rejects(t *testing.T) {
+	withImageLimits(t, util.ImageLimits{MaxBytes: 4096, MaxWidth: 100, MaxHeight: 50})
+
+	tests := map[string][]byte{
+		"unsupported format": encodeTestImage(t, "gif", 2, 2),
+		"not an image":       []byte("#!/bin/sh\necho hello\n"),
+		"truncated":          encodeTestImage(t, "png", 20, 20)[:60],
+		"too wide":           encodeTestImage(t, "png", 101, 1),
+		"too high":           encodeTestImage(t, "jpeg", 1, 51),
+		"too large":          append(encodeTestImage(t, "png", 1, 1), make([]byte, 4096)...),
+	}
+
+	for name, data := range tests {
+		_, err := util.ProcessImage(data)
+		assert.Error(t, err, name)
+	}
+}
+
+// A few bytes of header may claim a huge image; it must be rejected without
+// allocating it.
+func Test_ProcessImage_RejectsDimensionsBeforeDecoding(t *testing.T) {
+	data := encodeTestImage(t, "png", 1, 1)
+	binary.BigEndian.PutUint32(data[16:], 1_000_000) // IHDR width
+	binary.BigEndian.PutUint32(data[20:], 1_000_000) // IHDR height
+	binary.BigEndian.PutUint32(data[29:], crc32.ChecksumIEEE(data[12:29]))
+
+	_, err := util.ProcessImage(data)
+
+	assert.ErrorContains(t, err, "1000000x1000000")
+}
+
+func Test_Create_InvalidPhotoNamesIndex(t *testing.T) {
+	images := NewFakeImageStore()
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images))
+
+	dto := DefaultRoomCreateDTO
+	dto.PhotosPayload = []string{
+		SMALL_IMG,
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString([]byte("<svg onload=alert(1)>")),
+	}
+
+	mockUserClient.On("FindById", context.Background(), mock.AnythingOfType("uint")).Return(DefaultUser_Host, nil)
+
+	roomGot, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
+
+	code, message := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Contains(t, message, "Photo 1")
+	assert.Nil(t, roomGot)
+	assert.Empty(t, images.Keys())
+	mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 0)
+}
+
+func Test_Image_Resize(t *testing.T) {
+	image, err := util.ProcessImage(encodeTestImage(t, "jpeg", 1600, 900))
+	assert.NoError(t, err)
+
+	resized, err := image.Resize(320, 320)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 320, resized.Width)
+	assert.Equal(t, 180, resized.Height)
+	assert.Equal(t, "image/jpeg", resized.ContentType)
+	assert.Less(t, len(resized.Data), len(image.Data))
+
+	fits, err := image.Resize(2000, 2000)
+	assert.NoError(t, err)
+	assert.Nil(t, fits, "images are never scaled up")
+}
+
+func Test_Create_StoresVariants(t *testing.T) {
+	images := NewFakeImageStore()
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images))
+
+	dto := DefaultRoomCreateDTO
+	dto.PhotosPayload = []string{
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString(encodeTestImage(t, "png", 2000, 500)),
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString(encodeTestImage(t, "png", 600, 600)),
+	}
+
+	mockRepo.On("CreateWithPhotos", mock.AnythingOfType("*internal.Room"), []uint(nil)).Return(nil)
+	mockUserClient.On("FindById", context.Background(), mock.AnythingOfType("uint")).Return(DefaultUser_Host, nil)
+
+	roomGot, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
+
+	assert.NoError(t, err)
+	assert.Len(t, roomGot.Photos, 2)
+	wide, square := roomGot.Photos[0], roomGot.Photos[1]
+	base := strings.TrimSuffix(wide.Key, ".png")
+	assert.Equal(t, map[string]string{"thumbnail": base + "-thumbnail.png", "medium": base + "-medium.png"}, wide.Variants)
+	assert.Equal(t, []string{"thumbnail"}, slices.Collect(maps.Keys(square.Variants)))
+	assert.ElementsMatch(t, append(wide.Keys(), square.Keys()...), images.Keys())
+}
+
+func Test_Create_VariantSaveFailedCleansUp(t *testing.T) {
+	images := NewFakeImageStore()
+	images.PutErr = fmt.Errorf("some error")
+	images.FailAfter = 2
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images))
+
+	dto := DefaultRoomCreateDTO
+	dto.PhotosPayload = []string{
+		"data:image/png;base64," + base64.StdEncoding.EncodeToString(encodeTestImage(t, "png", 2000, 500)),
+	}
+
+	mockUserClient.On("FindById", context.Background(), mock.AnythingOfType("uint")).Return(DefaultUser_Host, nil)
+
+	_, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
+
+	assert.Error(t, err)
+	assert.Empty(t, images.Keys(), "the original and thumbnail saved before the failure are removed")
+	mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 0)
+}
+
+func Test_NewPhotoDTO(t *testing.T) {
+	internal.SetPhotoBaseURL("https://cdn.example.com/rooms")
+	t.Cleanup(func() { internal.SetPhotoBaseURL("/img/") })
+
+	dto := internal.NewPhotoDTO(internal.Photo{Key: "room-1-0.jpg", Variants: map[string]string{"thumbnail": "room-1-0-thumbnail.jpg"}})
+
+	assert.Equal(t, "room-1-0.jpg", dto.Key)
+	assert.Equal(t, map[string]string{
+		"original":  "https://cdn.example.com/rooms/room-1-0.jpg",
+		"thumbnail": "https://cdn.example.com/rooms/room-1-0-thumbnail.jpg",
+		"medium":    "https://cdn.example.com/rooms/room-1-0.jpg",
+	}, dto.URLs)
+}
@@ -0,0 +1,115 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	"bookem-room-service/util"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/prometheus/client_golang/prometheus"
+	dto "github.com/prometheus/client_model/go"
+	"github.com/stretchr/testify/assert"
+)
+
+// metricValue returns the value of a counter (or the sample count of a
+// histogram) registered in the default registry, or 0 if it has no samples
+// with the labels.
+func metricValue(t *testing.T, name string, labels map[string]string) float64 {
+	families, err := prometheus.DefaultGatherer.Gather()
+	assert.NoError(t, err)
+
+	for _, family := range families {
+		if family.GetName() != name {
+			continue
+		}
+		for _, metric := range family.GetMetric() {
+			if !hasLabels(metric, labels) {
+				continue
+			}
+			if metric.GetHistogram() != nil {
+				return float64(metric.GetHistogram().GetSampleCount())
+			}
+			return metric.GetCounter().GetValue()
+		}
+	}
+	return 0
+}
+
+func hasLabels(metric *dto.Metric, labels map[string]string) bool {
+	matched := 0
+	for _, pair := range metric.GetLabel() {
+		if value, ok := labels[pair.GetName()]; ok {
+			if value != pair.GetValue() {
+				return false
+			}
+			matched++
+		}
+	}
+	return matched == len(labels)
+}
+
+func Test_RegisterCollector_ReturnsExisting(t *testing.T) {
+	opts := prometheus.CounterOpts{Name: "test_register_collector_total", Help: "test"}
+
+	first := util.RegisterCollector(prometheus.NewCounter(opts))
+	second := util.RegisterCollector(prometheus.NewCounter(opts))
+
+	assert.Same(t, first, second)
+}
+
+func Test_PrometheusMiddleware_ConstructedTwice(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+
+	for range 2 {
+		server := gin.New()
+		server.Use(internal.PrometheusMiddleware())
+		server.GET("/metrics-test", func(c *gin.Context) { c.Status(http.StatusOK) })
+
+		server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/metrics-test", nil))
+	}
+
+	labels := map[string]string{"endpoint": "/metrics-test", "status": "200"}
+	assert.Equal(t, float64(2), metricValue(t, "http_requests_total", labels))
+	assert.Equal(t, float64(2), metricValue(t, "http_request_duration_seconds", labels))
+}
+
+func Test_Metrics_SearchCounted(t *testing.T) {
+	svc, mockRepo, _, _, _ := CreateTestRoomService()
+
+	query := *DefaultRoomsQueryDTO
+	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address, query.Query).Return([]internal.Room{}, nil)
+
+	labels := map[string]string{"mode": "fixed", "cache": "miss"}
+	searchesBefore := metricValue(t, "room_searches_total", labels)
+	hitsBefore := metricValue(t, "room_search_hits", nil)
+
+	_, _, err := svc.FindAvailableRooms(context.Background(), query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, searchesBefore+1, metricValue(t, "room_searches_total", labels))
+	assert.Equal(t, hitsBefore+1, metricValue(t, "room_search_hits", nil))
+}
+
+func Test_Metrics_QuoteFailureCounted(t *testing.T) {
+	svc, _, _, _, mockUserClient := CreateTestRoomService()
+
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Guest.Id).Return(nil, fmt.Errorf("not found"))
+
+	labels := map[string]string{"reason": "user_not_found"}
+	before := metricValue(t, "room_quote_failures_total", labels)
+
+	_, err := svc.QueryForReservation(context.Background(), DefaultUser_Guest.Id, internal.RoomReservationQueryDTO{
+		RoomID:     DefaultRoom.ID,
+		DateFrom:   time.Date(2025, 8, 20, 0, 0, 0, 0, time.UTC),
+		DateTo:     time.Date(2025, 8, 21, 0, 0, 0, 0, time.UTC),
+		GuestCount: 2,
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, before+1, metricValue(t, "room_quote_failures_total", labels))
+}
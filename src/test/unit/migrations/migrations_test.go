@@ -0,0 +1,74 @@
+package test
+
+import (
+	"bookem-room-service/migrations"
+	"testing"
+	"testing/fstest"
+
+	"github.com/stretchr/testify/assert"
+)
+
+func file(content string) *fstest.MapFile {
+	return &fstest.MapFile{Data: []byte(content)}
+}
+
+func Test_Parse_SortsByVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0010_add_index.up.sql":   file("CREATE INDEX ..."),
+		"0010_add_index.down.sql": file("DROP INDEX ..."),
+		"0002_add_rooms.up.sql":   file("CREATE TABLE rooms ..."),
+		"0002_add_rooms.down.sql": file("DROP TABLE rooms"),
+	}
+
+	result, err := migrations.Parse(fsys)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(result))
+	assert.Equal(t, 2, result[0].Version)
+	assert.Equal(t, "add_rooms", result[0].Name)
+	assert.Equal(t, "CREATE TABLE rooms ...", result[0].Up)
+	assert.Equal(t, "DROP TABLE rooms", result[0].Down)
+	assert.Equal(t, 10, result[1].Version)
+}
+
+func Test_Parse_MissingDown(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_init.up.sql": file("CREATE TABLE rooms ..."),
+	}
+
+	_, err := migrations.Parse(fsys)
+
+	assert.ErrorContains(t, err, "0001_init")
+}
+
+func Test_Parse_DuplicateVersion(t *testing.T) {
+	fsys := fstest.MapFS{
+		"0001_init.up.sql":    file("..."),
+		"0001_init.down.sql":  file("..."),
+		"0001_other.up.sql":   file("..."),
+		"0001_other.down.sql": file("..."),
+	}
+
+	_, err := migrations.Parse(fsys)
+
+	assert.Error(t, err)
+}
+
+func Test_Parse_BadFileName(t *testing.T) {
+	fsys := fstest.MapFS{
+		"init.sql": file("..."),
+	}
+
+	_, err := migrations.Parse(fsys)
+
+	assert.ErrorContains(t, err, "init.sql")
+}
+
+func Test_All_EmbeddedMigrationsAreValid(t *testing.T) {
+	result, err := migrations.All()
+
+	assert.NoError(t, err)
+	assert.NotEmpty(t, result)
+	assert.Equal(t, 1, result[0].Version)
+	assert.Contains(t, result[0].Up, "idx_rooms_search")
+}
@@ -0,0 +1,150 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	"context"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+)
+
+type cursorItem struct {
+	ID    uint
+	Price float64
+}
+
+var byPrice = internal.CursorOrder[cursorItem]{
+	Name: "price",
+	Key:  func(i cursorItem) (float64, uint) { return i.Price, i.ID },
+}
+
+// Sorted by price, ties broken by ID.
+var cursorItems = []cursorItem{
+	{ID: 3, Price: 10},
+	{ID: 1, Price: 20},
+	{ID: 4, Price: 20},
+	{ID: 2, Price: 30},
+	{ID: 5, Price: 40},
+}
+
+func ids(items []cursorItem) []uint {
+	result := make([]uint, 0, len(items))
+	for _, item := range items {
+		result = append(result, item.ID)
+	}
+	return result
+}
+
+func Test_PaginateByCursor_ForwardAndBack(t *testing.T) {
+	// [1] First page
+	page, prev, next, err := internal.PaginateByCursor(cursorItems, byPrice, "", 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{3, 1}, ids(page))
+	assert.Empty(t, prev)
+	assert.NotEmpty(t, next)
+
+	// [2] Second page, the tie on price is broken by ID
+	page, prev, next, err = internal.PaginateByCursor(cursorItems, byPrice, next, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{4, 2}, ids(page))
+	assert.NotEmpty(t, prev)
+	assert.NotEmpty(t, next)
+
+	// [3] Last page
+	page, _, nextLast, err := internal.PaginateByCursor(cursorItems, byPrice, next, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{5}, ids(page))
+	assert.Empty(t, nextLast)
+
+	// [4] Back to the first page
+	page, prev, _, err = internal.PaginateByCursor(cursorItems, byPrice, prev, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{3, 1}, ids(page))
+	assert.Empty(t, prev)
+}
+
+func Test_PaginateByCursor_StableWhenItemsAreAdded(t *testing.T) {
+	_, _, next, err := internal.PaginateByCursor(cursorItems, byPrice, "", 2)
+	assert.NoError(t, err)
+
+	// A cheaper item appears before the cursor, the next page does not shift.
+	items := append([]cursorItem{{ID: 6, Price: 5}}, cursorItems...)
+	page, _, _, err := internal.PaginateByCursor(items, byPrice, next, 2)
+
+	assert.NoError(t, err)
+	assert.Equal(t, []uint{4, 2}, ids(page))
+}
+
+func Test_PaginateByCursor_InvalidCursor(t *testing.T) {
+	page, _, _, err := internal.PaginateByCursor(cursorItems, byPrice, "not a cursor", 2)
+
+	assert.Error(t, err)
+	assert.Nil(t, page)
+}
+
+func Test_PaginateByCursor_OrderMismatch(t *testing.T) {
+	cursor := internal.EncodeCursor(internal.PageCursor{Order: "relevance", Key: 20, ID: 1})
+
+	page, _, _, err := internal.PaginateByCursor(cursorItems, byPrice, cursor, 2)
+
+	assert.Error(t, err)
+	assert.Nil(t, page)
+}
+
+func Test_FindAvailableRooms_Cursor(t *testing.T) {
+	svc, mockRepo, mockAvailRepo, mockPriceRepo, _ := CreateTestRoomService()
+
+	rooms := []internal.Room{
+		{ID: 1, MinGuests: 1, MaxGuests: 5, Status: internal.RoomPublished},
+		{ID: 2, MinGuests: 1, MaxGuests: 5, Status: internal.RoomPublished},
+		{ID: 3, MinGuests: 1, MaxGuests: 5, Status: internal.RoomPublished},
+	}
+
+	query := *DefaultRoomsQueryDTO
+	query.PageSize = 2
+
+	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address, query.Query).Return(rooms, nil)
+	for _, room := range rooms {
+		avail := *DefaultAvailabilityList
+		avail.Items = []internal.RoomAvailabilityItem{{
+			DateFrom:  query.DateFrom,
+			DateTo:    query.DateTo,
+			Available: true,
+		}}
+		mockAvailRepo.On("FindCurrentListOfRoom", room.ID).Return(&avail, nil)
+		mockPriceRepo.On("FindCurrentListOfRoom", room.ID).Return(DefaultPriceList, nil)
+	}
+
+	// [1] Page numbers also hand out cursors
+	roomsGot, infoGot, err := svc.FindAvailableRooms(context.Background(), query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, len(roomsGot))
+	assert.Empty(t, infoGot.PrevCursor)
+	assert.NotEmpty(t, infoGot.NextCursor)
+
+	// [2] Following the cursor
+	query.PageNumber = 0
+	query.Cursor = infoGot.NextCursor
+	roomsGot, infoGot, err = svc.FindAvailableRooms(context.Background(), query)
+
+	assert.NoError(t, err)
+	assert.Equal(t, 1, len(roomsGot))
+	assert.Equal(t, uint(3), roomsGot[0].ID)
+	assert.Equal(t, uint(0), infoGot.Page)
+	assert.Equal(t, uint(3), infoGot.TotalHits)
+	assert.NotEmpty(t, infoGot.PrevCursor)
+	assert.Empty(t, infoGot.NextCursor)
+
+	// [3] A cursor for another sort order is rejected
+	query.SortBy = internal.SortByPrice
+	roomsGot, infoGot, err = svc.FindAvailableRooms(context.Background(), query)
+
+	assert.Error(t, err)
+	assert.Nil(t, roomsGot)
+	assert.Nil(t, infoGot)
+}
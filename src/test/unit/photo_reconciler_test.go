@@ -0,0 +1,164 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	"context"
+	"crypto/sha256"
+	"encoding/hex"
+	"fmt"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+)
+
+// storedAgo stores an image in the store as if it was stored age ago.
+func storedAgo(images *FakeImageStore, key string, age time.Duration) {
+	images.Put(context.Background(), key, []byte("image"), "image/jpeg")
+	images.Modified[key] = time.Now().Add(-age)
+}
+
+// photoKey is the key of an image stored under the hash of name.
+func photoKey(name string, variant string) string {
+	hash := sha256.Sum256([]byte(name))
+	if variant == "" {
+		return hex.EncodeToString(hash[:]) + ".jpg"
+	}
+	return hex.EncodeToString(hash[:]) + "-" + variant + ".jpg"
+}
+
+func newTestReconciler(images *FakeImageStore) (*internal.PhotoReconciler, *MockRoomRepo, *MockRoomPhotoRepo) {
+	repo := new(MockRoomRepo)
+	photoRepo := new(MockRoomPhotoRepo)
+	return internal.NewPhotoReconciler(repo, photoRepo, NewFakePhotoBlobRepo(), images, time.Hour, 24*time.Hour), repo, photoRepo
+}
+
+func Test_Reconcile_DeletesOrphanedImages(t *testing.T) {
+	room, roomThumb := photoKey("room", ""), photoKey("room", "thumbnail")
+	staged := photoKey("staged", "")
+	orphan, orphanThumb := photoKey("orphan", ""), photoKey("orphan", "thumbnail")
+	young := photoKey("young", "")
+
+	images := NewFakeImageStore()
+	storedAgo(images, room, 48*time.Hour)
+	storedAgo(images, roomThumb, 48*time.Hour)
+	storedAgo(images, staged, 2*time.Hour)
+	storedAgo(images, orphan, 2*time.Hour)
+	storedAgo(images, orphanThumb, 2*time.Hour)
+	storedAgo(images, young, time.Minute)
+
+	reconciler, repo, photoRepo := newTestReconciler(images)
+	photoRepo.On("FindStagedBefore", mock.AnythingOfType("time.Time")).Return([]internal.RoomPhoto{}, nil)
+	photoRepo.On("FindAll").Return([]internal.RoomPhoto{{ID: 1, Key: staged}}, nil)
+	repo.On("FindAllPhotos").Return([]internal.Photo{
+		{Key: room, Variants: map[string]string{"thumbnail": roomThumb}},
+	}, nil)
+
+	deleted, err := reconciler.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 2, deleted)
+	assert.ElementsMatch(t, []string{room, roomThumb, staged, young}, images.Keys())
+}
+
+// The store may hold files that are not photos, such as those of an older
+// naming scheme or of another service sharing the bucket.
+func Test_Reconcile_KeepsOtherFiles(t *testing.T) {
+	others := []string{"index.html", "room-1-0.jpg", photoKey("orphan", "") + ".bak", photoKey("orphan", "large")}
+
+	images := NewFakeImageStore()
+	for _, key := range others {
+		storedAgo(images, key, 48*time.Hour)
+	}
+
+	reconciler, repo, photoRepo := newTestReconciler(images)
+	photoRepo.On("FindStagedBefore", mock.AnythingOfType("time.Time")).Return([]internal.RoomPhoto{}, nil)
+	photoRepo.On("FindAll").Return([]internal.RoomPhoto{}, nil)
+	repo.On("FindAllPhotos").Return([]internal.Photo{}, nil)
+
+	deleted, err := reconciler.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+	assert.ElementsMatch(t, others, images.Keys())
+}
+
+func Test_Reconcile_ExpiresStagedPhotos(t *testing.T) {
+	key, thumb := photoKey("old", ""), photoKey("old", "thumbnail")
+	images := NewFakeImageStore()
+	storedAgo(images, key, 48*time.Hour)
+	storedAgo(images, thumb, 48*time.Hour)
+
+	old := internal.RoomPhoto{ID: 1, Key: key, Variants: map[string]string{"thumbnail": thumb}}
+	blobs := NewFakePhotoBlobRepo()
+	blobs.Acquire(&internal.PhotoBlob{Key: old.Key, Variants: old.Variants})
+
+	repo := new(MockRoomRepo)
+	photoRepo := new(MockRoomPhotoRepo)
+	reconciler := internal.NewPhotoReconciler(repo, photoRepo, blobs, images, time.Hour, 24*time.Hour)
+	photoRepo.On("FindStagedBefore", mock.AnythingOfType("time.Time")).Return([]internal.RoomPhoto{old}, nil)
+	photoRepo.On("DeleteStaged", mock.AnythingOfType("*internal.RoomPhoto")).Return(true, nil)
+	photoRepo.On("FindAll").Return([]internal.RoomPhoto{}, nil)
+	repo.On("FindAllPhotos").Return([]internal.Photo{}, nil)
+
+	deleted, err := reconciler.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, deleted, "released images are not orphaned")
+	assert.Empty(t, images.Keys())
+	assert.Equal(t, 0, blobs.RefCount(old.Key))
+	photoRepo.AssertNumberOfCalls(t, "DeleteStaged", 1)
+
+	cutoff := photoRepo.Calls[0].Arguments.Get(0).(time.Time)
+	assert.WithinDuration(t, time.Now().Add(-24*time.Hour), cutoff, time.Minute)
+}
+
+// The photo is attached to a room between finding and deleting it: the room
+// now holds the reference, so nothing is released.
+func Test_Reconcile_StagedPhotoAttachedMeanwhile(t *testing.T) {
+	key, thumb := photoKey("old", ""), photoKey("old", "thumbnail")
+	images := NewFakeImageStore()
+	storedAgo(images, key, 48*time.Hour)
+	storedAgo(images, thumb, 48*time.Hour)
+
+	roomId := uint(1)
+	old := internal.RoomPhoto{ID: 1, Key: key, Variants: map[string]string{"thumbnail": thumb}}
+	attached := old
+	attached.RoomID = &roomId
+	blobs := NewFakePhotoBlobRepo()
+	blobs.Acquire(&internal.PhotoBlob{Key: old.Key, Variants: old.Variants})
+
+	repo := new(MockRoomRepo)
+	photoRepo := new(MockRoomPhotoRepo)
+	reconciler := internal.NewPhotoReconciler(repo, photoRepo, blobs, images, time.Hour, 24*time.Hour)
+	photoRepo.On("FindStagedBefore", mock.AnythingOfType("time.Time")).Return([]internal.RoomPhoto{old}, nil)
+	photoRepo.On("DeleteStaged", mock.AnythingOfType("*internal.RoomPhoto")).Return(false, nil)
+	photoRepo.On("FindAll").Return([]internal.RoomPhoto{attached}, nil)
+	repo.On("FindAllPhotos").Return([]internal.Photo{old.Photo()}, nil)
+
+	deleted, err := reconciler.Reconcile(context.Background())
+
+	assert.NoError(t, err)
+	assert.Equal(t, 0, deleted)
+	assert.ElementsMatch(t, []string{key, thumb}, images.Keys())
+	assert.Equal(t, 1, blobs.RefCount(old.Key), "the reference passed to the room")
+}
+
+// Without the references, everything would look orphaned.
+func Test_Reconcile_RepoFailedDeletesNothing(t *testing.T) {
+	key := photoKey("room", "")
+	images := NewFakeImageStore()
+	storedAgo(images, key, 48*time.Hour)
+
+	reconciler, repo, photoRepo := newTestReconciler(images)
+	photoRepo.On("FindStagedBefore", mock.AnythingOfType("time.Time")).Return([]internal.RoomPhoto{}, nil)
+	photoRepo.On("FindAll").Return([]internal.RoomPhoto{}, nil)
+	repo.On("FindAllPhotos").Return(nil, fmt.Errorf("some error"))
+
+	deleted, err := reconciler.Reconcile(context.Background())
+
+	assert.Error(t, err)
+	assert.Equal(t, 0, deleted)
+	assert.Equal(t, []string{key}, images.Keys())
+}
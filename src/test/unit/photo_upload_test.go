@@ -0,0 +1,366 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	"bookem-room-service/util"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"iter"
+	"mime/multipart"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+)
+
+func smallJpeg(t *testing.T) []byte {
+	data, err := util.DecodeImageB64(SMALL_IMG)
+	assert.NoError(t, err)
+	return data
+}
+
+// newUploadServer routes the room API with the given limits. Its requests
+// authenticate as DefaultUser_Host, with the bearer token as the role.
+func newUploadServer(t *testing.T, svc internal.Service, limits internal.UploadLimits) func(req *http.Request) *httptest.ResponseRecorder {
+	parseJWT := util.ParseJWT
+	util.ParseJWT = func(token string) (jwt.MapClaims, error) {
+		return jwt.MapClaims{"sub": float64(DefaultUser_Host.Id), "username": "host", "role": token}, nil
+	}
+	internal.SetUploadLimits(limits)
+	t.Cleanup(func() {
+		util.ParseJWT = parseJWT
+		internal.SetUploadLimits(internal.UploadLimits{MaxFileBytes: 10 << 20, MaxRequestBytes: 50 << 20, MaxFiles: 10})
+	})
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	internal.NewRoute(internal.NewHandler(svc)).Route(server.Group("/api"))
+
+	return func(req *http.Request) *httptest.ResponseRecorder {
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w
+	}
+}
+
+// uploadRequest builds a multipart upload of files as a user with the role.
+func uploadRequest(role string, files ...[]byte) *http.Request {
+	var body bytes.Buffer
+	writer := multipart.NewWriter(&body)
+	for i, file := range files {
+		part, _ := writer.CreateFormFile("photos", fmt.Sprintf("photo-%d.jpg", i))
+		part.Write(file)
+	}
+	writer.Close()
+
+	req := httptest.NewRequest(http.MethodPost, "/api/photos", &body)
+	req.Header.Set("Content-Type", writer.FormDataContentType())
+	req.Header.Set("Authorization", "Bearer "+role)
+	return req
+}
+
+// photoFiles yields files as uploaded photos, then err if it is not nil.
+func photoFiles(err error, files ...[]byte) iter.Seq2[[]byte, error] {
+	return func(yield func([]byte, error) bool) {
+		for _, file := range files {
+			if !yield(file, nil) {
+				return
+			}
+		}
+		if err != nil {
+			yield(nil, err)
+		}
+	}
+}
+
+var testUploadLimits = internal.UploadLimits{MaxFileBytes: 1024, MaxRequestBytes: 4096, MaxFiles: 2}
+
+func Test_UploadPhotos_Success(t *testing.T) {
+	images := NewFakeImageStore()
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, _, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithPhotoRepo(photoRepo))
+	send := newUploadServer(t, svc, testUploadLimits)
+
+	nextId := uint(0)
+	photoRepo.On("Create", mock.AnythingOfType("*internal.RoomPhoto")).Run(func(args mock.Arguments) {
+		nextId++
+		args.Get(0).(*internal.RoomPhoto).ID = nextId
+	}).Return(nil)
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	w := send(uploadRequest("host", smallJpeg(t), smallJpeg(t)))
+
+	assert.Equal(t, http.StatusCreated, w.Code)
+	var result []internal.UploadedPhotoDTO
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &result))
+	assert.Len(t, result, 2)
+	assert.Equal(t, uint(1), result[0].ID)
+	assert.Equal(t, uint(2), result[1].ID)
+	assert.Equal(t, "image/jpeg", result[0].ContentType)
+	assert.Equal(t, result[0].Key, result[1].Key, "identical pictures share one image")
+	assert.Equal(t, []string{result[0].Key}, images.Keys())
+	assert.Equal(t, "/img/"+result[0].Key, result[0].URLs["thumbnail"], "a 1x1 photo has no smaller variant")
+	photoRepo.AssertNumberOfCalls(t, "Create", 2)
+}
+
+func Test_UploadPhotos_FileTooLarge(t *testing.T) {
+	images := NewFakeImageStore()
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, _, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithPhotoRepo(photoRepo))
+	send := newUploadServer(t, svc, testUploadLimits)
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	w := send(uploadRequest("host", make([]byte, 1025)))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "Photo 0")
+	assert.Empty(t, images.Keys())
+	photoRepo.AssertNumberOfCalls(t, "Create", 0)
+}
+
+func Test_UploadPhotos_RequestTooLarge(t *testing.T) {
+	images := NewFakeImageStore()
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, _, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithPhotoRepo(photoRepo))
+	send := newUploadServer(t, svc, internal.UploadLimits{MaxFileBytes: 1024, MaxRequestBytes: 1500, MaxFiles: 2})
+	photoRepo.On("Create", mock.AnythingOfType("*internal.RoomPhoto")).Return(nil)
+	photoRepo.On("DeleteStaged", mock.AnythingOfType("*internal.RoomPhoto")).Return(true, nil)
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	// Trailing data of a photo is dropped, so it stays valid.
+	padded := append(smallJpeg(t), make([]byte, 600)...)
+	w := send(uploadRequest("host", padded, padded))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "Request is larger")
+	assert.Empty(t, images.Keys(), "the photo stored before is removed")
+}
+
+// Photos are stored as they are read, those stored before a part breaks the
+// limits are removed.
+func Test_UploadPhotos_TooManyFiles(t *testing.T) {
+	images := NewFakeImageStore()
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, _, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithPhotoRepo(photoRepo))
+	send := newUploadServer(t, svc, testUploadLimits)
+	photoRepo.On("Create", mock.AnythingOfType("*internal.RoomPhoto")).Return(nil)
+	photoRepo.On("DeleteStaged", mock.AnythingOfType("*internal.RoomPhoto")).Return(true, nil)
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	w := send(uploadRequest("host", smallJpeg(t), encodeTestImage(t, "png", 2, 2), []byte("c")))
+
+	assert.Equal(t, http.StatusRequestEntityTooLarge, w.Code)
+	assert.Contains(t, w.Body.String(), "At most 2 photos")
+	assert.Empty(t, images.Keys())
+	photoRepo.AssertNumberOfCalls(t, "Create", 2)
+	photoRepo.AssertNumberOfCalls(t, "DeleteStaged", 2)
+}
+
+func Test_UploadPhotos_StoresEachBeforeReadingNext(t *testing.T) {
+	images := NewFakeImageStore()
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, _, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithPhotoRepo(photoRepo))
+	photoRepo.On("Create", mock.AnythingOfType("*internal.RoomPhoto")).Return(nil)
+	photoRepo.On("DeleteStaged", mock.AnythingOfType("*internal.RoomPhoto")).Return(true, nil)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	stored := -1
+	files := func(yield func([]byte, error) bool) {
+		if !yield(smallJpeg(t), nil) {
+			return
+		}
+		stored = len(images.Keys())
+		yield(nil, fmt.Errorf("connection reset"))
+	}
+
+	_, err := svc.UploadPhotos(context.Background(), DefaultUser_Host.Id, files)
+
+	assert.EqualError(t, err, "connection reset")
+	assert.Equal(t, 1, stored, "the first photo is stored before the second is read")
+	assert.Empty(t, images.Keys())
+}
+
+func Test_UploadPhotos_None(t *testing.T) {
+	svc, _, _, _, mockUserClient := CreateTestRoomService()
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	_, err := svc.UploadPhotos(context.Background(), DefaultUser_Host.Id, photoFiles(nil))
+
+	code, _ := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusBadRequest, code)
+}
+
+func Test_UploadPhotos_NotAnImage(t *testing.T) {
+	images := NewFakeImageStore()
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, _, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithPhotoRepo(photoRepo))
+	send := newUploadServer(t, svc, testUploadLimits)
+
+	photoRepo.On("Create", mock.AnythingOfType("*internal.RoomPhoto")).Return(nil)
+	photoRepo.On("DeleteStaged", mock.AnythingOfType("*internal.RoomPhoto")).Return(true, nil)
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	w := send(uploadRequest("host", smallJpeg(t), []byte("#!/bin/sh\necho hello\n")))
+
+	assert.Equal(t, http.StatusBadRequest, w.Code)
+	assert.Contains(t, w.Body.String(), "Photo 1 is invalid")
+	assert.Empty(t, images.Keys(), "no photo is kept if any is invalid")
+}
+
+func Test_UploadPhotos_GuestIsUnauthorized(t *testing.T) {
+	svc, _, _, _, _ := CreateTestRoomService()
+	send := newUploadServer(t, svc, testUploadLimits)
+
+	w := send(uploadRequest("guest", smallJpeg(t)))
+
+	assert.Equal(t, http.StatusUnauthorized, w.Code)
+}
+
+func Test_UploadPhotos_RowFailureDeletesBlobs(t *testing.T) {
+	images := NewFakeImageStore()
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, _, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithPhotoRepo(photoRepo))
+
+	photoRepo.On("Create", mock.AnythingOfType("*internal.RoomPhoto")).Return(nil).Once()
+	photoRepo.On("Create", mock.AnythingOfType("*internal.RoomPhoto")).Return(fmt.Errorf("db error"))
+	photoRepo.On("DeleteStaged", mock.AnythingOfType("*internal.RoomPhoto")).Return(true, nil)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	photos, err := svc.UploadPhotos(context.Background(), DefaultUser_Host.Id, photoFiles(nil, smallJpeg(t), smallJpeg(t)))
+
+	assert.Error(t, err)
+	assert.Nil(t, photos)
+	assert.Empty(t, images.Keys(), "the photo staged before the failure is removed too")
+	photoRepo.AssertNumberOfCalls(t, "DeleteStaged", 1)
+}
+
+func Test_Create_WithUploadedPhotos(t *testing.T) {
+	images := NewFakeImageStore()
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithPhotoRepo(photoRepo))
+
+	dto := DefaultRoomCreateDTO
+	dto.PhotoIDs = []uint{4, 3}
+
+	photoRepo.On("FindByIds", []uint{4, 3}).Return([]internal.RoomPhoto{
+		{ID: 3, Key: "photo-c.png", UploaderID: DefaultUser_Host.Id},
+		{ID: 4, Key: "photo-d.jpg", Variants: map[string]string{"thumbnail": "photo-d-thumbnail.jpg"}, UploaderID: DefaultUser_Host.Id},
+	}, nil)
+	mockRepo.On("CreateWithPhotos", mock.AnythingOfType("*internal.Room"), []uint{4, 3}).Return(nil)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	roomGot, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
+
+	assert.NoError(t, err)
+	assert.Len(t, roomGot.Photos, 3)
+	assert.Equal(t, []internal.Photo{
+		{Key: "photo-d.jpg", Variants: map[string]string{"thumbnail": "photo-d-thumbnail.jpg"}},
+		{Key: "photo-c.png"},
+	}, roomGot.Photos[1:], "uploaded photos come after those of the payload")
+	photoRepo.AssertExpectations(t)
+	mockRepo.AssertExpectations(t)
+}
+
+func Test_Create_WithForeignPhoto(t *testing.T) {
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithPhotoRepo(photoRepo))
+
+	dto := DefaultRoomCreateDTO
+	dto.PhotoIDs = []uint{3}
+
+	photoRepo.On("FindByIds", []uint{3}).Return([]internal.RoomPhoto{
+		{ID: 3, Key: "photo-c.png", UploaderID: DefaultUser_Host.Id + 1},
+	}, nil)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	roomGot, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
+
+	code, _ := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Nil(t, roomGot)
+	mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 0)
+}
+
+func Test_Create_WithAttachedPhoto(t *testing.T) {
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithPhotoRepo(photoRepo))
+
+	roomId := uint(9)
+	dto := DefaultRoomCreateDTO
+	dto.PhotoIDs = []uint{3}
+
+	photoRepo.On("FindByIds", []uint{3}).Return([]internal.RoomPhoto{
+		{ID: 3, Key: "photo-c.png", UploaderID: DefaultUser_Host.Id, RoomID: &roomId},
+	}, nil)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	_, err := svc.Create(context.Background(), DefaultUser_Host.Id, dto)
+
+	assert.ErrorContains(t, err, "already used")
+	mockRepo.AssertNumberOfCalls(t, "CreateWithPhotos", 0)
+}
+
+func Test_AttachPhotos_Success(t *testing.T) {
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithPhotoRepo(photoRepo))
+
+	room := *DefaultRoom
+	room.Photos = []internal.Photo{{Key: "room-0-0.jpg"}}
+
+	mockRepo.On("FindById", room.ID).Return(&room, nil)
+	mockRepo.On("UpdateWithPhotos", &room, []uint{5}).Return(nil)
+	photoRepo.On("FindByIds", []uint{5}).Return([]internal.RoomPhoto{
+		{ID: 5, Key: "photo-e.jpg", UploaderID: DefaultUser_Host.Id},
+	}, nil)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	roomGot, err := svc.AttachPhotos(context.Background(), DefaultUser_Host.Id, room.ID, []uint{5})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.Photo{{Key: "room-0-0.jpg"}, {Key: "photo-e.jpg"}}, roomGot.Photos)
+	photoRepo.AssertExpectations(t)
+	mockRepo.AssertNumberOfCalls(t, "UpdateWithPhotos", 1)
+}
+
+func Test_AttachPhotos_AlreadyInRoom(t *testing.T) {
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithPhotoRepo(photoRepo))
+
+	room := *DefaultRoom
+	room.Photos = []internal.Photo{{Key: "a.jpg"}}
+
+	mockRepo.On("FindById", room.ID).Return(&room, nil)
+	photoRepo.On("FindByIds", []uint{5}).Return([]internal.RoomPhoto{
+		{ID: 5, Key: "a.jpg", UploaderID: DefaultUser_Host.Id},
+	}, nil)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	_, err := svc.AttachPhotos(context.Background(), DefaultUser_Host.Id, room.ID, []uint{5})
+
+	code, _ := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusBadRequest, code)
+	assert.Equal(t, []internal.Photo{{Key: "a.jpg"}}, room.Photos)
+	mockRepo.AssertNumberOfCalls(t, "UpdateWithPhotos", 0)
+}
+
+func Test_AttachPhotos_NotOwner(t *testing.T) {
+	photoRepo := new(MockRoomPhotoRepo)
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithPhotoRepo(photoRepo))
+
+	room := *DefaultRoom
+	room.HostID = DefaultUser_Host.Id + 1
+
+	mockRepo.On("FindById", room.ID).Return(&room, nil)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	_, err := svc.AttachPhotos(context.Background(), DefaultUser_Host.Id, room.ID, []uint{5})
+
+	assert.Equal(t, internal.ErrUnauthorized, err)
+	mockRepo.AssertNumberOfCalls(t, "UpdateWithPhotos", 0)
+}
@@ -11,13 +11,14 @@ import (
 )
 
 func Test_FindPriceListById_Success(t *testing.T) {
-	svc, _, _, mockPriceRepo, _ := CreateTestRoomService()
+	svc, mockRepo, _, mockPriceRepo, _ := CreateTestRoomService()
 
 	list := DefaultPriceList
 
 	mockPriceRepo.On("FindListById", list.ID).Return(list, nil)
+	mockRepo.On("FindById", list.RoomID).Return(DefaultRoom, nil)
 
-	listGot, err := svc.FindPriceListById(context.Background(), list.ID)
+	listGot, err := svc.FindPriceListById(context.Background(), nil, list.ID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, list, listGot)
@@ -30,7 +31,7 @@ func Test_FindPriceListById_NotFound(t *testing.T) {
 
 	mockPriceRepo.On("FindListById", uint(999)).Return(nil, fmt.Errorf("not found"))
 
-	listGot, err := svc.FindPriceListById(context.Background(), 999)
+	listGot, err := svc.FindPriceListById(context.Background(), nil, 999)
 
 	assert.Error(t, err)
 	assert.Nil(t, listGot)
@@ -56,7 +57,7 @@ func Test_FindPriceListsByRoomId_Success(t *testing.T) {
 	mockRepo.On("FindById", room.ID).Return(room, nil)
 	mockPriceRepo.On("FindListsByRoomId", room.ID).Return(lists, nil)
 
-	listsGot, err := svc.FindPriceListsByRoomId(context.Background(), room.ID)
+	listsGot, err := svc.FindPriceListsByRoomId(context.Background(), nil, room.ID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, lists, listsGot)
@@ -73,7 +74,7 @@ func Test_FindPriceListsByRoomId_Success_NoLists(t *testing.T) {
 	mockRepo.On("FindById", room.ID).Return(room, nil)
 	mockPriceRepo.On("FindListsByRoomId", room.ID).Return([]internal.RoomPriceList{}, nil)
 
-	listsGot, err := svc.FindPriceListsByRoomId(context.Background(), room.ID)
+	listsGot, err := svc.FindPriceListsByRoomId(context.Background(), nil, room.ID)
 
 	assert.NoError(t, err)
 	assert.Empty(t, listsGot)
@@ -90,7 +91,7 @@ func Test_FindPriceListsByRoomId_NotFound(t *testing.T) {
 	mockRepo.On("FindById", room.ID).Return(room, nil)
 	mockPriceRepo.On("FindListsByRoomId", room.ID).Return(nil, fmt.Errorf("not found"))
 
-	listsGot, err := svc.FindPriceListsByRoomId(context.Background(), room.ID)
+	listsGot, err := svc.FindPriceListsByRoomId(context.Background(), nil, room.ID)
 
 	assert.Error(t, err)
 	assert.Nil(t, listsGot)
@@ -103,7 +104,7 @@ func Test_FindPriceListsByRoomId_RoomNotFound(t *testing.T) {
 
 	mockRepo.On("FindById", uint(999)).Return(nil, fmt.Errorf("not found"))
 
-	listsGot, err := svc.FindPriceListsByRoomId(context.Background(), 999)
+	listsGot, err := svc.FindPriceListsByRoomId(context.Background(), nil, 999)
 
 	assert.Error(t, err)
 	assert.Nil(t, listsGot)
@@ -111,13 +112,14 @@ func Test_FindPriceListsByRoomId_RoomNotFound(t *testing.T) {
 }
 
 func Test_FindCurrentPriceListOfRoom_Success(t *testing.T) {
-	svc, _, _, mockPriceRepo, _ := CreateTestRoomService()
+	svc, mockRepo, _, mockPriceRepo, _ := CreateTestRoomService()
 
 	list := DefaultPriceList
 
 	mockPriceRepo.On("FindCurrentListOfRoom", list.RoomID).Return(list, nil)
+	mockRepo.On("FindById", list.RoomID).Return(DefaultRoom, nil)
 
-	listGot, err := svc.FindCurrentPriceListOfRoom(context.Background(), list.RoomID)
+	listGot, err := svc.FindCurrentPriceListOfRoom(context.Background(), nil, list.RoomID)
 
 	assert.NoError(t, err)
 	assert.Equal(t, list, listGot)
@@ -125,11 +127,12 @@ func Test_FindCurrentPriceListOfRoom_Success(t *testing.T) {
 }
 
 func Test_FindCurrentPriceListOfRoom_NotFound(t *testing.T) {
-	svc, _, _, mockPriceRepo, _ := CreateTestRoomService()
+	svc, mockRepo, _, mockPriceRepo, _ := CreateTestRoomService()
 
 	mockPriceRepo.On("FindCurrentListOfRoom", uint(999)).Return(nil, fmt.Errorf("not found"))
+	mockRepo.On("FindById", uint(999)).Return(DefaultRoom, nil)
 
-	listGot, err := svc.FindCurrentPriceListOfRoom(context.Background(), 999)
+	listGot, err := svc.FindCurrentPriceListOfRoom(context.Background(), nil, 999)
 
 	assert.Error(t, err)
 	assert.Nil(t, listGot)
@@ -0,0 +1,274 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	"bytes"
+	"context"
+	"encoding/json"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+)
+
+func roomWithStatus(status internal.RoomStatus) *internal.Room {
+	room := *DefaultRoom
+	room.Status = status
+	return &room
+}
+
+func Test_RoomStatus_CanBecome(t *testing.T) {
+	tests := []struct {
+		from internal.RoomStatus
+		to   internal.RoomStatus
+		can  bool
+	}{
+		{internal.RoomDraft, internal.RoomPendingReview, true},
+		{internal.RoomDraft, internal.RoomPublished, false},
+		{internal.RoomPendingReview, internal.RoomPublished, true},
+		{internal.RoomPendingReview, internal.RoomDraft, true},
+		{internal.RoomPublished, internal.RoomSuspended, true},
+		{internal.RoomPublished, internal.RoomArchived, true},
+		{internal.RoomPublished, internal.RoomDraft, false},
+		{internal.RoomSuspended, internal.RoomPublished, true},
+		{internal.RoomSuspended, internal.RoomArchived, false},
+		{internal.RoomArchived, internal.RoomPendingReview, true},
+		{internal.RoomArchived, internal.RoomPublished, false},
+	}
+
+	for _, test := range tests {
+		assert.Equal(t, test.can, test.from.CanBecome(test.to), "%s to %s", test.from, test.to)
+	}
+}
+
+func Test_SubmitRoom_Success(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	room := roomWithStatus(internal.RoomDraft)
+	room.StatusComment = "photos are blurry"
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockRepo.On("UpdateStatusWithAudit", room, mock.AnythingOfType("internal.RoomStatus"), mock.AnythingOfType("*internal.AuditEntry")).Return(true, nil)
+
+	roomGot, err := svc.SubmitRoom(context.Background(), DefaultUser_Host.Id, room.ID)
+
+	assert.NoError(t, err)
+	assert.Equal(t, internal.RoomPendingReview, roomGot.Status)
+	assert.Empty(t, roomGot.StatusComment, "the comment was about the previous draft")
+
+	entry := mockRepo.Calls[1].Arguments.Get(2).(*internal.AuditEntry)
+	assert.Equal(t, DefaultUser_Host.Id, entry.ActorID)
+	assert.Equal(t, internal.AuditSubmitRoom, entry.Action)
+}
+
+func Test_SubmitRoom_Rejects(t *testing.T) {
+	other := roomWithStatus(internal.RoomDraft)
+	other.HostID = DefaultUser_Host.Id + 1
+
+	tests := map[string]struct {
+		room *internal.Room
+		code int
+	}{
+		"not the host":      {other, http.StatusUnauthorized},
+		"pending already":   {roomWithStatus(internal.RoomPendingReview), http.StatusConflict},
+		"published already": {roomWithStatus(internal.RoomPublished), http.StatusConflict},
+		"suspended":         {roomWithStatus(internal.RoomSuspended), http.StatusConflict},
+	}
+
+	for name, test := range tests {
+		svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+		mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+		mockRepo.On("FindById", test.room.ID).Return(test.room, nil)
+
+		_, err := svc.SubmitRoom(context.Background(), DefaultUser_Host.Id, test.room.ID)
+
+		code, _ := internal.MapErrorToHTTP(err)
+		assert.Equal(t, test.code, code, name)
+		mockRepo.AssertNumberOfCalls(t, "UpdateStatusWithAudit", 0)
+	}
+}
+
+func Test_ApproveRoom(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	room := roomWithStatus(internal.RoomPendingReview)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockRepo.On("UpdateStatusWithAudit", room, internal.RoomPendingReview, mock.AnythingOfType("*internal.AuditEntry")).Return(true, nil)
+
+	roomGot, err := svc.ApproveRoom(context.Background(), DefaultUser_Admin.Id, room.ID, " Looks great ")
+
+	assert.NoError(t, err)
+	assert.Equal(t, internal.RoomPublished, roomGot.Status)
+	assert.Equal(t, "Looks great", roomGot.StatusComment)
+
+	// Approving twice, or a suspended room, is not allowed; those are restored.
+	for _, status := range []internal.RoomStatus{internal.RoomPublished, internal.RoomSuspended} {
+		room.Status = status
+		_, err = svc.ApproveRoom(context.Background(), DefaultUser_Admin.Id, room.ID, "")
+
+		code, _ := internal.MapErrorToHTTP(err)
+		assert.Equal(t, http.StatusConflict, code, status)
+	}
+	mockRepo.AssertNumberOfCalls(t, "UpdateStatusWithAudit", 1)
+}
+
+func Test_RejectRoom(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	room := roomWithStatus(internal.RoomPendingReview)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockRepo.On("UpdateStatusWithAudit", room, mock.AnythingOfType("internal.RoomStatus"), mock.AnythingOfType("*internal.AuditEntry")).Return(true, nil)
+
+	_, err := svc.RejectRoom(context.Background(), DefaultUser_Admin.Id, room.ID, " ")
+
+	code, _ := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusBadRequest, code, "a comment is required")
+
+	roomGot, err := svc.RejectRoom(context.Background(), DefaultUser_Admin.Id, room.ID, "Add photos of the bathroom")
+
+	assert.NoError(t, err)
+	assert.Equal(t, internal.RoomDraft, roomGot.Status)
+	assert.Equal(t, "Add photos of the bathroom", roomGot.StatusComment)
+
+	entry := mockRepo.Calls[len(mockRepo.Calls)-1].Arguments.Get(2).(*internal.AuditEntry)
+	assert.Equal(t, internal.AuditRejectRoom, entry.Action)
+	assert.Equal(t, "Add photos of the bathroom", entry.Details)
+}
+
+func Test_ChangeStatus_UpdateFailed(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	room := roomWithStatus(internal.RoomPublished)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockRepo.On("UpdateStatusWithAudit", room, mock.AnythingOfType("internal.RoomStatus"), mock.AnythingOfType("*internal.AuditEntry")).Return(false, fmt.Errorf("some error"))
+
+	_, err := svc.ArchiveRoom(context.Background(), DefaultUser_Host.Id, room.ID)
+
+	assert.Error(t, err)
+	assert.Equal(t, internal.RoomPublished, room.Status)
+}
+
+// Another admin rejected the room after it was read: approving it must not
+// overwrite that.
+func Test_ChangeStatus_ChangedMeanwhile(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	room := roomWithStatus(internal.RoomPendingReview)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockRepo.On("UpdateStatusWithAudit", room, internal.RoomPendingReview, mock.AnythingOfType("*internal.AuditEntry")).Return(false, nil)
+
+	_, err := svc.ApproveRoom(context.Background(), DefaultUser_Admin.Id, room.ID, "")
+
+	code, _ := internal.MapErrorToHTTP(err)
+	assert.Equal(t, http.StatusConflict, code)
+	assert.Equal(t, internal.RoomPendingReview, room.Status)
+}
+
+func Test_FindById_NotPublished(t *testing.T) {
+	for _, status := range []internal.RoomStatus{internal.RoomDraft, internal.RoomPendingReview, internal.RoomArchived} {
+		svc, mockRepo, _, _, _ := CreateTestRoomService()
+		room := roomWithStatus(status)
+		mockRepo.On("FindById", room.ID).Return(room, nil)
+
+		_, err := svc.FindById(context.Background(), room.ID)
+
+		code, _ := internal.MapErrorToHTTP(err)
+		assert.Equal(t, http.StatusNotFound, code, status)
+	}
+}
+
+func Test_FindAvailableRooms_OnlyPublished(t *testing.T) {
+	svc, mockRepo, mockAvailRepo, mockPriceRepo, _ := CreateTestRoomService()
+
+	var rooms []internal.Room
+	for i, status := range []internal.RoomStatus{internal.RoomDraft, internal.RoomPendingReview, internal.RoomPublished, internal.RoomArchived} {
+		room := *roomWithStatus(status)
+		room.ID = uint(i + 1)
+		rooms = append(rooms, room)
+	}
+
+	d := *DefaultRoomsQueryDTO
+	mockRepo.On("FindByFilters", d.GuestsNumber, d.Address, d.Query).Return(rooms, nil)
+	mockAvailRepo.On("FindCurrentListOfRoom", uint(3)).Return(nil, fmt.Errorf("no list"))
+	mockPriceRepo.On("FindCurrentListOfRoom", uint(3)).Return(nil, fmt.Errorf("no list"))
+
+	_, _, err := svc.FindAvailableRooms(context.Background(), d)
+
+	assert.NoError(t, err)
+	mockAvailRepo.AssertCalled(t, "FindCurrentListOfRoom", uint(3))
+	mockAvailRepo.AssertNumberOfCalls(t, "FindCurrentListOfRoom", 1)
+}
+
+func Test_RoomStatusRoutes(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+	send := newUploadServer(t, svc, internal.UploadLimits{MaxFileBytes: 1, MaxRequestBytes: 1, MaxFiles: 1})
+	room := roomWithStatus(internal.RoomDraft)
+	room.HostID = DefaultUser_Host.Id
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockRepo.On("UpdateStatusWithAudit", room, mock.AnythingOfType("internal.RoomStatus"), mock.AnythingOfType("*internal.AuditEntry")).Return(true, nil)
+
+	// [1] The host submits the draft
+
+	req := httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/%d/submit", room.ID), nil)
+	req.Header.Set("Authorization", "Bearer host")
+	w := send(req)
+	assert.Equal(t, http.StatusOK, w.Code)
+
+	var dto internal.RoomDTO
+	assert.NoError(t, json.Unmarshal(w.Body.Bytes(), &dto))
+	assert.Equal(t, "pending_review", dto.Status)
+
+	// [2] Only admins review it
+
+	body, _ := json.Marshal(internal.ReviewRoomDTO{Comment: "ok"})
+	req = httptest.NewRequest(http.MethodPost, fmt.Sprintf("/api/admin/rooms/%d/approve", room.ID), bytes.NewReader(body))
+	req.Header.Set("Authorization", "Bearer host")
+	assert.Equal(t, http.StatusUnauthorized, send(req).Code)
+	assert.Equal(t, internal.RoomPendingReview, room.Status)
+}
+
+func Test_FindLists_OfUnpublishedRoom(t *testing.T) {
+	anonymous := (*uint)(nil)
+	host, guest, admin := DefaultUser_Host.Id, DefaultUser_Guest.Id, DefaultUser_Admin.Id
+
+	tests := []struct {
+		status  internal.RoomStatus
+		deleted bool
+		caller  *uint
+		found   bool
+	}{
+		{internal.RoomPublished, false, anonymous, true},
+		{internal.RoomDraft, false, anonymous, false},
+		{internal.RoomSuspended, false, &guest, false},
+		{internal.RoomDraft, false, &host, true},
+		{internal.RoomPendingReview, false, &admin, true},
+		{internal.RoomSuspended, false, &host, true},
+		{internal.RoomDraft, true, &host, false},
+	}
+
+	for i, test := range tests {
+		svc, mockRepo, mockAvailRepo, mockPriceRepo, mockUserClient := CreateTestRoomService()
+		room := roomWithStatus(test.status)
+		room.Deleted = test.deleted
+		mockRepo.On("FindById", room.ID).Return(room, nil)
+		mockUserClient.On("FindById", context.Background(), guest).Return(DefaultUser_Guest, nil)
+		mockUserClient.On("FindById", context.Background(), admin).Return(DefaultUser_Admin, nil)
+		mockAvailRepo.On("FindListsByRoomId", room.ID).Return([]internal.RoomAvailabilityList{}, nil)
+		mockPriceRepo.On("FindCurrentListOfRoom", room.ID).Return(DefaultPriceList, nil)
+
+		_, listsErr := svc.FindAvailabilityListsByRoomId(context.Background(), test.caller, room.ID)
+		_, currentErr := svc.FindCurrentPriceListOfRoom(context.Background(), test.caller, room.ID)
+
+		name := fmt.Sprintf("case %d: %s", i, test.status)
+		if test.found {
+			assert.NoError(t, listsErr, name)
+			assert.NoError(t, currentErr, name)
+		} else {
+			assert.Equal(t, internal.ErrNotFound("room", room.ID), listsErr, name)
+			assert.Equal(t, internal.ErrNotFound("room", room.ID), currentErr, name)
+		}
+	}
+}
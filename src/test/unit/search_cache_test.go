@@ -0,0 +1,157 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	"context"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+)
+
+func Test_SearchCacheKey_Normalized(t *testing.T) {
+	d1 := *DefaultRoomsQueryDTO
+	d1.Address = "  Room ADDRESS "
+	d1.PageNumber = 1
+
+	d2 := *DefaultRoomsQueryDTO
+	d2.Address = "room address"
+	d2.PageNumber = 3
+	d2.Cursor = "abc"
+
+	assert.Equal(t, internal.SearchCacheKey(d1), internal.SearchCacheKey(d2))
+
+	d2.GuestsNumber++
+	assert.NotEqual(t, internal.SearchCacheKey(d1), internal.SearchCacheKey(d2))
+}
+
+// The address is matched with LIKE, where inner spaces count.
+func Test_SearchCacheKey_KeepsInnerSpacesOfAddress(t *testing.T) {
+	d1 := *DefaultRoomsQueryDTO
+	d1.Address = "Main  St"
+	d1.Query = "sea  view"
+
+	d2 := *DefaultRoomsQueryDTO
+	d2.Address = "Main St"
+	d2.Query = "sea  view"
+
+	assert.NotEqual(t, internal.SearchCacheKey(d1), internal.SearchCacheKey(d2))
+
+	d2.Address = "Main  St"
+	d2.Query = "Sea View"
+	assert.Equal(t, internal.SearchCacheKey(d1), internal.SearchCacheKey(d2))
+}
+
+func Test_MemorySearchCache_GetSet(t *testing.T) {
+	cache := internal.NewMemorySearchCache(time.Minute, 10)
+	hits := []internal.RoomResultDTO{*DefaultRoomResult}
+
+	_, ok := cache.Get("key")
+	assert.False(t, ok)
+
+	cache.Set("key", hits, []uint{1, 2})
+	hitsGot, ok := cache.Get("key")
+	assert.True(t, ok)
+	assert.Equal(t, hits, hitsGot)
+}
+
+func Test_MemorySearchCache_HitsAreCopies(t *testing.T) {
+	cache := internal.NewMemorySearchCache(time.Minute, 10)
+	hit := *DefaultRoomResult
+	hit.Photos = []internal.PhotoDTO{{Key: "a.jpg", URLs: map[string]string{"original": "/images/a.jpg"}}}
+	cache.Set("key", []internal.RoomResultDTO{hit}, []uint{1})
+
+	hitsGot, _ := cache.Get("key")
+	hitsGot[0].Photos[0].URLs["original"] = "changed"
+	hitsGot[0].Photos[0].Caption = "changed"
+
+	hitsGot, _ = cache.Get("key")
+	assert.Equal(t, "/images/a.jpg", hitsGot[0].Photos[0].URLs["original"])
+	assert.Empty(t, hitsGot[0].Photos[0].Caption)
+}
+
+func Test_MemorySearchCache_Expires(t *testing.T) {
+	cache := internal.NewMemorySearchCache(10*time.Millisecond, 10)
+
+	cache.Set("key", []internal.RoomResultDTO{}, []uint{1})
+	time.Sleep(20 * time.Millisecond)
+
+	_, ok := cache.Get("key")
+	assert.False(t, ok)
+}
+
+func Test_MemorySearchCache_EvictsLeastRecentlyUsed(t *testing.T) {
+	cache := internal.NewMemorySearchCache(time.Minute, 2)
+
+	cache.Set("a", []internal.RoomResultDTO{}, nil)
+	cache.Set("b", []internal.RoomResultDTO{}, nil)
+	cache.Get("a")
+	cache.Set("c", []internal.RoomResultDTO{}, nil)
+
+	_, ok := cache.Get("a")
+	assert.True(t, ok)
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	_, ok = cache.Get("c")
+	assert.True(t, ok)
+}
+
+func Test_MemorySearchCache_InvalidateRooms(t *testing.T) {
+	cache := internal.NewMemorySearchCache(time.Minute, 10)
+
+	cache.Set("a", []internal.RoomResultDTO{}, []uint{1, 2})
+	cache.Set("b", []internal.RoomResultDTO{}, []uint{2, 3})
+	cache.Set("c", []internal.RoomResultDTO{}, []uint{4})
+
+	cache.InvalidateRooms(2)
+
+	_, ok := cache.Get("a")
+	assert.False(t, ok)
+	_, ok = cache.Get("b")
+	assert.False(t, ok)
+	_, ok = cache.Get("c")
+	assert.True(t, ok)
+
+	cache.InvalidateAll()
+
+	_, ok = cache.Get("c")
+	assert.False(t, ok)
+}
+
+func Test_FindAvailableRooms_Cached_InvalidatedByAvailabilityUpdate(t *testing.T) {
+	cache := internal.NewMemorySearchCache(time.Minute, 10)
+	svc, mockRepo, mockAvailRepo, mockPriceRepo, mockUserClient := CreateTestRoomService(WithSearchCache(cache))
+
+	room := *DefaultRoom
+	room.ID = 7
+	query := *DefaultRoomsQueryDTO
+
+	mockRepo.On("FindByFilters", query.GuestsNumber, query.Address, query.Query).Return([]internal.Room{room}, nil)
+	mockAvailRepo.On("FindCurrentListOfRoom", room.ID).Return(nil, assert.AnError)
+
+	// [1] Cache miss, then cache hit
+	_, _, err := svc.FindAvailableRooms(context.Background(), query)
+	assert.NoError(t, err)
+	query.PageNumber = 2
+	_, _, err = svc.FindAvailableRooms(context.Background(), query)
+	assert.NoError(t, err)
+
+	mockRepo.AssertNumberOfCalls(t, "FindByFilters", 1)
+
+	// [2] Updating the availability of a candidate room drops the entry
+	mockUserClient.On("FindById", context.Background(), mock.AnythingOfType("uint")).Return(DefaultUser_Host, nil)
+	mockRepo.On("FindById", room.ID).Return(&room, nil)
+	mockAvailRepo.On("CreateList", mock.AnythingOfType("*internal.RoomAvailabilityList")).Return(nil)
+
+	dto := DefaultCreateAvailabilityListDTO
+	dto.RoomID = room.ID
+	_, err = svc.UpdateAvailability(context.Background(), room.HostID, dto)
+	assert.NoError(t, err)
+
+	_, _, err = svc.FindAvailableRooms(context.Background(), query)
+	assert.NoError(t, err)
+
+	mockRepo.AssertNumberOfCalls(t, "FindByFilters", 2)
+	mockPriceRepo.AssertNumberOfCalls(t, "FindCurrentListOfRoom", 0)
+}
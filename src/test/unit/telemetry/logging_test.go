@@ -0,0 +1,131 @@
+package test
+
+import (
+	"bookem-room-service/util"
+	"bytes"
+	"context"
+	"encoding/json"
+	"log/slog"
+	"net/http"
+	"net/http/httptest"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/golang-jwt/jwt/v5"
+	"github.com/stretchr/testify/assert"
+)
+
+func Test_Telemetry_LogLineCarriesContextIDs(t *testing.T) {
+	var buffer bytes.Buffer
+	util.TEL.UseLogHandler(slog.NewJSONHandler(&buffer, nil))
+	_, err := util.TEL.InitTracing(context.Background(), "test", "test", memoryTracing)
+	assert.NoError(t, err)
+
+	ctx, span := util.TEL.Start(context.Background(), "test")
+	defer span.End()
+	ctx = util.WithRequestID(ctx, "request-1")
+	ctx = util.TEL.SetUser(ctx, 7)
+
+	util.TEL.Info(ctx, "hello", "key", "value")
+
+	var line map[string]any
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &line))
+	assert.Equal(t, "hello", line["msg"])
+	assert.Equal(t, "value", line["key"])
+	assert.Equal(t, span.SpanContext().TraceID().String(), line["trace_id"])
+	assert.Equal(t, span.SpanContext().SpanID().String(), line["span_id"])
+	assert.Equal(t, "request-1", line["request_id"])
+	assert.Equal(t, float64(7), line["user_id"])
+}
+
+func Test_Telemetry_LogLineWithoutContextIDs(t *testing.T) {
+	var buffer bytes.Buffer
+	util.TEL.UseLogHandler(slog.NewJSONHandler(&buffer, nil))
+
+	util.TEL.Info(context.Background(), "hello")
+
+	var line map[string]any
+	assert.NoError(t, json.Unmarshal(buffer.Bytes(), &line))
+	assert.NotContains(t, line, "trace_id")
+	assert.NotContains(t, line, "request_id")
+	assert.NotContains(t, line, "user_id")
+}
+
+func Test_RequestIDMiddleware(t *testing.T) {
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(util.RequestIDMiddleware())
+
+	var seen string
+	server.GET("/", func(c *gin.Context) {
+		seen, _ = util.RequestIDFromContext(c.Request.Context())
+	})
+
+	// [1] The caller's ID is kept
+	w := httptest.NewRecorder()
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set(util.RequestIDHeader, "abc")
+	server.ServeHTTP(w, req)
+
+	assert.Equal(t, "abc", seen)
+	assert.Equal(t, "abc", w.Header().Get(util.RequestIDHeader))
+
+	// [2] An ID is generated otherwise
+	w = httptest.NewRecorder()
+	server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, "/", nil))
+
+	assert.NotEmpty(t, seen)
+	assert.NotEqual(t, "abc", seen)
+	assert.Equal(t, seen, w.Header().Get(util.RequestIDHeader))
+}
+
+// The middleware and the handler of a request share a single parse of its JWT.
+func Test_IdentifyUserMiddleware_ParsesOnce(t *testing.T) {
+	parses := 0
+	parseJWT := util.ParseJWT
+	util.ParseJWT = func(token string) (jwt.MapClaims, error) {
+		parses++
+		return jwt.MapClaims{"sub": float64(7), "username": "host", "role": token}, nil
+	}
+	t.Cleanup(func() { util.ParseJWT = parseJWT })
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(util.IdentifyUserMiddleware())
+
+	var userID uint
+	var got *util.Jwt
+	server.GET("/", func(c *gin.Context) {
+		userID, _ = util.UserIDFromContext(c.Request.Context())
+		got, _ = util.GetJwt(c)
+		util.GetJwt(c)
+	})
+
+	req := httptest.NewRequest(http.MethodGet, "/", nil)
+	req.Header.Set("Authorization", "Bearer host")
+	server.ServeHTTP(httptest.NewRecorder(), req)
+
+	assert.Equal(t, 1, parses)
+	assert.Equal(t, uint(7), userID)
+	assert.Equal(t, &util.Jwt{ID: 7, Username: "host", Role: util.Host}, got)
+
+	// Without a token nothing is parsed, and the handler still learns why.
+	parses = 0
+	var err error
+	server.GET("/anonymous", func(c *gin.Context) {
+		_, err = util.GetJwt(c)
+	})
+	server.ServeHTTP(httptest.NewRecorder(), httptest.NewRequest(http.MethodGet, "/anonymous", nil))
+
+	assert.Equal(t, 0, parses)
+	assert.Error(t, err)
+}
+
+func Test_ParseLogLevel(t *testing.T) {
+	level, err := util.ParseLogLevel("warn")
+	assert.NoError(t, err)
+	assert.Equal(t, slog.LevelWarn, level)
+
+	_, err = util.ParseLogLevel("loud")
+	assert.Error(t, err)
+}
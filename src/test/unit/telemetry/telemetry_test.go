@@ -0,0 +1,181 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	. "bookem-room-service/test/unit"
+	"bookem-room-service/util"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"testing"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+	"go.opentelemetry.io/otel/attribute"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+)
+
+var memoryTracing = util.TracingConfig{Exporter: util.ExporterMemory, SampleRatio: 1}
+
+func attributeValue(attrs []attribute.KeyValue, key string) string {
+	for _, kv := range attrs {
+		if string(kv.Key) == key {
+			return kv.Value.Emit()
+		}
+	}
+	return ""
+}
+
+// Run with -race: parallel requests must not share spans, and each request
+// must produce its own trace tree.
+func Test_Telemetry_ParallelRequests_SeparateTraceTrees(t *testing.T) {
+	_, err := util.TEL.InitTracing(context.Background(), "test", "test", memoryTracing)
+	assert.NoError(t, err)
+	recorder := util.TEL.SpanRecorder()
+
+	svc, mockRepo, _, _, _ := CreateTestRoomService()
+
+	const requests = 50
+	for id := uint(1); id <= requests; id++ {
+		mockRepo.On("FindById", id).Return(&internal.Room{ID: id, Status: internal.RoomPublished}, nil)
+	}
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.Use(func(c *gin.Context) {
+		ctx, span := util.TEL.Start(c.Request.Context(), "request", attribute.String("path", c.Request.URL.Path))
+		defer span.End()
+
+		c.Request = c.Request.WithContext(ctx)
+		c.Next()
+	})
+	internal.NewRoute(internal.NewHandler(svc)).Route(server.Group("/api"))
+
+	var wg sync.WaitGroup
+	for id := 1; id <= requests; id++ {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			w := httptest.NewRecorder()
+			server.ServeHTTP(w, httptest.NewRequest(http.MethodGet, fmt.Sprintf("/api/%d", id), nil))
+			assert.Equal(t, http.StatusOK, w.Code)
+		}()
+	}
+	wg.Wait()
+
+	spans := recorder.Ended()
+	assert.Equal(t, 3*requests, len(spans))
+
+	byParent := make(map[string][]sdktrace.ReadOnlySpan)
+	for _, span := range spans {
+		parent := span.Parent().SpanID().String()
+		byParent[parent] = append(byParent[parent], span)
+	}
+
+	for _, root := range spans {
+		if root.Name() != "request" {
+			continue
+		}
+		assert.False(t, root.Parent().IsValid())
+
+		// request -> find-room-by-id-api -> find-room-in-db, all in one trace.
+		apiSpans := byParent[root.SpanContext().SpanID().String()]
+		if !assert.Equal(t, 1, len(apiSpans)) {
+			continue
+		}
+		api := apiSpans[0]
+		assert.Equal(t, "find-room-by-id-api", api.Name())
+		assert.Equal(t, root.SpanContext().TraceID(), api.SpanContext().TraceID())
+
+		dbSpans := byParent[api.SpanContext().SpanID().String()]
+		if !assert.Equal(t, 1, len(dbSpans)) {
+			continue
+		}
+		assert.Equal(t, "find-room-in-db", dbSpans[0].Name())
+		assert.Equal(t, root.SpanContext().TraceID(), dbSpans[0].SpanContext().TraceID())
+
+		// The service logged the room of this very request.
+		path := attributeValue(root.Attributes(), "path")
+		var loggedID string
+		for _, event := range api.Events() {
+			if event.Name == "find room" {
+				loggedID = attributeValue(event.Attributes, "id")
+			}
+		}
+		assert.Equal(t, path, "/api/"+loggedID)
+	}
+}
+
+// The phases of a service call are siblings, each ended before the next one
+// starts.
+func Test_Telemetry_ServicePhasesAreSiblings(t *testing.T) {
+	_, err := util.TEL.InitTracing(context.Background(), "test", "test", memoryTracing)
+	assert.NoError(t, err)
+	recorder := util.TEL.SpanRecorder()
+
+	svc, _, _, _, mockUserClient := CreateTestRoomService()
+	mockUserClient.On("FindById", mock.Anything, DefaultUser_Admin.Id).Return(DefaultUser_Admin, nil)
+
+	ctx, root := util.TEL.Start(context.Background(), "request")
+	_, err = svc.CreateCommodity(ctx, DefaultUser_Admin.Id, internal.CommodityDTO{
+		Key:      "hot-tub",
+		Category: "outdoor",
+		Labels:   map[string]string{"en": "Hot tub"},
+	})
+	root.End()
+	assert.NoError(t, err)
+
+	phases := make([]sdktrace.ReadOnlySpan, 0)
+	for _, span := range recorder.Ended() {
+		if span.Parent().SpanID() == root.SpanContext().SpanID() {
+			phases = append(phases, span)
+		}
+	}
+
+	names := make([]string, 0, len(phases))
+	for i, phase := range phases {
+		names = append(names, phase.Name())
+		if i > 0 {
+			assert.False(t, phase.StartTime().Before(phases[i-1].EndTime()), "%s overlaps %s", phase.Name(), phases[i-1].Name())
+		}
+	}
+	assert.Equal(t, []string{"validate-user", "validate-commodity", "create-commodity-in-db"}, names)
+}
+
+func Test_Telemetry_InitTracing_None(t *testing.T) {
+	_, err := util.TEL.InitTracing(context.Background(), "test", "test", util.TracingConfig{Exporter: util.ExporterNone, SampleRatio: 1})
+	assert.NoError(t, err)
+
+	ctx, span := util.TEL.Start(context.Background(), "test")
+	defer span.End()
+
+	assert.Equal(t, context.Background(), ctx)
+	assert.False(t, span.IsRecording())
+	assert.Nil(t, util.TEL.SpanRecorder())
+}
+
+func Test_Telemetry_InitTracing_InvalidConfig(t *testing.T) {
+	_, err := util.TEL.InitTracing(context.Background(), "test", "test", util.TracingConfig{Exporter: "carrier-pigeon", SampleRatio: 1})
+	assert.Error(t, err)
+
+	_, err = util.TEL.InitTracing(context.Background(), "test", "test", util.TracingConfig{Exporter: util.ExporterMemory, SampleRatio: 2})
+	assert.Error(t, err)
+
+	// Tracing is disabled after a failed init.
+	ctx, _ := util.TEL.Start(context.Background(), "test")
+	assert.Equal(t, context.Background(), ctx)
+}
+
+func Test_Telemetry_InitTracing_SampleRatio(t *testing.T) {
+	_, err := util.TEL.InitTracing(context.Background(), "test", "test", util.TracingConfig{Exporter: util.ExporterMemory, SampleRatio: 0})
+	assert.NoError(t, err)
+
+	_, span := util.TEL.Start(context.Background(), "not-sampled")
+	span.End()
+
+	assert.False(t, span.SpanContext().IsSampled())
+	assert.Empty(t, util.TEL.SpanRecorder().Ended())
+}
@@ -0,0 +1,157 @@
+package test
+
+import (
+	"bookem-room-service/internal"
+	"context"
+	"fmt"
+	"net/http"
+	"strings"
+	"testing"
+
+	"github.com/stretchr/testify/assert"
+	mock "github.com/stretchr/testify/mock"
+)
+
+func roomWithPhotos(keys ...string) *internal.Room {
+	room := *DefaultRoom
+	room.Photos = nil
+	for _, key := range keys {
+		room.Photos = append(room.Photos, internal.Photo{Key: key})
+	}
+	return &room
+}
+
+func Test_UpdatePhotos_Success(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+
+	room := roomWithPhotos("a.jpg", "b.jpg", "c.jpg")
+	room.Photos[0].Cover = true
+
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockRepo.On("UpdateDetachingPhotos", mock.AnythingOfType("*internal.Room"), []string{}).Return(nil)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	roomGot, err := svc.UpdatePhotos(context.Background(), DefaultUser_Host.Id, room.ID, internal.UpdatePhotosDTO{
+		Photos: []internal.PhotoMetadataDTO{
+			{Key: "c.jpg", Caption: " Terrace ", AltText: "A terrace facing the sea"},
+			{Key: "a.jpg"},
+			{Key: "b.jpg", Caption: "Kitchen"},
+		},
+		Cover: "b.jpg",
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.Photo{
+		{Key: "c.jpg", Caption: "Terrace", AltText: "A terrace facing the sea"},
+		{Key: "a.jpg"},
+		{Key: "b.jpg", Caption: "Kitchen", Cover: true},
+	}, roomGot.Photos)
+	mockRepo.AssertNumberOfCalls(t, "UpdateDetachingPhotos", 1)
+}
+
+func Test_UpdatePhotos_RemovesLeftOut(t *testing.T) {
+	images := NewFakeImageStore()
+	blobs := NewFakePhotoBlobRepo()
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithBlobRepo(blobs))
+
+	room := roomWithPhotos("a.jpg", "b.jpg", "c.jpg")
+	for _, photo := range room.Photos {
+		assert.NoError(t, blobs.Acquire(&internal.PhotoBlob{Key: photo.Key}))
+		assert.NoError(t, images.Put(context.Background(), photo.Key, []byte("image"), "image/jpeg"))
+	}
+
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockRepo.On("UpdateDetachingPhotos", mock.AnythingOfType("*internal.Room"), []string{"a.jpg", "c.jpg"}).Return(nil)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	roomGot, err := svc.UpdatePhotos(context.Background(), DefaultUser_Host.Id, room.ID, internal.UpdatePhotosDTO{
+		Photos: []internal.PhotoMetadataDTO{{Key: "b.jpg"}},
+	})
+
+	assert.NoError(t, err)
+	assert.Equal(t, []internal.Photo{{Key: "b.jpg"}}, roomGot.Photos)
+	assert.Equal(t, []string{"b.jpg"}, images.Keys())
+	assert.Equal(t, 0, blobs.RefCount("a.jpg"))
+	assert.Equal(t, 1, blobs.RefCount("b.jpg"))
+	mockRepo.AssertExpectations(t)
+}
+
+func Test_UpdatePhotos_RemoveFails(t *testing.T) {
+	images := NewFakeImageStore()
+	blobs := NewFakePhotoBlobRepo()
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService(WithImageStore(images), WithBlobRepo(blobs))
+
+	room := roomWithPhotos("a.jpg", "b.jpg")
+	for _, photo := range room.Photos {
+		assert.NoError(t, blobs.Acquire(&internal.PhotoBlob{Key: photo.Key}))
+		assert.NoError(t, images.Put(context.Background(), photo.Key, []byte("image"), "image/jpeg"))
+	}
+
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockRepo.On("UpdateDetachingPhotos", mock.AnythingOfType("*internal.Room"), []string{"b.jpg"}).Return(fmt.Errorf("db error"))
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	_, err := svc.UpdatePhotos(context.Background(), DefaultUser_Host.Id, room.ID, internal.UpdatePhotosDTO{
+		Photos: []internal.PhotoMetadataDTO{{Key: "a.jpg"}},
+	})
+
+	assert.Error(t, err)
+	assert.Equal(t, []string{"a.jpg", "b.jpg"}, images.Keys(), "nothing is released if the room is not saved")
+	assert.Equal(t, 1, blobs.RefCount("b.jpg"))
+}
+
+func Test_UpdatePhotos_Rejects(t *testing.T) {
+	tests := map[string]internal.UpdatePhotosDTO{
+		"unknown photo": {Photos: []internal.PhotoMetadataDTO{{Key: "a.jpg"}, {Key: "x.jpg"}}},
+		"listed twice":  {Photos: []internal.PhotoMetadataDTO{{Key: "a.jpg"}, {Key: "a.jpg"}}},
+		"unknown cover": {Photos: []internal.PhotoMetadataDTO{{Key: "a.jpg"}, {Key: "b.jpg"}}, Cover: "x.jpg"},
+		"long caption":  {Photos: []internal.PhotoMetadataDTO{{Key: "a.jpg", Caption: strings.Repeat("a", 201)}, {Key: "b.jpg"}}},
+		"long alt text": {Photos: []internal.PhotoMetadataDTO{{Key: "a.jpg"}, {Key: "b.jpg", AltText: strings.Repeat("a", 251)}}},
+	}
+
+	for name, dto := range tests {
+		svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+		room := roomWithPhotos("a.jpg", "b.jpg")
+
+		mockRepo.On("FindById", room.ID).Return(room, nil)
+		mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+		_, err := svc.UpdatePhotos(context.Background(), DefaultUser_Host.Id, room.ID, dto)
+
+		code, _ := internal.MapErrorToHTTP(err)
+		assert.Equal(t, http.StatusBadRequest, code, name)
+		mockRepo.AssertNumberOfCalls(t, "UpdateDetachingPhotos", 0)
+	}
+}
+
+func Test_UpdatePhotos_NotOwner(t *testing.T) {
+	svc, mockRepo, _, _, mockUserClient := CreateTestRoomService()
+
+	room := roomWithPhotos("a.jpg")
+	room.HostID = DefaultUser_Host.Id + 1
+
+	mockRepo.On("FindById", room.ID).Return(room, nil)
+	mockUserClient.On("FindById", context.Background(), DefaultUser_Host.Id).Return(DefaultUser_Host, nil)
+
+	_, err := svc.UpdatePhotos(context.Background(), DefaultUser_Host.Id, room.ID, internal.UpdatePhotosDTO{
+		Photos: []internal.PhotoMetadataDTO{{Key: "a.jpg"}},
+	})
+
+	assert.Equal(t, internal.ErrUnauthorized, err)
+	mockRepo.AssertNumberOfCalls(t, "UpdateDetachingPhotos", 0)
+}
+
+func Test_NewRoomResultDTO_CoverFirst(t *testing.T) {
+	room := roomWithPhotos("a.jpg", "b.jpg", "c.jpg")
+	room.Photos[2].Cover = true
+
+	result := internal.NewRoomResultDTO(*room, false, 10, 10)
+
+	keys := []string{}
+	for _, photo := range result.Photos {
+		keys = append(keys, photo.Key)
+	}
+	assert.Equal(t, []string{"c.jpg", "a.jpg", "b.jpg"}, keys)
+	assert.True(t, result.Photos[0].Cover)
+	assert.Equal(t, "c.jpg", room.Photos[2].Key, "the room keeps its order")
+}
@@ -0,0 +1,200 @@
+package test
+
+import (
+	"bookem-room-service/client/userclient"
+	"context"
+	"fmt"
+	"net/http"
+	"net/http/httptest"
+	"sync"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/gin-gonic/gin"
+	"github.com/stretchr/testify/assert"
+)
+
+// fakeUserClient knows the users in roles and counts its calls. If gate is
+// set, calls block until it is closed.
+type fakeUserClient struct {
+	roles map[uint]string
+	calls atomic.Int32
+	gate  chan struct{}
+	err   error
+}
+
+func (f *fakeUserClient) FindById(ctx context.Context, id uint) (*userclient.UserDTO, error) {
+	f.calls.Add(1)
+	if f.gate != nil {
+		<-f.gate
+	}
+	if f.err != nil {
+		return nil, f.err
+	}
+	role, ok := f.roles[id]
+	if !ok {
+		return nil, fmt.Errorf("%w: %d", userclient.ErrUserNotFound, id)
+	}
+	return &userclient.UserDTO{Id: id, Role: role}, nil
+}
+
+func (f *fakeUserClient) Ping(ctx context.Context) error { return nil }
+
+func Test_UserCache_HitAfterMiss(t *testing.T) {
+	next := &fakeUserClient{roles: map[uint]string{1: "host"}}
+	cache := userclient.NewCachingUserClient(next, time.Minute, time.Minute, 0)
+
+	for range 3 {
+		user, err := cache.FindById(context.Background(), 1)
+		assert.NoError(t, err)
+		assert.Equal(t, "host", user.Role)
+	}
+
+	assert.Equal(t, int32(1), next.calls.Load())
+}
+
+func Test_UserCache_ReturnsCopies(t *testing.T) {
+	next := &fakeUserClient{roles: map[uint]string{1: "host"}}
+	cache := userclient.NewCachingUserClient(next, time.Minute, time.Minute, 0)
+
+	user, _ := cache.FindById(context.Background(), 1)
+	user.Role = "guest"
+
+	user, _ = cache.FindById(context.Background(), 1)
+	assert.Equal(t, "host", user.Role)
+}
+
+func Test_UserCache_Expires(t *testing.T) {
+	next := &fakeUserClient{roles: map[uint]string{1: "host"}}
+	cache := userclient.NewCachingUserClient(next, 10*time.Millisecond, time.Minute, 0)
+
+	cache.FindById(context.Background(), 1)
+	time.Sleep(20 * time.Millisecond)
+	cache.FindById(context.Background(), 1)
+
+	assert.Equal(t, int32(2), next.calls.Load())
+}
+
+func Test_UserCache_NegativeCaching(t *testing.T) {
+	next := &fakeUserClient{roles: map[uint]string{}}
+	cache := userclient.NewCachingUserClient(next, time.Minute, time.Minute, 0)
+
+	for range 3 {
+		_, err := cache.FindById(context.Background(), 7)
+		assert.ErrorIs(t, err, userclient.ErrUserNotFound)
+	}
+
+	assert.Equal(t, int32(1), next.calls.Load())
+}
+
+func Test_UserCache_UnavailableIsNotCached(t *testing.T) {
+	next := &fakeUserClient{err: userclient.ErrUserServiceUnavailable}
+	cache := userclient.NewCachingUserClient(next, time.Minute, time.Minute, 0)
+
+	for range 2 {
+		_, err := cache.FindById(context.Background(), 1)
+		assert.ErrorIs(t, err, userclient.ErrUserServiceUnavailable)
+	}
+
+	assert.Equal(t, int32(2), next.calls.Load())
+}
+
+func Test_UserCache_SizeBound(t *testing.T) {
+	next := &fakeUserClient{roles: map[uint]string{1: "host", 2: "host", 3: "host"}}
+	cache := userclient.NewCachingUserClient(next, time.Minute, time.Minute, 2)
+
+	cache.FindById(context.Background(), 1)
+	cache.FindById(context.Background(), 2)
+	cache.FindById(context.Background(), 1) // 2 is now the least recently used.
+	cache.FindById(context.Background(), 3) // Evicts 2.
+	assert.Equal(t, int32(3), next.calls.Load())
+
+	cache.FindById(context.Background(), 1)
+	assert.Equal(t, int32(3), next.calls.Load())
+	cache.FindById(context.Background(), 2)
+	assert.Equal(t, int32(4), next.calls.Load())
+}
+
+func Test_UserCache_ConcurrentLookupsShareOneCall(t *testing.T) {
+	next := &fakeUserClient{roles: map[uint]string{1: "host"}, gate: make(chan struct{})}
+	cache := userclient.NewCachingUserClient(next, time.Minute, time.Minute, 0)
+
+	var wg sync.WaitGroup
+	for range 20 {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			user, err := cache.FindById(context.Background(), 1)
+			assert.NoError(t, err)
+			assert.Equal(t, uint(1), user.Id)
+		}()
+	}
+
+	// Let the lookups pile up on the blocked call, then release it.
+	assert.Eventually(t, func() bool { return next.calls.Load() == 1 }, time.Second, time.Millisecond)
+	time.Sleep(20 * time.Millisecond)
+	close(next.gate)
+	wg.Wait()
+
+	assert.Equal(t, int32(1), next.calls.Load())
+}
+
+func Test_UserCache_Invalidate(t *testing.T) {
+	next := &fakeUserClient{roles: map[uint]string{1: "host", 2: "guest"}}
+	cache := userclient.NewCachingUserClient(next, time.Minute, time.Minute, 0)
+
+	cache.FindById(context.Background(), 1)
+	cache.FindById(context.Background(), 2)
+
+	cache.Invalidate(1)
+	cache.FindById(context.Background(), 1)
+	cache.FindById(context.Background(), 2)
+	assert.Equal(t, int32(3), next.calls.Load())
+
+	cache.InvalidateAll()
+	cache.FindById(context.Background(), 1)
+	cache.FindById(context.Background(), 2)
+	assert.Equal(t, int32(5), next.calls.Load())
+}
+
+func Test_UserCache_InvalidationHandler(t *testing.T) {
+	next := &fakeUserClient{roles: map[uint]string{1: "host"}}
+	cache := userclient.NewCachingUserClient(next, time.Minute, time.Minute, 0)
+
+	gin.SetMode(gin.TestMode)
+	server := gin.New()
+	server.DELETE("/internal/user-cache/*id", cache.InvalidationHandler("secret"))
+
+	invalidate := func(path string, token string) int {
+		req := httptest.NewRequest(http.MethodDelete, path, nil)
+		req.Header.Set("Authorization", "Bearer "+token)
+		w := httptest.NewRecorder()
+		server.ServeHTTP(w, req)
+		return w.Code
+	}
+
+	cache.FindById(context.Background(), 1)
+
+	// [1] Wrong token
+
+	assert.Equal(t, http.StatusUnauthorized, invalidate("/internal/user-cache/1", "guess"))
+	cache.FindById(context.Background(), 1)
+	assert.Equal(t, int32(1), next.calls.Load())
+
+	// [2] One user
+
+	assert.Equal(t, http.StatusNoContent, invalidate("/internal/user-cache/1", "secret"))
+	cache.FindById(context.Background(), 1)
+	assert.Equal(t, int32(2), next.calls.Load())
+
+	// [3] Every user
+
+	assert.Equal(t, http.StatusNoContent, invalidate("/internal/user-cache/", "secret"))
+	cache.FindById(context.Background(), 1)
+	assert.Equal(t, int32(3), next.calls.Load())
+
+	// [4] Bad ID
+
+	assert.Equal(t, http.StatusBadRequest, invalidate("/internal/user-cache/abc", "secret"))
+}
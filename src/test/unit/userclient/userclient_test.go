@@ -0,0 +1,155 @@
+package test
+
+import (
+	"bookem-room-service/client/userclient"
+	"bookem-room-service/config"
+	"context"
+	"net/http"
+	"net/http/httptest"
+	"sync/atomic"
+	"testing"
+	"time"
+
+	"github.com/stretchr/testify/assert"
+)
+
+// userService serves the given statuses in order, repeating the last one.
+// 200 responses carry a user.
+func userService(t *testing.T, statuses ...int) (*httptest.Server, *atomic.Int32) {
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		call := int(calls.Add(1)) - 1
+		status := statuses[min(call, len(statuses)-1)]
+		w.WriteHeader(status)
+		if status == http.StatusOK {
+			w.Write([]byte(`{"id": 1, "username": "host", "role": "host"}`))
+		}
+	}))
+	t.Cleanup(server.Close)
+	return server, &calls
+}
+
+func clientConfig(baseURL string) config.UserServiceConfig {
+	return config.UserServiceConfig{
+		BaseURL:          baseURL,
+		Timeout:          config.Duration(time.Second),
+		MaxRetries:       2,
+		RetryBackoff:     config.Duration(time.Millisecond),
+		BreakerThreshold: 3,
+		BreakerCooldown:  config.Duration(time.Minute),
+	}
+}
+
+func Test_UserClient_FindById_OK(t *testing.T) {
+	server, calls := userService(t, http.StatusOK)
+	client := userclient.NewUserClient(clientConfig(server.URL), nil)
+
+	user, err := client.FindById(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.Equal(t, "host", user.Username)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func Test_UserClient_FindById_NotFoundIsNotRetried(t *testing.T) {
+	server, calls := userService(t, http.StatusNotFound)
+	client := userclient.NewUserClient(clientConfig(server.URL), nil)
+
+	_, err := client.FindById(context.Background(), 1)
+
+	assert.ErrorIs(t, err, userclient.ErrUserNotFound)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func Test_UserClient_FindById_RetriesServerErrors(t *testing.T) {
+	server, calls := userService(t, http.StatusBadGateway, http.StatusServiceUnavailable, http.StatusOK)
+	client := userclient.NewUserClient(clientConfig(server.URL), nil)
+
+	user, err := client.FindById(context.Background(), 1)
+
+	assert.NoError(t, err)
+	assert.NotNil(t, user)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func Test_UserClient_FindById_RetriesExhausted(t *testing.T) {
+	server, calls := userService(t, http.StatusInternalServerError)
+	client := userclient.NewUserClient(clientConfig(server.URL), nil)
+
+	_, err := client.FindById(context.Background(), 1)
+
+	assert.ErrorIs(t, err, userclient.ErrUserServiceUnavailable)
+	assert.Equal(t, int32(3), calls.Load())
+}
+
+func Test_UserClient_FindById_OtherClientErrorIsNotRetried(t *testing.T) {
+	server, calls := userService(t, http.StatusBadRequest)
+	client := userclient.NewUserClient(clientConfig(server.URL), nil)
+
+	_, err := client.FindById(context.Background(), 1)
+
+	assert.Error(t, err)
+	assert.NotErrorIs(t, err, userclient.ErrUserNotFound)
+	assert.NotErrorIs(t, err, userclient.ErrUserServiceUnavailable)
+	assert.Equal(t, int32(1), calls.Load())
+}
+
+func Test_UserClient_FindById_Timeout(t *testing.T) {
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		<-r.Context().Done()
+	}))
+	defer server.Close()
+
+	cfg := clientConfig(server.URL)
+	cfg.Timeout = config.Duration(20 * time.Millisecond)
+	cfg.MaxRetries = 0
+	client := userclient.NewUserClient(cfg, nil)
+
+	start := time.Now()
+	_, err := client.FindById(context.Background(), 1)
+
+	assert.ErrorIs(t, err, userclient.ErrUserServiceUnavailable)
+	assert.Less(t, time.Since(start), time.Second)
+}
+
+func Test_UserClient_BreakerOpensAndRecovers(t *testing.T) {
+	var status atomic.Int32
+	status.Store(http.StatusInternalServerError)
+	var calls atomic.Int32
+	server := httptest.NewServer(http.HandlerFunc(func(w http.ResponseWriter, r *http.Request) {
+		calls.Add(1)
+		w.WriteHeader(int(status.Load()))
+		w.Write([]byte(`{"id": 1}`))
+	}))
+	defer server.Close()
+
+	cfg := clientConfig(server.URL)
+	cfg.MaxRetries = 0
+	cfg.BreakerCooldown = config.Duration(50 * time.Millisecond)
+	client := userclient.NewUserClient(cfg, nil)
+
+	// [1] Threshold failures open the breaker
+
+	for range 3 {
+		_, err := client.FindById(context.Background(), 1)
+		assert.ErrorIs(t, err, userclient.ErrUserServiceUnavailable)
+	}
+	assert.Equal(t, int32(3), calls.Load())
+
+	// [2] Calls fail fast while open
+
+	_, err := client.FindById(context.Background(), 1)
+	assert.ErrorIs(t, err, userclient.ErrUserServiceUnavailable)
+	assert.Equal(t, int32(3), calls.Load())
+
+	// [3] After the cooldown, a successful trial closes it
+
+	time.Sleep(60 * time.Millisecond)
+	status.Store(http.StatusOK)
+
+	_, err = client.FindById(context.Background(), 1)
+	assert.NoError(t, err)
+	_, err = client.FindById(context.Background(), 1)
+	assert.NoError(t, err)
+	assert.Equal(t, int32(5), calls.Load())
+}
@@ -3,13 +3,47 @@ package test
 import (
 	"bookem-room-service/client/userclient"
 	"bookem-room-service/internal"
+	"bookem-room-service/storage"
 	"context"
+	"fmt"
+	"sort"
+	"sync"
 	"time"
 
 	mock "github.com/stretchr/testify/mock"
 )
 
-func CreateTestRoomService() (
+// ServiceOption replaces one of the dependencies CreateTestRoomService
+// defaults to.
+type ServiceOption func(*internal.ServiceDeps)
+
+func WithImageStore(imageStore storage.ImageStore) ServiceOption {
+	return func(deps *internal.ServiceDeps) { deps.ImageStore = imageStore }
+}
+
+func WithPhotoRepo(photoRepo internal.RoomPhotoRepo) ServiceOption {
+	return func(deps *internal.ServiceDeps) { deps.PhotoRepo = photoRepo }
+}
+
+func WithBlobRepo(blobRepo internal.PhotoBlobRepo) ServiceOption {
+	return func(deps *internal.ServiceDeps) { deps.BlobRepo = blobRepo }
+}
+
+func WithCommodityRepo(commodityRepo internal.CommodityRepo) ServiceOption {
+	return func(deps *internal.ServiceDeps) { deps.CommodityRepo = commodityRepo }
+}
+
+func WithAuditRepo(auditRepo internal.AuditRepo) ServiceOption {
+	return func(deps *internal.ServiceDeps) { deps.AuditRepo = auditRepo }
+}
+
+func WithSearchCache(cache internal.SearchCache) ServiceOption {
+	return func(deps *internal.ServiceDeps) { deps.SearchCache = cache }
+}
+
+// CreateTestRoomService creates a service on mocked repos and user client,
+// and on fakes for everything else, unless opts replace them.
+func CreateTestRoomService(opts ...ServiceOption) (
 	internal.Service,
 	*MockRoomRepo,
 	*MockRoomAvailabilityRepo,
@@ -21,7 +55,22 @@ func CreateTestRoomService() (
 	mockRoomPriceRepo := new(MockRoomPriceRepo)
 	mockUserClient := new(MockUserClient)
 
-	svc := internal.NewService(mockRepo, mockRoomAvailRepo, mockRoomPriceRepo, mockUserClient)
+	deps := internal.ServiceDeps{
+		Repo:             mockRepo,
+		AvailabilityRepo: mockRoomAvailRepo,
+		PriceRepo:        mockRoomPriceRepo,
+		PhotoRepo:        new(MockRoomPhotoRepo),
+		BlobRepo:         NewFakePhotoBlobRepo(),
+		CommodityRepo:    NewFakeCommodityRepo(DefaultCommodities...),
+		AuditRepo:        NewFakeAuditRepo(),
+		UserClient:       mockUserClient,
+		ImageStore:       NewFakeImageStore(),
+	}
+	for _, opt := range opts {
+		opt(&deps)
+	}
+
+	svc := internal.NewService(deps)
 	return svc, mockRepo, mockRoomAvailRepo, mockRoomPriceRepo, mockUserClient
 }
 
@@ -36,11 +85,43 @@ func (r *MockRoomRepo) Create(room *internal.Room) error {
 	return args.Error(0)
 }
 
+func (r *MockRoomRepo) CreateWithPhotos(room *internal.Room, photoIds []uint) error {
+	args := r.Called(room, photoIds)
+	return args.Error(0)
+}
+
 func (r *MockRoomRepo) Update(room *internal.Room) error {
 	args := r.Called(room)
 	return args.Error(0)
 }
 
+func (r *MockRoomRepo) UpdateWithPhotos(room *internal.Room, photoIds []uint) error {
+	args := r.Called(room, photoIds)
+	return args.Error(0)
+}
+
+func (r *MockRoomRepo) UpdateDetachingPhotos(room *internal.Room, removed []string) error {
+	args := r.Called(room, removed)
+	return args.Error(0)
+}
+
+func (r *MockRoomRepo) FindAllPhotos() ([]internal.Photo, error) {
+	args := r.Called()
+	photos, _ := args.Get(0).([]internal.Photo)
+	return photos, args.Error(1)
+}
+
+func (r *MockRoomRepo) UpdateStatusWithAudit(room *internal.Room, from internal.RoomStatus, entry *internal.AuditEntry) (bool, error) {
+	args := r.Called(room, from, entry)
+	return args.Bool(0), args.Error(1)
+}
+
+func (r *MockRoomRepo) FindAll() ([]internal.Room, error) {
+	args := r.Called()
+	rooms, _ := args.Get(0).([]internal.Room)
+	return rooms, args.Error(1)
+}
+
 func (r *MockRoomRepo) Delete(room *internal.Room) error {
 	args := r.Called(room)
 	return args.Error(0)
@@ -52,14 +133,19 @@ func (r *MockRoomRepo) FindById(id uint) (*internal.Room, error) {
 	return user, args.Error(1)
 }
 
+func (r *MockUserClient) Ping(context context.Context) error {
+	args := r.Called(context)
+	return args.Error(0)
+}
+
 func (r *MockRoomRepo) FindByHost(hostId uint) ([]internal.Room, error) {
 	args := r.Called(uint(hostId))
 	user, _ := args.Get(0).([]internal.Room)
 	return user, args.Error(1)
 }
 
-func (r *MockRoomRepo) FindByFilters(guestsNumber uint, location string) ([]internal.Room, error) {
-	args := r.Called(uint(guestsNumber), string(location))
+func (r *MockRoomRepo) FindByFilters(guestsNumber uint, location string, text string) ([]internal.Room, error) {
+	args := r.Called(uint(guestsNumber), string(location), string(text))
 	rooms, _ := args.Get(0).([]internal.Room)
 	return rooms, args.Error(1)
 }
@@ -127,6 +213,217 @@ func (m *MockRoomPriceRepo) FindCurrentListOfRoom(roomId uint) (*internal.RoomPr
 	return list, args.Error(1)
 }
 
+// ----------------------------------------------- Mock Room photo repo
+
+type MockRoomPhotoRepo struct {
+	mock.Mock
+}
+
+func (m *MockRoomPhotoRepo) Create(photo *internal.RoomPhoto) error {
+	args := m.Called(photo)
+	return args.Error(0)
+}
+
+func (m *MockRoomPhotoRepo) DeleteStaged(photo *internal.RoomPhoto) (bool, error) {
+	args := m.Called(photo)
+	return args.Bool(0), args.Error(1)
+}
+
+func (m *MockRoomPhotoRepo) FindByIds(ids []uint) ([]internal.RoomPhoto, error) {
+	args := m.Called(ids)
+	photos, _ := args.Get(0).([]internal.RoomPhoto)
+	return photos, args.Error(1)
+}
+
+func (m *MockRoomPhotoRepo) FindAll() ([]internal.RoomPhoto, error) {
+	args := m.Called()
+	photos, _ := args.Get(0).([]internal.RoomPhoto)
+	return photos, args.Error(1)
+}
+
+func (m *MockRoomPhotoRepo) FindStagedBefore(t time.Time) ([]internal.RoomPhoto, error) {
+	args := m.Called(t)
+	photos, _ := args.Get(0).([]internal.RoomPhoto)
+	return photos, args.Error(1)
+}
+
+// ----------------------------------------------- Fake photo blob repo
+
+// FakePhotoBlobRepo counts references in memory.
+type FakePhotoBlobRepo struct {
+	mu    sync.Mutex
+	Blobs map[string]*internal.PhotoBlob
+}
+
+func NewFakePhotoBlobRepo() *FakePhotoBlobRepo {
+	return &FakePhotoBlobRepo{Blobs: make(map[string]*internal.PhotoBlob)}
+}
+
+func (r *FakePhotoBlobRepo) Acquire(blob *internal.PhotoBlob) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if existing, ok := r.Blobs[blob.Key]; ok {
+		existing.RefCount++
+		return nil
+	}
+	blob.RefCount = 1
+	r.Blobs[blob.Key] = blob
+	return nil
+}
+
+func (r *FakePhotoBlobRepo) Release(key string, unreferenced func(blob *internal.PhotoBlob)) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	blob, ok := r.Blobs[key]
+	if !ok {
+		return nil
+	}
+	blob.RefCount--
+	if blob.RefCount == 0 {
+		delete(r.Blobs, key)
+		unreferenced(blob)
+	}
+	return nil
+}
+
+// RefCount returns the references to the blob with the given key.
+func (r *FakePhotoBlobRepo) RefCount(key string) int {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if blob, ok := r.Blobs[key]; ok {
+		return blob.RefCount
+	}
+	return 0
+}
+
+// ----------------------------------------------- Fake commodity repo
+
+// FakeCommodityRepo keeps the catalog in memory. Rooms holds the number of
+// rooms having each commodity.
+type FakeCommodityRepo struct {
+	mu          sync.Mutex
+	Commodities map[string]internal.Commodity
+	Rooms       map[string]int64
+}
+
+func NewFakeCommodityRepo(commodities ...internal.Commodity) *FakeCommodityRepo {
+	repo := &FakeCommodityRepo{Commodities: make(map[string]internal.Commodity), Rooms: make(map[string]int64)}
+	for _, commodity := range commodities {
+		repo.Commodities[commodity.Key] = commodity
+	}
+	return repo
+}
+
+func (r *FakeCommodityRepo) Create(commodity *internal.Commodity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	if _, ok := r.Commodities[commodity.Key]; ok {
+		return fmt.Errorf("duplicate key %s", commodity.Key)
+	}
+	r.Commodities[commodity.Key] = *commodity
+	return nil
+}
+
+func (r *FakeCommodityRepo) Update(commodity *internal.Commodity) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	r.Commodities[commodity.Key] = *commodity
+	return nil
+}
+
+func (r *FakeCommodityRepo) Delete(key string) error {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	delete(r.Commodities, key)
+	return nil
+}
+
+func (r *FakeCommodityRepo) FindByKey(key string) (*internal.Commodity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	commodity, ok := r.Commodities[key]
+	if !ok {
+		return nil, fmt.Errorf("record not found")
+	}
+	return &commodity, nil
+}
+
+func (r *FakeCommodityRepo) FindByKeys(keys []string) ([]internal.Commodity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	var commodities []internal.Commodity
+	for _, key := range keys {
+		if commodity, ok := r.Commodities[key]; ok {
+			commodities = append(commodities, commodity)
+		}
+	}
+	return commodities, nil
+}
+
+func (r *FakeCommodityRepo) FindAll() ([]internal.Commodity, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	commodities := make([]internal.Commodity, 0, len(r.Commodities))
+	for _, commodity := range r.Commodities {
+		commodities = append(commodities, commodity)
+	}
+	sort.Slice(commodities, func(i, j int) bool {
+		if commodities[i].Category != commodities[j].Category {
+			return commodities[i].Category < commodities[j].Category
+		}
+		return commodities[i].Key < commodities[j].Key
+	})
+	return commodities, nil
+}
+
+func (r *FakeCommodityRepo) CountRooms(key string) (int64, error) {
+	r.mu.Lock()
+	defer r.mu.Unlock()
+
+	return r.Rooms[key], nil
+}
+
+// ----------------------------------------------- Fake audit repo
+
+type FakeAuditRepo struct {
+	Entries []internal.AuditEntry
+	// CreateErr, if set, is returned by Create instead of recording.
+	CreateErr error
+}
+
+func NewFakeAuditRepo() *FakeAuditRepo {
+	return &FakeAuditRepo{}
+}
+
+func (r *FakeAuditRepo) Create(entry *internal.AuditEntry) error {
+	if r.CreateErr != nil {
+		return r.CreateErr
+	}
+	entry.ID = uint(len(r.Entries) + 1)
+	entry.CreatedAt = time.Now()
+	r.Entries = append(r.Entries, *entry)
+	return nil
+}
+
+func (r *FakeAuditRepo) FindByRoom(roomId uint) ([]internal.AuditEntry, error) {
+	var entries []internal.AuditEntry
+	for _, entry := range r.Entries {
+		if entry.RoomID != nil && *entry.RoomID == roomId {
+			entries = append(entries, entry)
+		}
+	}
+	return entries, nil
+}
+
 // ----------------------------------------------- Mock user client
 
 type MockUserClient struct {
@@ -139,6 +436,69 @@ func (r *MockUserClient) FindById(context context.Context, id uint) (*userclient
 	return user, args.Error(1)
 }
 
+// ----------------------------------------------- Fake image store
+
+// FakeImageStore keeps images in memory. Put fails with PutErr once
+// FailAfter images are stored, if PutErr is set. Modified holds when each
+// image was stored.
+type FakeImageStore struct {
+	mu        sync.Mutex
+	Images    map[string][]byte
+	Modified  map[string]time.Time
+	PutErr    error
+	FailAfter int
+}
+
+func NewFakeImageStore() *FakeImageStore {
+	return &FakeImageStore{Images: make(map[string][]byte), Modified: make(map[string]time.Time)}
+}
+
+func (s *FakeImageStore) Put(ctx context.Context, key string, data []byte, contentType string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	if s.PutErr != nil && len(s.Images) >= s.FailAfter {
+		return s.PutErr
+	}
+	s.Images[key] = data
+	s.Modified[key] = time.Now()
+	return nil
+}
+
+func (s *FakeImageStore) Delete(ctx context.Context, key string) error {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	delete(s.Images, key)
+	delete(s.Modified, key)
+	return nil
+}
+
+func (s *FakeImageStore) Check(ctx context.Context) error { return nil }
+
+func (s *FakeImageStore) List(ctx context.Context) ([]storage.StoredImage, error) {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	images := make([]storage.StoredImage, 0, len(s.Images))
+	for key := range s.Images {
+		images = append(images, storage.StoredImage{Key: key, LastModified: s.Modified[key]})
+	}
+	return images, nil
+}
+
+func (s *FakeImageStore) Keys() []string {
+	s.mu.Lock()
+	defer s.mu.Unlock()
+
+	keys := make([]string, 0, len(s.Images))
+	for key := range s.Images {
+		keys = append(keys, key)
+	}
+	sort.Strings(keys)
+	return keys
+}
+
 // ----------------------------------------------- Mock data
 
 const (
@@ -154,9 +514,10 @@ var DefaultRoom = &internal.Room{
 	Address:     "Room Address",
 	MinGuests:   1,
 	MaxGuests:   5,
-	Photos:      []string{"test.png"},
-	Commodities: []string{"WiFi"},
+	Photos:      []internal.Photo{{Key: "room-0-0.jpg"}},
+	Commodities: []string{"wifi"},
 	Deleted:     false,
+	Status:      internal.RoomPublished,
 }
 
 var DefaultRoomDTO = internal.RoomDTO{
@@ -166,7 +527,7 @@ var DefaultRoomDTO = internal.RoomDTO{
 	Address:     DefaultRoom.Address,
 	MinGuests:   DefaultRoom.MinGuests,
 	MaxGuests:   DefaultRoom.MaxGuests,
-	Photos:      DefaultRoom.Photos,
+	Photos:      internal.NewPhotoDTOs(DefaultRoom.Photos),
 	Commodities: DefaultRoom.Commodities,
 	Deleted:     DefaultRoom.Deleted,
 }
@@ -183,6 +544,11 @@ var DefaultRoomCreateDTO = internal.CreateRoomDTO{
 	Deleted:       DefaultRoom.Deleted,
 }
 
+var DefaultCommodities = []internal.Commodity{
+	{Key: "wifi", Category: "connectivity", Icon: "wifi", Labels: map[string]string{"en": "Wi-Fi"}},
+	{Key: "parking", Category: "outdoor", Icon: "local_parking", Labels: map[string]string{"en": "Free parking"}},
+}
+
 var DefaultUser_Guest = &userclient.UserDTO{
 	Id:       1,
 	Username: "guser",
@@ -202,6 +568,16 @@ var DefaultUser_Host = &userclient.UserDTO{
 	Role:     "host",
 	Address:  "hAddress 123",
 }
+
+var DefaultUser_Admin = &userclient.UserDTO{
+	Id:       3,
+	Username: "auser",
+	Email:    "aemail@mail.com",
+	Name:     "aname",
+	Surname:  "asurname",
+	Role:     "admin",
+	Address:  "aAddress 123",
+}
 var DefaultAvailabilityItem = internal.RoomAvailabilityItem{
 	ID:        1,
 	DateFrom:  time.Date(2025, 8, 20, 0, 0, 0, 0, time.UTC),
@@ -298,7 +674,7 @@ var DefaultRoomResult = &internal.RoomResultDTO{
 	Name:        "Room Name",
 	Description: "Room Desc",
 	Address:     "Room Address",
-	Photos:      []string{"test.png"},
+	Photos:      internal.NewPhotoDTOs([]internal.Photo{{Key: "test.png"}}),
 	UnitPrice:   100.0,
 	TotalPrice:  200.0,
 }
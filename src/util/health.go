@@ -0,0 +1,133 @@
+package util
+
+import (
+	"context"
+	"net/http"
+	"sync"
+	"sync/atomic"
+	"time"
+
+	"github.com/gin-gonic/gin"
+)
+
+const (
+	HealthStatusOK          = "ok"
+	HealthStatusUnavailable = "unavailable"
+)
+
+// HealthCheck reports whether a dependency is usable. It should respect ctx,
+// which is cancelled once the check timeout elapses.
+type HealthCheck func(ctx context.Context) error
+
+type namedHealthCheck struct {
+	name  string
+	check HealthCheck
+}
+
+type HealthCheckResult struct {
+	Status     string `json:"status"`
+	Error      string `json:"error,omitempty"`
+	DurationMs int64  `json:"durationMs"`
+}
+
+type HealthReport struct {
+	Status string                       `json:"status"`
+	Checks map[string]HealthCheckResult `json:"checks,omitempty"`
+	// Reason is set when the service reports itself unavailable regardless of
+	// its dependencies, e.g. while shutting down.
+	Reason string `json:"reason,omitempty"`
+}
+
+// Health serves the liveness and readiness probes. Liveness only tells that
+// the process is serving requests; readiness runs every registered check.
+type Health struct {
+	timeout  time.Duration
+	checks   []namedHealthCheck
+	shutdown atomic.Bool
+}
+
+// NewHealth returns a Health whose checks each get at most timeout.
+func NewHealth(timeout time.Duration) *Health {
+	return &Health{timeout: timeout}
+}
+
+// AddCheck registers a readiness check. Not safe to call while serving.
+func (h *Health) AddCheck(name string, check HealthCheck) {
+	h.checks = append(h.checks, namedHealthCheck{name, check})
+}
+
+// ShuttingDown makes readiness fail from now on, so that load balancers stop
+// routing new requests while in-flight ones are drained.
+func (h *Health) ShuttingDown() {
+	h.shutdown.Store(true)
+}
+
+// Check runs every check concurrently and reports on each of them.
+func (h *Health) Check(ctx context.Context) HealthReport {
+	report := HealthReport{Status: HealthStatusOK}
+	if h.shutdown.Load() {
+		report.Status = HealthStatusUnavailable
+		report.Reason = "shutting down"
+		return report
+	}
+
+	results := make([]HealthCheckResult, len(h.checks))
+	var wg sync.WaitGroup
+	for i, check := range h.checks {
+		wg.Add(1)
+		go func() {
+			defer wg.Done()
+			results[i] = h.run(ctx, check.check)
+		}()
+	}
+	wg.Wait()
+
+	report.Checks = make(map[string]HealthCheckResult, len(h.checks))
+	for i, check := range h.checks {
+		report.Checks[check.name] = results[i]
+		if results[i].Status != HealthStatusOK {
+			report.Status = HealthStatusUnavailable
+		}
+	}
+	return report
+}
+
+func (h *Health) run(ctx context.Context, check HealthCheck) HealthCheckResult {
+	ctx, cancel := context.WithTimeout(ctx, h.timeout)
+	defer cancel()
+
+	start := time.Now()
+	err := check(ctx)
+	if err == nil && ctx.Err() != nil {
+		err = ctx.Err()
+	}
+
+	result := HealthCheckResult{Status: HealthStatusOK, DurationMs: time.Since(start).Milliseconds()}
+	if err != nil {
+		result.Status = HealthStatusUnavailable
+		result.Error = err.Error()
+	}
+	return result
+}
+
+// LivenessHandler answers 200 as long as the process can serve requests. It
+// checks no dependencies, so that an outage of one does not get the service
+// restarted.
+func (h *Health) LivenessHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		ctx.JSON(http.StatusOK, HealthReport{Status: HealthStatusOK})
+	}
+}
+
+// ReadinessHandler answers 200 if every check passes and 503 otherwise, with
+// a report per dependency.
+func (h *Health) ReadinessHandler() gin.HandlerFunc {
+	return func(ctx *gin.Context) {
+		report := h.Check(ctx.Request.Context())
+		if report.Status != HealthStatusOK {
+			ctx.JSON(http.StatusServiceUnavailable, report)
+			return
+		}
+		ctx.JSON(http.StatusOK, report)
+	}
+}
@@ -1,55 +1,146 @@
 package util
 
 import (
+	"bytes"
 	"encoding/base64"
 	"fmt"
-	"os"
+	"image"
+	"image/jpeg"
+	"image/png"
+	"math"
+	"net/http"
 	"strings"
+
+	"golang.org/x/image/draw"
 )
 
-const IMG_DIRECTORY = "/app/images/" // Should this be a configurable variable?
+// ImageLimits bound the photos that are accepted.
+type ImageLimits struct {
+	MaxBytes  int64
+	MaxWidth  int
+	MaxHeight int
+}
+
+var imageLimits = ImageLimits{
+	MaxBytes:  10 << 20,
+	MaxWidth:  8000,
+	MaxHeight: 8000,
+}
+
+func SetImageLimits(limits ImageLimits) {
+	imageLimits = limits
+}
+
+// jpegQuality is the quality JPEG photos are re-encoded with.
+const jpegQuality = 90
+
+// Image is a photo that passed ProcessImage, ready to be stored.
+type Image struct {
+	Data        []byte
+	ContentType string
+	// Extension includes the leading dot.
+	Extension string
+	Width     int
+	Height    int
+
+	pixels image.Image
+}
 
-// SaveImageB64 saves a b64 image with a MIME type onto permanent storage.
-// Returns (full_path, relative_path, error) `full_path` is the full path on the
-// device where the image was saved. `relative_path` is the image path relative
-// to IMG_DIRECTORY, including file name and extension.
-var SaveImageB64 = func(base64Image string, filename string) (string, string, error) {
+// DecodeImageB64 decodes a b64 image with a MIME type, as sent by the
+// frontend ("data:image/png;base64,..."). The MIME type is not trusted, see
+// ProcessImage.
+func DecodeImageB64(base64Image string) ([]byte, error) {
 	// [1] Split payload
 
 	parts := strings.Split(base64Image, ",")
 	if len(parts) != 2 {
-		return "", "", fmt.Errorf("invalid base64 image format")
+		return nil, fmt.Errorf("invalid base64 image format")
 	}
 
-	mimeType := parts[0] // "data:image/png;base64"
-	data := parts[1]     // Image (as base64)
-
 	// [2] Decode image from B64
 
-	imgBytes, err := base64.StdEncoding.DecodeString(data)
+	imgBytes, err := base64.StdEncoding.DecodeString(parts[1])
 	if err != nil {
-		return "", "", fmt.Errorf("could not decode base64 image, %v", err)
+		return nil, fmt.Errorf("could not decode base64 image, %v", err)
 	}
+	return imgBytes, nil
+}
 
-	// [3] Determine file type
+// ProcessImage checks that data is a PNG or JPEG image within the limits, by
+// its content rather than by what the client claims, and re-encodes it. Only
+// the pixels survive re-encoding, so metadata (EXIF, GPS location) and
+// anything smuggled after the image are dropped.
+func ProcessImage(data []byte) (*Image, error) {
+	limits := imageLimits
 
-	var extension string
-	if strings.Contains(mimeType, "image/png") {
-		extension = ".png"
-	} else if strings.Contains(mimeType, "image/jpeg") {
-		extension = ".jpg"
-	} else {
-		return "", "", fmt.Errorf("unknown MIME type %s", mimeType)
+	// [1] Size and format
+
+	if int64(len(data)) > limits.MaxBytes {
+		return nil, fmt.Errorf("image is larger than %d bytes", limits.MaxBytes)
 	}
 
-	// [4] Save image
+	contentType := http.DetectContentType(data)
+	if contentType != "image/png" && contentType != "image/jpeg" {
+		return nil, fmt.Errorf("unsupported image type %s, expected PNG or JPEG", contentType)
+	}
+
+	// [2] Dimensions, read from the header so that a small file claiming
+	// huge dimensions is never decoded
 
-	path_relative := filename + extension
-	path := IMG_DIRECTORY + filename + extension
-	err = os.WriteFile(path, imgBytes, 0644)
+	config, format, err := image.DecodeConfig(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not read image, %v", err)
+	}
+	if "image/"+format != contentType {
+		return nil, fmt.Errorf("image header does not match its content")
+	}
+	if config.Width > limits.MaxWidth || config.Height > limits.MaxHeight {
+		return nil, fmt.Errorf("image is %dx%d, larger than %dx%d", config.Width, config.Height, limits.MaxWidth, limits.MaxHeight)
+	}
+
+	// [3] Re-encode
+
+	img, _, err := image.Decode(bytes.NewReader(data))
+	if err != nil {
+		return nil, fmt.Errorf("could not decode image, %v", err)
+	}
+
+	return encodeImage(img, contentType)
+}
+
+// Resize returns a copy of the image scaled down, keeping its aspect ratio,
+// to fit within maxWidth x maxHeight. It returns nil if the image fits already.
+func (img *Image) Resize(maxWidth int, maxHeight int) (*Image, error) {
+	if img.Width <= maxWidth && img.Height <= maxHeight {
+		return nil, nil
+	}
+
+	scale := min(float64(maxWidth)/float64(img.Width), float64(maxHeight)/float64(img.Height))
+	width := max(1, int(math.Round(float64(img.Width)*scale)))
+	height := max(1, int(math.Round(float64(img.Height)*scale)))
+
+	resized := image.NewRGBA(image.Rect(0, 0, width, height))
+	draw.CatmullRom.Scale(resized, resized.Bounds(), img.pixels, img.pixels.Bounds(), draw.Src, nil)
+
+	return encodeImage(resized, img.ContentType)
+}
+
+func encodeImage(pixels image.Image, contentType string) (*Image, error) {
+	var out bytes.Buffer
+	var err error
+	bounds := pixels.Bounds()
+	result := &Image{ContentType: contentType, Width: bounds.Dx(), Height: bounds.Dy(), pixels: pixels}
+	if contentType == "image/png" {
+		err = png.Encode(&out, pixels)
+		result.Extension = ".png"
+	} else {
+		err = jpeg.Encode(&out, pixels, &jpeg.Options{Quality: jpegQuality})
+		result.Extension = ".jpg"
+	}
 	if err != nil {
-		return "", "", err
+		return nil, fmt.Errorf("could not encode image, %v", err)
 	}
 
-	return path, path_relative, nil
+	result.Data = out.Bytes()
+	return result, nil
 }
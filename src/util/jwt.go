@@ -1,27 +1,50 @@
 package util
 
 import (
+	"context"
+	"crypto/rsa"
 	"fmt"
 	"os"
 
 	"github.com/golang-jwt/jwt/v5"
 )
 
-var JWT_PUBLIC_KEY_PATH = os.Getenv("JWT_PUBLIC_KEY_PATH")
+var jwtPublicKeyPath string
 
-var ParseJWT = parseJWT
+// SetJWTPublicKeyPath sets the PEM file holding the public key that JWTs are
+// verified with.
+func SetJWTPublicKeyPath(path string) {
+	jwtPublicKeyPath = path
+}
 
-// ParseJWT validates and extracts claims from an encoded JWT string.
-func parseJWT(tokenString string) (jwt.MapClaims, error) {
-	publicKeyData, err := os.ReadFile(JWT_PUBLIC_KEY_PATH)
+// CheckJWTPublicKey reports whether the public key can be loaded, i.e.
+// whether JWTs can be verified at all.
+func CheckJWTPublicKey(ctx context.Context) error {
+	_, err := loadJWTPublicKey()
+	return err
+}
+
+func loadJWTPublicKey() (*rsa.PublicKey, error) {
+	publicKeyData, err := os.ReadFile(jwtPublicKeyPath)
 	if err != nil {
-		return nil, fmt.Errorf("could not open public key %s: %w", JWT_PUBLIC_KEY_PATH, err)
+		return nil, fmt.Errorf("could not open public key %s: %w", jwtPublicKeyPath, err)
 	}
 
 	publicKey, err := jwt.ParseRSAPublicKeyFromPEM(publicKeyData)
 	if err != nil {
 		return nil, fmt.Errorf("could not parse public key: %w", err)
 	}
+	return publicKey, nil
+}
+
+var ParseJWT = parseJWT
+
+// ParseJWT validates and extracts claims from an encoded JWT string.
+func parseJWT(tokenString string) (jwt.MapClaims, error) {
+	publicKey, err := loadJWTPublicKey()
+	if err != nil {
+		return nil, err
+	}
 
 	token, err := jwt.Parse(tokenString, func(token *jwt.Token) (interface{}, error) {
 		if _, ok := token.Method.(*jwt.SigningMethodRSA); !ok {
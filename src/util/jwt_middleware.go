@@ -23,34 +23,51 @@ type Jwt struct {
 	Role     UserRole
 }
 
-func GetJwtString(ctx *gin.Context) (string, error) {
+// jwtClaimsKey keys the outcome of parsing the JWT of a request in its gin
+// context, see requestClaims.
+const jwtClaimsKey = "jwt-claims"
+
+type parsedJwt struct {
+	claims jwt.MapClaims
+	err    error
+}
+
+// requestClaims parses the JWT of the request on first use and keeps the
+// outcome in the gin context, so that the middleware and the handler of a
+// request share a single parse.
+func requestClaims(ctx *gin.Context) (jwt.MapClaims, error) {
+	if value, ok := ctx.Get(jwtClaimsKey); ok {
+		parsed := value.(parsedJwt)
+		return parsed.claims, parsed.err
+	}
+
+	claims, err := parseRequestJwt(ctx)
+	ctx.Set(jwtClaimsKey, parsedJwt{claims, err})
+	return claims, err
+}
+
+func parseRequestJwt(ctx *gin.Context) (jwt.MapClaims, error) {
 	header := ctx.GetHeader("Authorization")
 	if header == "" {
-		return "", fmt.Errorf("Unauthenticated")
+		return nil, fmt.Errorf("Unauthenticated")
 	}
 
 	if !strings.HasPrefix(header, "Bearer ") {
-		return "", errors.New("invalid authorization model (must be Bearer)")
+		return nil, errors.New("invalid authorization model (must be Bearer)")
 	}
 
-	jwt := strings.SplitN(header, "Bearer ", 2)[1]
-	_, err := ParseJWT(jwt)
-
-	return jwt, err
+	return ParseJWT(strings.SplitN(header, "Bearer ", 2)[1])
 }
 
-func GetJwtData(ctx *gin.Context) (jwt.MapClaims, error) {
-	jwtString, err := GetJwtString(ctx)
-	if err != nil {
-		return nil, err
-	}
-
-	jwt, err := ParseJWT(jwtString)
-	if err != nil {
-		return nil, err
+func GetJwtString(ctx *gin.Context) (string, error) {
+	if _, err := requestClaims(ctx); err != nil {
+		return "", err
 	}
+	return strings.SplitN(ctx.GetHeader("Authorization"), "Bearer ", 2)[1], nil
+}
 
-	return jwt, err
+func GetJwtData(ctx *gin.Context) (jwt.MapClaims, error) {
+	return requestClaims(ctx)
 }
 
 // GetJwt returns the JWT data embedded in the request header. If the user is
@@ -84,3 +101,18 @@ func GetJwtFromString(jwtString string) (*Jwt, error) {
 
 	return &jwt, nil
 }
+
+// IdentifyUserMiddleware records the caller in the request context if the
+// request carries a valid JWT, so that the logs and spans of the request name
+// the user. It does not reject any request, handlers still check the JWT, but
+// GetJwt reuses the claims parsed here.
+func IdentifyUserMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		if claims, err := requestClaims(c); err == nil {
+			if sub, ok := claims["sub"].(float64); ok {
+				c.Request = c.Request.WithContext(TEL.SetUser(c.Request.Context(), uint(sub)))
+			}
+		}
+		c.Next()
+	}
+}
@@ -0,0 +1,105 @@
+package util
+
+import (
+	"context"
+	"crypto/rand"
+	"encoding/hex"
+	"fmt"
+	"log/slog"
+	"strings"
+
+	"github.com/gin-gonic/gin"
+	"go.opentelemetry.io/otel/trace"
+)
+
+const RequestIDHeader = "X-Request-ID"
+
+type requestIDKey struct{}
+type userIDKey struct{}
+
+// WithRequestID returns a context carrying the request ID, which is then
+// added to every log line written with that context.
+func WithRequestID(ctx context.Context, id string) context.Context {
+	return context.WithValue(ctx, requestIDKey{}, id)
+}
+
+func RequestIDFromContext(ctx context.Context) (string, bool) {
+	id, ok := ctx.Value(requestIDKey{}).(string)
+	return id, ok
+}
+
+// WithUserID returns a context carrying the ID of the calling user, which is
+// then added to every log line written with that context.
+func WithUserID(ctx context.Context, id uint) context.Context {
+	return context.WithValue(ctx, userIDKey{}, id)
+}
+
+func UserIDFromContext(ctx context.Context) (uint, bool) {
+	id, ok := ctx.Value(userIDKey{}).(uint)
+	return id, ok
+}
+
+// RequestIDMiddleware puts the request ID into the request context and echoes
+// it in the response. The ID is taken from the X-Request-ID header, or
+// generated if the caller did not send one.
+func RequestIDMiddleware() gin.HandlerFunc {
+	return func(c *gin.Context) {
+		id := c.GetHeader(RequestIDHeader)
+		if id == "" || len(id) > 128 {
+			id = newRequestID()
+		}
+
+		c.Header(RequestIDHeader, id)
+		c.Request = c.Request.WithContext(WithRequestID(c.Request.Context(), id))
+		c.Next()
+	}
+}
+
+func newRequestID() string {
+	bytes := make([]byte, 16)
+	_, _ = rand.Read(bytes)
+	return hex.EncodeToString(bytes)
+}
+
+// ParseLogLevel parses a level name (debug, info, warn, error).
+func ParseLogLevel(name string) (slog.Level, error) {
+	var level slog.Level
+	if err := level.UnmarshalText([]byte(strings.TrimSpace(name))); err != nil {
+		return slog.LevelDebug, fmt.Errorf("invalid log level %q", name)
+	}
+	return level, nil
+}
+
+// contextHandler adds the IDs found in the context (trace, span, request and
+// user) to every record before passing it on.
+type contextHandler struct {
+	slog.Handler
+}
+
+func NewContextHandler(handler slog.Handler) slog.Handler {
+	return contextHandler{handler}
+}
+
+func (h contextHandler) Handle(ctx context.Context, record slog.Record) error {
+	if spanCtx := trace.SpanContextFromContext(ctx); spanCtx.IsValid() {
+		record.AddAttrs(
+			slog.String("trace_id", spanCtx.TraceID().String()),
+			slog.String("span_id", spanCtx.SpanID().String()),
+		)
+	}
+	if id, ok := RequestIDFromContext(ctx); ok {
+		record.AddAttrs(slog.String("request_id", id))
+	}
+	if id, ok := UserIDFromContext(ctx); ok {
+		record.AddAttrs(slog.Uint64("user_id", uint64(id)))
+	}
+	return h.Handler.Handle(ctx, record)
+}
+
+func (h contextHandler) WithAttrs(attrs []slog.Attr) slog.Handler {
+	return contextHandler{h.Handler.WithAttrs(attrs)}
+}
+
+func (h contextHandler) WithGroup(name string) slog.Handler {
+	return contextHandler{h.Handler.WithGroup(name)}
+}
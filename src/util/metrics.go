@@ -0,0 +1,26 @@
+package util
+
+import (
+	"errors"
+
+	"github.com/prometheus/client_golang/prometheus"
+)
+
+// RegisterCollector registers collector with the default Prometheus registry
+// and returns it. If an equal collector is already registered (e.g. when a
+// middleware is constructed twice in tests), the registered one is returned
+// instead of panicking like prometheus.MustRegister.
+func RegisterCollector[T prometheus.Collector](collector T) T {
+	err := prometheus.Register(collector)
+	if err == nil {
+		return collector
+	}
+
+	var alreadyRegistered prometheus.AlreadyRegisteredError
+	if errors.As(err, &alreadyRegistered) {
+		if existing, ok := alreadyRegistered.ExistingCollector.(T); ok {
+			return existing
+		}
+	}
+	panic(err)
+}
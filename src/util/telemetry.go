@@ -7,6 +7,7 @@ import (
 	"log/slog"
 	"net/http"
 	"os"
+	"path/filepath"
 	"time"
 
 	"github.com/gin-gonic/gin"
@@ -14,27 +15,24 @@ import (
 	"go.opentelemetry.io/otel"
 	"go.opentelemetry.io/otel/attribute"
 	"go.opentelemetry.io/otel/codes"
-	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
 	"go.opentelemetry.io/otel/propagation"
-	"go.opentelemetry.io/otel/sdk/resource"
-	sdktrace "go.opentelemetry.io/otel/sdk/trace"
-	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
 	"go.opentelemetry.io/otel/trace"
 )
 
-type SpanPair struct {
-	Ctx  context.Context
-	Span trace.Span
-}
-
+// Telemetry wraps the logger and the tracer of the service.
+//
+// Spans are carried in a context.Context: Start returns a context holding the
+// new span, which is then passed down the call chain. Logging methods take the
+// context so that the message is also recorded as an event of its span. None
+// of this is shared between requests, so it is safe for concurrent use.
 type Telemetry struct {
-	// During tests, the tracer is not set up, so we silently ignore tracing.
-	// This has to be done manually (i.e. don't call tracer methods, don't touch
-	// spans etc.)
+	// Without a tracer (in unit tests, or with the "none" exporter), tracing
+	// is silently ignored. This has to be done manually (i.e. don't call
+	// tracer methods, don't touch spans etc.)
 	tracerReady bool
 	Tracer      trace.Tracer
-
-	SpanStack []SpanPair
+	recorder    *tracetest.SpanRecorder
 
 	loggerReady bool
 	logger      *slog.Logger
@@ -42,10 +40,10 @@ type Telemetry struct {
 
 var TEL Telemetry
 
-func (t *Telemetry) Init(ctx context.Context, serviceName, deploymentEnvironment string) func(context.Context) error {
+func (t *Telemetry) Init(ctx context.Context, serviceName, deploymentEnvironment string, logging LogConfig, tracing TracingConfig) func(context.Context) error {
 	// [0] Init logger
 	{
-		err := t.initLogger()
+		err := t.initLogger(logging)
 		if err != nil {
 			// log.Printf instead of the logger here!
 			log.Printf("Could not initialize logger: %v", err)
@@ -54,55 +52,68 @@ func (t *Telemetry) Init(ctx context.Context, serviceName, deploymentEnvironment
 	}
 	// [1] Init tracer
 	{
-		exp, err := otlptracehttp.New(ctx)
+		shutdown, err := t.InitTracing(ctx, serviceName, deploymentEnvironment, tracing)
 		if err != nil {
-			log.Fatalf("Failed to create an OTLP HTTP exporter: %v", err)
+			t.Error(ctx, "could not initialize tracing, spans will not be exported", err, "exporter", tracing.Exporter)
 		}
+		return shutdown
+	}
+}
 
-		tp := sdktrace.NewTracerProvider(
-			sdktrace.WithBatcher(exp),
-			sdktrace.WithResource(resource.NewWithAttributes(
-				semconv.SchemaURL,
-				semconv.ServiceName(serviceName),
-				semconv.DeploymentEnvironment(deploymentEnvironment),
-			)),
-		)
-		otel.SetTracerProvider(tp)
-		otel.SetTextMapPropagator(propagation.TraceContext{})
+type LogConfig struct {
+	// Level is the minimum level: debug, info, warn or error.
+	Level string `json:"level"`
+	// File is the path of the JSON log file. Empty disables the file.
+	File string `json:"file"`
+}
 
-		t.Tracer = otel.Tracer(serviceName)
-		t.tracerReady = true
-		return tp.Shutdown
-	}
+func (c LogConfig) Validate() error {
+	_, err := ParseLogLevel(c.Level)
+	return err
 }
 
-func (t *Telemetry) initLogger() error {
-	err := os.MkdirAll("/app/logs", 0755)
+// initLogger sets up a logger writing text to stdout and JSON to a file.
+func (t *Telemetry) initLogger(config LogConfig) error {
+	level, err := ParseLogLevel(config.Level)
 	if err != nil {
 		return err
 	}
 
-	logFile, err := os.OpenFile("/app/logs/app.log", os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
-	if err != nil {
-		return err
+	options := &slog.HandlerOptions{Level: level}
+	handlers := []slog.Handler{slog.NewTextHandler(os.Stdout, options)}
+
+	if config.File != "" {
+		err := os.MkdirAll(filepath.Dir(config.File), 0755)
+		if err != nil {
+			return err
+		}
+
+		logFile, err := os.OpenFile(config.File, os.O_CREATE|os.O_WRONLY|os.O_APPEND, 0666)
+		if err != nil {
+			return err
+		}
+		handlers = append(handlers, slog.NewJSONHandler(logFile, options))
 	}
 
-	t.logger = slog.New(
-		slogmulti.Fanout(
-			slog.NewTextHandler(os.Stdout, &slog.HandlerOptions{Level: slog.LevelDebug}),
-			slog.NewJSONHandler(logFile, &slog.HandlerOptions{Level: slog.LevelDebug}),
-		),
-	)
+	t.UseLogHandler(slogmulti.Fanout(handlers...))
+	t.Debug(context.Background(), "Logger initialized", "level", level.String(), "file", config.File)
+	return nil
+}
 
+// UseLogHandler makes the telemetry log to handler. Every record is enriched
+// with the trace, span, request and user IDs found in its context.
+func (t *Telemetry) UseLogHandler(handler slog.Handler) {
+	t.logger = slog.New(NewContextHandler(handler))
 	t.loggerReady = true
-	t.Debug("Logger initialized")
-	return nil
 }
 
 func (t *Telemetry) GetLoggingMiddleware() gin.HandlerFunc {
 	return func(c *gin.Context) {
 		c.Next()
-		t.logger.Info("request",
+		if !t.loggerReady {
+			return
+		}
+		t.logger.InfoContext(c.Request.Context(), "request",
 			slog.String("method", c.Request.Method),
 			slog.String("path", c.Request.URL.Path),
 			slog.Int("status", c.Writer.Status()),
@@ -113,101 +124,127 @@ func (t *Telemetry) GetLoggingMiddleware() gin.HandlerFunc {
 	}
 }
 
-func (t *Telemetry) Push(ctx context.Context, name string, attrs ...attribute.KeyValue) {
-	if t.tracerReady {
-		newCtx, span := t.Tracer.Start(ctx, name, trace.WithAttributes(attrs...))
-		t.SpanStack = append(t.SpanStack, SpanPair{Ctx: newCtx, Span: span})
-	} else {
-		newCtx := ctx
-		var span trace.Span
-		t.SpanStack = append(t.SpanStack, SpanPair{Ctx: newCtx, Span: span})
-	}
+// UseTracerProvider makes the telemetry create spans with a tracer of tp. Init
+// calls it with the exporting provider, tests may call it with a recording one.
+func (t *Telemetry) UseTracerProvider(tp trace.TracerProvider, name string) {
+	t.Tracer = tp.Tracer(name)
+	t.tracerReady = true
 }
 
-func (t *Telemetry) Pop() {
-	top := t.SpanStack[len(t.SpanStack)-1]
-	if t.tracerReady {
-		top.Span.End()
+// Start starts a span as a child of the span in ctx and returns a context
+// holding it. The caller must end the span:
+//
+//	ctx, span := util.TEL.Start(ctx, "name")
+//	defer span.End()
+//
+// A function made of phases starts each of them from the context it was
+// given, so that they are siblings, and ends each one where it ends. The
+// deferred End covers early returns; ending a span twice does nothing.
+//
+//	reqCtx := ctx
+//	ctx, span := util.TEL.Start(reqCtx, "validate")
+//	defer span.End()
+//	...
+//	span.End()
+//
+//	ctx, span = util.TEL.Start(reqCtx, "save")
+//	defer span.End()
+//
+// If the tracer is not set up, ctx is returned as is, along with the
+// (non-recording) span it already holds.
+func (t *Telemetry) Start(ctx context.Context, name string, attrs ...attribute.KeyValue) (context.Context, trace.Span) {
+	if !t.tracerReady {
+		return ctx, trace.SpanFromContext(ctx)
 	}
-	t.SpanStack = t.SpanStack[:len(t.SpanStack)-1]
-}
-
-func (t *Telemetry) Top() SpanPair {
-	return t.SpanStack[len(t.SpanStack)-1]
+	return t.Tracer.Start(ctx, name, trace.WithAttributes(attrs...))
 }
 
-func (t *Telemetry) Ctx() context.Context {
-	if len(t.SpanStack) > 0 {
-		return t.Top().Ctx
-	} else {
-		return context.Background() // Ehh...
+func (t *Telemetry) SetAttrib(ctx context.Context, kv ...attribute.KeyValue) {
+	if span := t.currentSpan(ctx); span != nil {
+		span.SetAttributes(kv...)
 	}
 }
 
-func (t *Telemetry) SetAttrib(kv ...attribute.KeyValue) {
-	if t.tracerReady {
-		t.Top().Span.SetAttributes(kv...)
-	}
+// SetUser records the calling user on the span in ctx and returns a context
+// that adds the user ID to log lines.
+func (t *Telemetry) SetUser(ctx context.Context, id uint) context.Context {
+	t.SetAttrib(ctx, attribute.String("user.id", fmt.Sprintf("%d", id)))
+	return WithUserID(ctx, id)
 }
 
-func (t *Telemetry) SetUser(id uint) {
-	if t.tracerReady {
-		t.Top().Span.SetAttributes(attribute.String("user.id", fmt.Sprintf("%d", id)))
-	}
-}
-
-func (t *Telemetry) Inject(outgoingRequest *http.Request) {
-	otel.GetTextMapPropagator().Inject(t.Ctx(), propagation.HeaderCarrier(outgoingRequest.Header))
+// Inject propagates the span in ctx to an outgoing request.
+func (t *Telemetry) Inject(ctx context.Context, outgoingRequest *http.Request) {
+	otel.GetTextMapPropagator().Inject(ctx, propagation.HeaderCarrier(outgoingRequest.Header))
 }
 
-func (t *Telemetry) Info(msg string, attrs ...any) {
+func (t *Telemetry) Info(ctx context.Context, msg string, attrs ...any) {
 	if t.loggerReady {
-		t.logger.Info(msg, attrs...)
+		t.logger.InfoContext(ctx, msg, attrs...)
 	}
-	if span := t.currentSpan(); span != nil {
-		span.AddEvent(msg)
+	if span := t.currentSpan(ctx); span != nil {
+		span.AddEvent(msg, trace.WithAttributes(eventAttributes(attrs)...))
 	}
 }
 
-func (t *Telemetry) Warn(msg string, attrs ...any) {
+func (t *Telemetry) Warn(ctx context.Context, msg string, attrs ...any) {
 	if t.loggerReady {
-		t.logger.Warn(msg, attrs...)
+		t.logger.WarnContext(ctx, msg, attrs...)
 	}
-	if span := t.currentSpan(); span != nil {
-		span.AddEvent(msg)
+	if span := t.currentSpan(ctx); span != nil {
+		span.AddEvent(msg, trace.WithAttributes(eventAttributes(attrs)...))
 	}
 }
 
-func (t *Telemetry) Debug(msg string, attrs ...any) {
+func (t *Telemetry) Debug(ctx context.Context, msg string, attrs ...any) {
 	if t.loggerReady {
-		t.logger.Debug(msg, attrs...)
+		t.logger.DebugContext(ctx, msg, attrs...)
 	}
-	if span := t.currentSpan(); span != nil {
-		span.AddEvent(msg)
+	if span := t.currentSpan(ctx); span != nil {
+		span.AddEvent(msg, trace.WithAttributes(eventAttributes(attrs)...))
 	}
 }
 
-func (t *Telemetry) Error(msg string, err error, attrs ...any) {
+func (t *Telemetry) Error(ctx context.Context, msg string, err error, attrs ...any) {
 	if t.loggerReady {
+		logAttrs := attrs
 		if err != nil {
-			attrs = append(attrs, slog.Any("error", err))
+			logAttrs = append(logAttrs, slog.Any("error", err))
 		}
-		t.logger.Error(msg, attrs...)
+		t.logger.ErrorContext(ctx, msg, logAttrs...)
 	}
 
-	if span := t.currentSpan(); span != nil {
-		span.AddEvent(msg, trace.WithAttributes(attribute.Bool("error", true)))
+	if span := t.currentSpan(ctx); span != nil {
+		eventAttrs := append(eventAttributes(attrs), attribute.Bool("error", true))
 		span.SetStatus(codes.Error, "error")
 		if err != nil {
-			span.AddEvent(msg, trace.WithAttributes(attribute.String("error.message", err.Error())))
+			eventAttrs = append(eventAttrs, attribute.String("error.message", err.Error()))
 			span.SetStatus(codes.Error, err.Error())
 		}
+		span.AddEvent(msg, trace.WithAttributes(eventAttrs...))
 	}
 }
 
-func (t *Telemetry) currentSpan() trace.Span {
-	if t.tracerReady && len(t.SpanStack) > 0 {
-		return t.SpanStack[len(t.SpanStack)-1].Span
+// currentSpan returns the recording span in ctx, or nil if there is none.
+func (t *Telemetry) currentSpan(ctx context.Context) trace.Span {
+	if !t.tracerReady {
+		return nil
+	}
+	if span := trace.SpanFromContext(ctx); span.IsRecording() {
+		return span
 	}
 	return nil
 }
+
+// eventAttributes converts slog-style attributes (alternating keys and values,
+// or slog.Attr) into span event attributes.
+func eventAttributes(attrs []any) []attribute.KeyValue {
+	var record slog.Record
+	record.Add(attrs...)
+
+	result := make([]attribute.KeyValue, 0, record.NumAttrs())
+	record.Attrs(func(a slog.Attr) bool {
+		result = append(result, attribute.String(a.Key, a.Value.String()))
+		return true
+	})
+	return result
+}
@@ -0,0 +1,117 @@
+package util
+
+import (
+	"context"
+	"fmt"
+
+	"go.opentelemetry.io/otel"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracegrpc"
+	"go.opentelemetry.io/otel/exporters/otlp/otlptrace/otlptracehttp"
+	"go.opentelemetry.io/otel/exporters/stdout/stdouttrace"
+	"go.opentelemetry.io/otel/propagation"
+	"go.opentelemetry.io/otel/sdk/resource"
+	sdktrace "go.opentelemetry.io/otel/sdk/trace"
+	"go.opentelemetry.io/otel/sdk/trace/tracetest"
+	semconv "go.opentelemetry.io/otel/semconv/v1.21.0"
+)
+
+// Span exporters, selected by TracingConfig.Exporter.
+const (
+	// ExporterOTLPHTTP sends spans to an OTLP collector over HTTP. The
+	// collector is configured by the standard OTEL_EXPORTER_OTLP_* variables.
+	ExporterOTLPHTTP = "otlphttp"
+	// ExporterOTLPGRPC sends spans to an OTLP collector over gRPC.
+	ExporterOTLPGRPC = "otlpgrpc"
+	// ExporterStdout prints spans to stdout.
+	ExporterStdout = "stdout"
+	// ExporterNone disables tracing: Start returns the context it was given.
+	ExporterNone = "none"
+	// ExporterMemory keeps ended spans in memory, see Telemetry.SpanRecorder.
+	ExporterMemory = "memory"
+)
+
+type TracingConfig struct {
+	Exporter string `json:"exporter"`
+	// SampleRatio is the fraction of new traces that are sampled, between 0
+	// and 1. Spans of sampled remote parents are always sampled.
+	SampleRatio float64 `json:"sampleRatio"`
+}
+
+func (c TracingConfig) Validate() error {
+	switch c.Exporter {
+	case ExporterOTLPHTTP, ExporterOTLPGRPC, ExporterStdout, ExporterNone, ExporterMemory:
+	default:
+		return fmt.Errorf("unknown trace exporter %q, expected one of %s, %s, %s, %s or %s",
+			c.Exporter, ExporterOTLPHTTP, ExporterOTLPGRPC, ExporterStdout, ExporterNone, ExporterMemory)
+	}
+
+	if c.SampleRatio < 0 || c.SampleRatio > 1 {
+		return fmt.Errorf("trace sample ratio %v is not between 0 and 1", c.SampleRatio)
+	}
+	return nil
+}
+
+// InitTracing sets up the tracer provider for the configured exporter and
+// returns a function that flushes and stops it. On error, tracing stays
+// disabled.
+func (t *Telemetry) InitTracing(ctx context.Context, serviceName, deploymentEnvironment string, config TracingConfig) (func(context.Context) error, error) {
+	noShutdown := func(context.Context) error { return nil }
+	t.tracerReady = false
+	t.recorder = nil
+
+	if err := config.Validate(); err != nil {
+		return noShutdown, err
+	}
+
+	otel.SetTextMapPropagator(propagation.TraceContext{})
+
+	options := []sdktrace.TracerProviderOption{
+		sdktrace.WithSampler(sdktrace.ParentBased(sdktrace.TraceIDRatioBased(config.SampleRatio))),
+		sdktrace.WithResource(resource.NewWithAttributes(
+			semconv.SchemaURL,
+			semconv.ServiceName(serviceName),
+			semconv.DeploymentEnvironment(deploymentEnvironment),
+		)),
+	}
+
+	switch config.Exporter {
+	case ExporterNone:
+		return noShutdown, nil
+
+	case ExporterMemory:
+		t.recorder = tracetest.NewSpanRecorder()
+		options = append(options, sdktrace.WithSpanProcessor(t.recorder))
+
+	default:
+		exporter, err := newSpanExporter(ctx, config.Exporter)
+		if err != nil {
+			return noShutdown, fmt.Errorf("could not create %s span exporter: %w", config.Exporter, err)
+		}
+		options = append(options, sdktrace.WithBatcher(exporter))
+	}
+
+	tp := sdktrace.NewTracerProvider(options...)
+	otel.SetTracerProvider(tp)
+
+	t.UseTracerProvider(tp, serviceName)
+	return tp.Shutdown, nil
+}
+
+func newSpanExporter(ctx context.Context, name string) (sdktrace.SpanExporter, error) {
+	switch name {
+	case ExporterOTLPHTTP:
+		return otlptracehttp.New(ctx)
+	case ExporterOTLPGRPC:
+		return otlptracegrpc.New(ctx)
+	case ExporterStdout:
+		return stdouttrace.New(stdouttrace.WithPrettyPrint())
+	default:
+		return nil, fmt.Errorf("unknown trace exporter %q", name)
+	}
+}
+
+// SpanRecorder returns the recorder of the memory exporter, or nil if another
+// exporter is used. Tests can assert against the spans it holds.
+func (t *Telemetry) SpanRecorder() *tracetest.SpanRecorder {
+	return t.recorder
+}